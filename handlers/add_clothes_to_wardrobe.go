@@ -4,64 +4,101 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
 	"github.com/zulfkhar00/instafit_mvp/services/storage"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+const (
+	// DuplicateWarn uploads near-duplicates but reports the matching item.
+	DuplicateWarn = "warn"
+	// DuplicateSkip does not upload near-duplicates at all.
+	DuplicateSkip = "skip"
 )
 
 type ClothesHandler struct {
-	Storage storage.StorageService
+	Storage  storage.StorageService
+	Wardrobe wardrobe.Store
+	Outfits  outfits.Store
+	WearLog  wearlog.Store
+	// Segmenter cuts the garments out of uploaded photos.
+	Segmenter *internal.Segmenter
 }
 
 // Handler for adding clothes to wardrobe endpoint
 func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app.RequestContext) {
-	userIdVal, exists := c.Get("userId")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{
-			"success": false,
-			"error":   "user ID missing from context",
-		})
-		return
-	}
-	userId, ok := userIdVal.(string)
-	if !ok || userId == "" {
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "invalid user ID format in context",
-		})
+	userId, ok := userIDFromContext(c)
+	if !ok {
 		return
 	}
 
 	// Get files from form data
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.String(http.StatusBadRequest, fmt.Sprintf("Failed to parse form: %v", err))
+		response.Error(c, apperr.Newf(apperr.Validation, "failed to parse form: %w", err))
 		return
 	}
 
 	clothFiles := form.File["clothes"]
 	if len(clothFiles) == 0 {
-		c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"error":   "No cloth images uploaded",
-		})
+		response.Error(c, apperr.New(apperr.Validation, "no cloth images uploaded"))
+		return
+	}
+
+	onDuplicate := c.PostForm("on_duplicate")
+	if onDuplicate == "" {
+		onDuplicate = DuplicateWarn
+	}
+	if onDuplicate != DuplicateWarn && onDuplicate != DuplicateSkip {
+		response.Error(c, apperr.New(apperr.Validation, "on_duplicate must be 'warn' or 'skip'"))
+		return
+	}
+
+	existingItems, err := h.Wardrobe.ListItems(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wardrobe: %w", err))
 		return
 	}
 
 	var wg sync.WaitGroup
+	type DuplicateInfo struct {
+		ClothingID string `json:"clothing_id"`
+		ImageURL   string `json:"image_url"`
+		Distance   int    `json:"distance"`
+	}
 	type UploadedResult struct {
-		ImageURL   string                 `json:"image_url"`
-		ClothingID string                 `json:"clothing_id"`
-		Metadata   map[string]interface{} `json:"metadata"`
+		ImageURL    string                      `json:"image_url"`
+		ClothingID  string                      `json:"clothing_id"`
+		Attributes  internal.ClothingAttributes `json:"attributes"`
+		DuplicateOf *DuplicateInfo              `json:"duplicate_of,omitempty"`
+	}
+	type SkippedResult struct {
+		Reason      string                      `json:"reason"`
+		Error       string                      `json:"error,omitempty"`
+		Attributes  internal.ClothingAttributes `json:"attributes"`
+		DuplicateOf *DuplicateInfo              `json:"duplicate_of,omitempty"`
 	}
 	resultCh := make(chan UploadedResult, len(clothFiles)*5)
+	skippedCh := make(chan SkippedResult, len(clothFiles)*5)
 	errorCh := make(chan error, len(clothFiles))
 
+	// Items accepted so far, guarded so garments repeated within one upload
+	// are caught as well as those already in the wardrobe.
+	var seenMu sync.Mutex
+	seenItems := existingItems
+
 	for fileIdx, file := range clothFiles {
 		wg.Add(1)
 		go func(imageFileIdx int, imageFile *multipart.FileHeader) {
@@ -81,19 +118,75 @@ func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app
 			}
 
 			// segment image, retrieve metadata
-			segmentedImages, err := internal.Segment_clothes(imgBytes)
+			segmentedImages, err := h.Segmenter.Segment(ctx, imgBytes)
 			if err != nil {
-				fmt.Printf("Segment_clothes error for file %d: %v", imageFileIdx, err)
-				errorCh <- fmt.Errorf("segmentation failed for file %d: %v", imageFileIdx, err)
+				log.Printf("Segmentation error for file %d: %v", imageFileIdx, err)
+				errorCh <- apperr.Newf(apperr.UpstreamFailed, "segmentation failed for file %d: %w", imageFileIdx, err)
 				return
 			}
 			if len(segmentedImages) == 0 {
-				errorCh <- fmt.Errorf("no segmented images returned for file %d", imageFileIdx)
+				errorCh <- apperr.Newf(apperr.UpstreamFailed, "no segmented images returned for file %d", imageFileIdx)
 				return
 			}
 
 			for i, segmentedImg := range segmentedImages {
+				hash, err := internal.DifferenceHash(segmentedImg.Image)
+				if err != nil {
+					errorCh <- fmt.Errorf("failed to hash segmented image %d_%d: %v", imageFileIdx, i, err)
+					return
+				}
+				colors, err := internal.ExtractColors(segmentedImg.Image, internal.DefaultColorCount)
+				if err != nil {
+					errorCh <- fmt.Errorf("failed to extract colors of segmented image %d_%d: %v", imageFileIdx, i, err)
+					return
+				}
+
+				// Garments the normalizer cannot place in the vocabulary
+				// are not stored, since filters and outfits rely on them.
+				attrs := internal.NormalizeAttributes(segmentedImg.Metadata, colors)
+				if err := attrs.Validate(); err != nil {
+					skippedCh <- SkippedResult{
+						Reason:     "invalid_attributes",
+						Error:      err.Error(),
+						Attributes: attrs,
+					}
+					continue
+				}
+
 				filename := fmt.Sprintf("wardrobe/%s/%s.jpg", userId, segmentedImg.ID)
+				item := wardrobe.ClothingItem{
+					ID:             segmentedImg.ID,
+					UserID:         userId,
+					ObjectKey:      filename,
+					Attributes:     attrs,
+					PerceptualHash: hash,
+					CreatedAt:      time.Now().UTC(),
+				}
+
+				seenMu.Lock()
+				match, distance, isDuplicate := wardrobe.FindDuplicate(seenItems, hash, internal.DefaultDuplicateDistance)
+				if !isDuplicate || onDuplicate != DuplicateSkip {
+					seenItems = append(seenItems, item)
+				}
+				seenMu.Unlock()
+
+				var duplicateOf *DuplicateInfo
+				if isDuplicate {
+					duplicateOf = &DuplicateInfo{
+						ClothingID: match.ID,
+						ImageURL:   match.ImageURL,
+						Distance:   distance,
+					}
+					if onDuplicate == DuplicateSkip {
+						skippedCh <- SkippedResult{
+							Reason:      "duplicate",
+							Attributes:  attrs,
+							DuplicateOf: duplicateOf,
+						}
+						continue
+					}
+				}
+
 				contentType := "image/jpeg"
 				url, err := h.Storage.UploadBlob(ctx, segmentedImg.Image, filename, contentType)
 				if err != nil {
@@ -101,10 +194,17 @@ func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app
 					return
 				}
 
+				item.ImageURL = url
+				if err := h.Wardrobe.SaveItem(ctx, item); err != nil {
+					errorCh <- fmt.Errorf("failed to save segmented image %d_%d: %v", imageFileIdx, i, err)
+					return
+				}
+
 				resultCh <- UploadedResult{
-					ImageURL:   url,
-					ClothingID: segmentedImg.ID,
-					Metadata:   segmentedImg.Metadata,
+					ImageURL:    url,
+					ClothingID:  segmentedImg.ID,
+					Attributes:  attrs,
+					DuplicateOf: duplicateOf,
 				}
 			}
 		}(fileIdx, file)
@@ -114,6 +214,7 @@ func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app
 	go func() {
 		wg.Wait()
 		close(resultCh)
+		close(skippedCh)
 		close(errorCh)
 	}()
 
@@ -122,6 +223,10 @@ func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app
 	for result := range resultCh {
 		uploadedItems = append(uploadedItems, result)
 	}
+	skippedItems := make([]SkippedResult, 0)
+	for skipped := range skippedCh {
+		skippedItems = append(skippedItems, skipped)
+	}
 
 	// Collect errors explicitly
 	var firstErr error
@@ -132,18 +237,13 @@ func (h *ClothesHandler) AddClothesToWardrobeHandler(ctx context.Context, c *app
 	}
 	// Check if any error occurred
 	if firstErr != nil {
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   firstErr.Error(),
-		})
+		response.Error(c, firstErr)
 		return
 	}
 
-	// TODO: insert `uploadedItems` into DB
-
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
+	response.Success(c, http.StatusOK, map[string]interface{}{
 		"clothes": uploadedItems,
+		"skipped": skippedItems,
 	})
 
 }
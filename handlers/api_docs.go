@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// DocsHandler serves the API documentation.
+type DocsHandler struct {
+	// OpenAPI is the encoded OpenAPI document.
+	OpenAPI []byte
+}
+
+// OpenAPIHandler returns the OpenAPI document as is, outside the response
+// envelope, so that tools can load it directly.
+func (h *DocsHandler) OpenAPIHandler(ctx context.Context, c *app.RequestContext) {
+	c.Data(http.StatusOK, "application/json", h.OpenAPI)
+}
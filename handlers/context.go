@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+// userIDFromContext returns the user ID injected by AuthMiddleware. When it
+// is missing or malformed an error response is written and ok is false.
+func userIDFromContext(c *app.RequestContext) (userId string, ok bool) {
+	userIdVal, exists := c.Get("userId")
+	if !exists {
+		response.Error(c, apperr.New(apperr.Unauthorized, "user ID missing from context"))
+		return "", false
+	}
+	userId, ok = userIdVal.(string)
+	if !ok || userId == "" {
+		response.Error(c, apperr.New(apperr.Internal, "invalid user ID format in context"))
+		return "", false
+	}
+	return userId, true
+}
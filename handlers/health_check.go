@@ -2,22 +2,28 @@ package handlers
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
 )
 
-// Health check handler
-func HealthCheckHandler(ctx context.Context, c *app.RequestContext) {
+type HealthHandler struct {
+	ComfyUI *internal.Pool
+}
+
+// Health check handler. ComfyUI counts as running while any backend of
+// the pool is healthy; comfyui_backends details each one.
+func (h *HealthHandler) HealthCheckHandler(ctx context.Context, c *app.RequestContext) {
 	comfyUIStatus := "not running"
-	if internal.IsComfyUIRunning() {
+	if h.ComfyUI.Healthy() {
 		comfyUIStatus = "running"
 	}
 
-	response := map[string]string{
-		"status":         "ok",
-		"comfyui_status": comfyUIStatus,
-	}
-
-	c.JSON(200, response)
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"status":           "ok",
+		"comfyui_status":   comfyUIStatus,
+		"comfyui_backends": h.ComfyUI.Status(),
+	})
 }
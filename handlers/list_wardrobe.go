@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
+)
+
+// ListWardrobeHandler lists the user's wardrobe items. The optional
+// color_family, category, season and formality query parameters each take a
+// comma-separated list and keep items matching any of the listed values.
+// not_worn_days=N keeps items not worn in the last N days, and
+// sort=least_worn orders items by wear count and last-worn date.
+func (h *ClothesHandler) ListWardrobeHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	filters := make(map[string][]string)
+	for param, vocabulary := range map[string][]string{
+		"color_family": internal.ColorFamilies,
+		"category":     internal.Categories,
+		"season":       internal.Seasons,
+		"formality":    internal.Formalities,
+	} {
+		values, err := parseQueryList(c.Query(param), vocabulary)
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Validation, "invalid %s: %w", param, err))
+			return
+		}
+		filters[param] = values
+	}
+
+	notWornDays := 0
+	if raw := c.Query("not_worn_days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			response.Error(c, apperr.New(apperr.Validation, "not_worn_days must be a positive integer"))
+			return
+		}
+		notWornDays = days
+	}
+	sortOrder := c.Query("sort")
+	if sortOrder != "" && sortOrder != "least_worn" {
+		response.Error(c, apperr.New(apperr.Validation, "sort must be 'least_worn'"))
+		return
+	}
+
+	items, err := h.Wardrobe.ListItems(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wardrobe: %w", err))
+		return
+	}
+	entries, err := h.WearLog.ListEntries(ctx, userId, "", "")
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wear log: %w", err))
+		return
+	}
+	wearStats := wearlog.Stats(entries)
+	wornSince := ""
+	if notWornDays > 0 {
+		wornSince = time.Now().UTC().AddDate(0, 0, -notWornDays).Format(wearlog.DateLayout)
+	}
+
+	filtered := make([]wardrobe.ClothingItem, 0, len(items))
+	for _, item := range items {
+		attrs := item.Attributes
+		if len(filters["color_family"]) > 0 && !slices.ContainsFunc(filters["color_family"], item.HasColorFamily) {
+			continue
+		}
+		if len(filters["category"]) > 0 && !slices.Contains(filters["category"], attrs.Category) {
+			continue
+		}
+		if len(filters["season"]) > 0 && !slices.ContainsFunc(filters["season"], attrs.WornInSeason) {
+			continue
+		}
+		if len(filters["formality"]) > 0 && !slices.Contains(filters["formality"], attrs.Formality) {
+			continue
+		}
+		if wornSince != "" && wearStats[item.ID].LastWorn > wornSince {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	itemStats := make(map[string]wearlog.ItemStats, len(filtered))
+	for _, item := range filtered {
+		stats := wearStats[item.ID]
+		stats.ClothingID = item.ID
+		itemStats[item.ID] = stats
+	}
+	if sortOrder == "least_worn" {
+		slices.SortStableFunc(filtered, func(a, b wardrobe.ClothingItem) int {
+			return wearlog.CompareLeastWorn(itemStats[a.ID], itemStats[b.ID])
+		})
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"clothes":    filtered,
+		"wear_stats": itemStats,
+	})
+}
+
+// parseQueryList splits a comma-separated query value and checks every
+// entry against the vocabulary. An empty value yields no entries.
+func parseQueryList(raw string, vocabulary []string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !slices.Contains(vocabulary, value) {
+			return nil, fmt.Errorf("unknown value %q", value)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
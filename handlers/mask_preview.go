@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+// MaskPreviewHandler runs only the masking part of the try-on workflow on
+// the person image and returns the mask and the mask tinted over the
+// person, both as base64 PNGs. It takes the same person fields as
+// VirtualTryOnHandler plus prompt and threshold, so users can tune the
+// mask before paying for a full render. Without a prompt, clothing_id
+// selects the prompt of that wardrobe item's type.
+func (h *TryOnHandler) MaskPreviewHandler(ctx context.Context, c *app.RequestContext) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Validation, "failed to parse form: %w", err))
+		return
+	}
+
+	template, err := os.ReadFile(h.workflowPath())
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to read workflow: %w", err))
+		return
+	}
+	var workflow map[string]interface{}
+	if err := json.Unmarshal(template, &workflow); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to parse workflow: %w", err))
+		return
+	}
+	tryOnParams, err := internal.ParseTryOnParams(internal.DefaultTryOnParams(workflow), c.PostForm)
+	if err != nil {
+		response.Error(c, apperr.Wrap(apperr.Validation, err))
+		return
+	}
+
+	// Preview the prompt a try-on with this wardrobe item would use
+	if clothingId := c.PostForm("clothing_id"); clothingId != "" && !tryOnParams.ExplicitPrompt {
+		userId, _ := userIDFromContext(c)
+		cloth, err := h.Wardrobe.GetItem(ctx, userId, clothingId)
+		if errors.Is(err, wardrobe.ErrItemNotFound) {
+			response.Error(c, apperr.New(apperr.Validation, "clothing item not found in wardrobe"))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+			return
+		}
+		tryOnParams.Prompt = h.MaskPrompts.For(cloth.Attributes)
+	}
+
+	sessionID := uuid.NewString()
+	personPath, _, ok := h.personImage(ctx, c, form, sessionID)
+	if !ok {
+		return
+	}
+	defer os.Remove(personPath)
+	personData, err := os.ReadFile(personPath)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to read person image: %w", err))
+		return
+	}
+
+	tryOnParams.Apply(workflow)
+	internal.SetWorkflowInput(workflow, internal.PersonImageNode, "image", personPath)
+
+	if err := h.comfyUIReady(ctx); err != nil {
+		response.Error(c, err)
+		return
+	}
+	images, err := h.ComfyUI.GetImages(h.debugContext(ctx, c, sessionID), internal.MaskWorkflow(workflow))
+	if ctx.Err() != nil {
+		log.Printf("Mask preview %s cancelled: %v", sessionID, ctx.Err())
+		return
+	}
+	if err != nil {
+		log.Printf("Error rendering mask preview %s: %v", sessionID, err)
+		response.Error(c, fmt.Errorf("failed to render mask: %w", comfyUIError(err)))
+		return
+	}
+	if len(images[internal.MaskOutputNode]) == 0 {
+		response.Error(c, apperr.New(apperr.UpstreamFailed, "no mask received from ComfyUI"))
+		return
+	}
+	mask := images[internal.MaskOutputNode][0]
+
+	overlay, err := internal.MaskOverlay(personData, mask)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to draw mask overlay: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"prompt":    tryOnParams.Prompt,
+		"threshold": tryOnParams.Threshold,
+		"mask":      base64.StdEncoding.EncodeToString(mask),
+		"overlay":   base64.StdEncoding.EncodeToString(overlay),
+	})
+}
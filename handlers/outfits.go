@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+type OutfitHandler struct {
+	Outfits  outfits.Store
+	Wardrobe wardrobe.Store
+}
+
+// CreateOutfitHandler saves a named outfit made of the user's wardrobe items.
+// An item's slot defaults to its garment category.
+func (h *OutfitHandler) CreateOutfitHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	type OutfitItemRequest struct {
+		ClothingID string `json:"clothing_id"`
+		Slot       string `json:"slot"`
+	}
+	type CreateOutfitRequest struct {
+		Name            string              `json:"name"`
+		Items           []OutfitItemRequest `json:"items"`
+		CoverClothingID string              `json:"cover_clothing_id"`
+	}
+
+	var req CreateOutfitRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		response.Error(c, apperr.Newf(apperr.Validation, "invalid request body: %w", err))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		response.Error(c, apperr.New(apperr.Validation, "name is required"))
+		return
+	}
+	if len(req.Items) == 0 {
+		response.Error(c, apperr.New(apperr.Validation, "an outfit needs at least one item"))
+		return
+	}
+
+	items := make([]outfits.OutfitItem, 0, len(req.Items))
+	clothes := make(map[string]wardrobe.ClothingItem, len(req.Items))
+	for _, reqItem := range req.Items {
+		if _, dup := clothes[reqItem.ClothingID]; dup {
+			response.Error(c, apperr.Newf(apperr.Validation, "clothing item %s is listed twice", reqItem.ClothingID))
+			return
+		}
+		cloth, err := h.Wardrobe.GetItem(ctx, userId, reqItem.ClothingID)
+		if errors.Is(err, wardrobe.ErrItemNotFound) {
+			response.Error(c, apperr.Newf(apperr.Validation, "clothing item %s not found in wardrobe", reqItem.ClothingID))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+			return
+		}
+		clothes[cloth.ID] = cloth
+
+		slot := reqItem.Slot
+		if slot == "" {
+			slot = cloth.Attributes.Category
+		}
+		if !slices.Contains(outfits.Slots, slot) {
+			response.Error(c, apperr.Newf(apperr.Validation, "invalid slot %q for clothing item %s", slot, cloth.ID))
+			return
+		}
+		// Accessories can be stacked; every other slot takes one item.
+		if slot != internal.CategoryAccessory && slices.ContainsFunc(items, func(item outfits.OutfitItem) bool { return item.Slot == slot }) {
+			response.Error(c, apperr.Newf(apperr.Validation, "slot %q is already filled", slot))
+			return
+		}
+		items = append(items, outfits.OutfitItem{ClothingID: cloth.ID, Slot: slot})
+	}
+	outfits.SortItems(items)
+
+	coverID := req.CoverClothingID
+	if coverID == "" {
+		coverID = items[0].ClothingID
+	}
+	cover, ok := clothes[coverID]
+	if !ok {
+		response.Error(c, apperr.New(apperr.Validation, "cover_clothing_id must be one of the outfit items"))
+		return
+	}
+
+	now := time.Now().UTC()
+	outfit := outfits.Outfit{
+		ID:              uuid.NewString(),
+		UserID:          userId,
+		Name:            req.Name,
+		Items:           items,
+		CoverClothingID: cover.ID,
+		CoverImageURL:   cover.ImageURL,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := h.Outfits.SaveOutfit(ctx, outfit); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to save outfit: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"outfit": outfit,
+	})
+}
+
+// ListOutfitsHandler lists the user's saved outfits.
+func (h *OutfitHandler) ListOutfitsHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userOutfits, err := h.Outfits.ListOutfits(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load outfits: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"outfits": userOutfits,
+	})
+}
+
+// GetOutfitHandler returns a single outfit.
+func (h *OutfitHandler) GetOutfitHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	outfit, err := h.Outfits.GetOutfit(ctx, userId, c.Param("outfitId"))
+	if errors.Is(err, outfits.ErrOutfitNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "outfit not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load outfit: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"outfit": outfit,
+	})
+}
+
+// DeleteOutfitHandler deletes an outfit. The wardrobe items it references
+// are left untouched.
+func (h *OutfitHandler) DeleteOutfitHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	outfitId := c.Param("outfitId")
+	err := h.Outfits.DeleteOutfit(ctx, userId, outfitId)
+	if errors.Is(err, outfits.ErrOutfitNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "outfit not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete outfit: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"outfit_id": outfitId,
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/storage"
+)
+
+// PersonPhotoPrefix is the storage prefix for saved person photos.
+const PersonPhotoPrefix = "people"
+
+// imageExtensions maps the accepted upload content types to file extensions.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type PersonPhotoHandler struct {
+	Storage storage.StorageService
+	Photos  photos.Store
+}
+
+// UploadPersonPhotoHandler saves a person photo to the user's library. The
+// photo becomes the default if it is the first one or set_default is true.
+func (h *PersonPhotoHandler) UploadPersonPhotoHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		response.Error(c, apperr.New(apperr.Validation, "photo is required"))
+		return
+	}
+	setDefault := false
+	if raw := c.PostForm("set_default"); raw != "" {
+		setDefault, err = strconv.ParseBool(raw)
+		if err != nil {
+			response.Error(c, apperr.New(apperr.Validation, "set_default must be a boolean"))
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Validation, "failed to open photo: %w", err))
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Validation, "failed to read photo: %w", err))
+		return
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		response.Error(c, apperr.New(apperr.Validation, "photo must be a JPEG, PNG or WebP image"))
+		return
+	}
+
+	photoId := uuid.NewString()
+	objectKey := fmt.Sprintf("%s/%s/%s%s", PersonPhotoPrefix, userId, photoId, ext)
+	url, err := h.Storage.UploadBlob(ctx, data, objectKey, contentType)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to upload photo: %w", err))
+		return
+	}
+
+	photo, err := h.Photos.SavePhoto(ctx, photos.PersonPhoto{
+		ID:          photoId,
+		UserID:      userId,
+		ImageURL:    url,
+		ObjectKey:   objectKey,
+		ContentType: contentType,
+		IsDefault:   setDefault,
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to save photo: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"photo": photo,
+	})
+}
+
+// ListPersonPhotosHandler lists the user's saved person photos.
+func (h *PersonPhotoHandler) ListPersonPhotosHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userPhotos, err := h.Photos.ListPhotos(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load photos: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"photos": userPhotos,
+	})
+}
+
+// SetDefaultPersonPhotoHandler makes a saved photo the user's default for try-ons.
+func (h *PersonPhotoHandler) SetDefaultPersonPhotoHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	photo, err := h.Photos.SetDefaultPhoto(ctx, userId, c.Param("photoId"))
+	if errors.Is(err, photos.ErrPhotoNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "person photo not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to set default photo: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"photo": photo,
+	})
+}
+
+// DeletePersonPhotoHandler removes a saved photo from storage and the library.
+func (h *PersonPhotoHandler) DeletePersonPhotoHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	photoId := c.Param("photoId")
+	photo, err := h.Photos.GetPhoto(ctx, userId, photoId)
+	if errors.Is(err, photos.ErrPhotoNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "person photo not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load photo: %w", err))
+		return
+	}
+
+	if err := h.Storage.DeleteBlob(ctx, photo.ObjectKey); err != nil {
+		log.Printf("Error deleting person photo %s for user %s: %v", photoId, userId, err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete photo: %w", err))
+		return
+	}
+	if err := h.Photos.DeletePhoto(ctx, userId, photoId); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete photo: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"photo_id": photoId,
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/recommendation"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+type RecommendationHandler struct {
+	Recommender *recommendation.Service
+}
+
+// RecommendOutfitsHandler returns ranked outfits composed from the user's
+// wardrobe, each with the reasons it was chosen. Optional query parameters
+// are season, formality, occasion, location and limit. A location adapts
+// the outfits to its current weather.
+func (h *RecommendationHandler) RecommendOutfitsHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	req := recommendation.Request{
+		Season:    strings.ToLower(c.Query("season")),
+		Formality: strings.ToLower(c.Query("formality")),
+		Occasion:  strings.ToLower(c.Query("occasion")),
+		Location:  strings.TrimSpace(c.Query("location")),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			response.Error(c, apperr.New(apperr.Validation, "limit must be an integer"))
+			return
+		}
+		req.Limit = limit
+	}
+
+	result, err := h.Recommender.Recommend(ctx, userId, req)
+	if err != nil {
+		response.Error(c, recommendationError(err, req.Location))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"season":          result.Season,
+		"formality":       result.Formality,
+		"occasion":        result.Occasion,
+		"weather":         result.Weather,
+		"recommendations": result.Recommendations,
+	})
+}
+
+// recommendationError classifies a failed recommendation. Weather
+// failures are upstream errors, like ComfyUI failures.
+func recommendationError(err error, location string) error {
+	switch {
+	case errors.Is(err, recommendation.ErrInvalidRequest):
+		return apperr.Wrap(apperr.Validation, err)
+	case errors.Is(err, weather.ErrUnknownLocation):
+		return apperr.Newf(apperr.Validation, "unknown location %q", location)
+	case errors.Is(err, recommendation.ErrWeatherUnavailable):
+		return apperr.New(apperr.UpstreamUnavailable, "weather-based recommendations are not available")
+	case errors.Is(err, weather.ErrServiceUnavailable):
+		return apperr.Wrap(apperr.UpstreamUnavailable, err)
+	case errors.Is(err, recommendation.ErrWeatherFailed):
+		return apperr.Wrap(apperr.UpstreamFailed, err)
+	}
+	return apperr.Newf(apperr.Internal, "failed to recommend outfits: %w", err)
+}
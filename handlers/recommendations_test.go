@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/services/recommendation"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+func TestRecommendationError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		kind apperr.Kind
+	}{
+		{"invalid request", fmt.Errorf("%w: unknown season %q", recommendation.ErrInvalidRequest, "monsoon"), apperr.Validation},
+		{"unknown location", fmt.Errorf("%w: %w", recommendation.ErrWeatherFailed, weather.ErrUnknownLocation), apperr.Validation},
+		{"no provider", recommendation.ErrWeatherUnavailable, apperr.UpstreamUnavailable},
+		{"service unreachable", fmt.Errorf("%w: %w", recommendation.ErrWeatherFailed, weather.ErrServiceUnavailable), apperr.UpstreamUnavailable},
+		{"bad response", fmt.Errorf("%w: %w", recommendation.ErrWeatherFailed, errors.New("bad response from weather service")), apperr.UpstreamFailed},
+		{"wardrobe", errors.New("failed to load wardrobe"), apperr.Internal},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := apperr.KindOf(recommendationError(tc.err, "Atlantis")); got != tc.kind {
+				t.Errorf("kind = %s, want %s", got, tc.kind)
+			}
+		})
+	}
+}
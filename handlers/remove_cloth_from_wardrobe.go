@@ -2,60 +2,88 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
 )
 
 func (h *ClothesHandler) RemoveClothingFromWardrobeHandler(ctx context.Context, c *app.RequestContext) {
-	// Extract userId from context
-	userIdVal, exists := c.Get("userId")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{
-			"success": false,
-			"error":   "user ID missing from context",
-		})
-		return
-	}
-	userId, ok := userIdVal.(string)
-	if !ok || userId == "" {
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "invalid user ID format in context",
-		})
+	userId, ok := userIDFromContext(c)
+	if !ok {
 		return
 	}
 
 	// Extract clothId from route parameters
 	clothId := c.Param("clothId")
 	if clothId == "" {
-		c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"error":   "cloth ID required",
-		})
+		response.Error(c, apperr.New(apperr.Validation, "cloth ID required"))
 		return
 	}
 
-	// Construct Cloudflare/S3 object key
-	objectKey := fmt.Sprintf("wardrobe/%s/%s.jpg", userId, clothId)
+	item, err := h.Wardrobe.GetItem(ctx, userId, clothId)
+	if errors.Is(err, wardrobe.ErrItemNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "clothing item not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+		return
+	}
 
 	// Remove image from storage
-	err := h.Storage.DeleteBlob(ctx, objectKey)
+	err = h.Storage.DeleteBlob(ctx, item.ObjectKey)
 	if err != nil {
 		log.Printf("Error deleting cloth %s for user %s: %v", clothId, userId, err)
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   fmt.Sprintf("failed to delete clothing item: %v", err),
-		})
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete clothing item: %w", err))
 		return
 	}
 
-	// TODO: Also remove the clothing entry from your database, if applicable.
+	if err := h.Wardrobe.DeleteItem(ctx, userId, clothId); err != nil {
+		log.Printf("Error removing cloth %s for user %s from wardrobe: %v", clothId, userId, err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete clothing item: %w", err))
+		return
+	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"clothId": clothId,
+	// Cascade to outfits: drop the item, delete outfits left empty and pick
+	// a new cover where the removed item was the cover.
+	updatedOutfits, deletedOutfits, err := h.Outfits.RemoveClothing(ctx, userId, clothId)
+	if err != nil {
+		log.Printf("Error removing cloth %s for user %s from outfits: %v", clothId, userId, err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to update outfits: %w", err))
+		return
+	}
+	for _, outfitId := range updatedOutfits {
+		if err := h.refreshOutfitCover(ctx, userId, outfitId); err != nil {
+			log.Printf("Error refreshing cover of outfit %s for user %s: %v", outfitId, userId, err)
+		}
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"cloth_id":        clothId,
+		"updated_outfits": updatedOutfits,
+		"deleted_outfits": deletedOutfits,
 	})
 }
+
+// refreshOutfitCover fills in the cover image of an outfit whose cover was
+// reassigned after its previous cover item was removed.
+func (h *ClothesHandler) refreshOutfitCover(ctx context.Context, userId, outfitId string) error {
+	outfit, err := h.Outfits.GetOutfit(ctx, userId, outfitId)
+	if err != nil {
+		return err
+	}
+	if outfit.CoverImageURL != "" {
+		return nil
+	}
+	cover, err := h.Wardrobe.GetItem(ctx, userId, outfit.CoverClothingID)
+	if err != nil {
+		return err
+	}
+	outfit.CoverImageURL = cover.ImageURL
+	return h.Outfits.SaveOutfit(ctx, outfit)
+}
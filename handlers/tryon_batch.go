@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+const (
+	// MaxBatchCells caps the number of combinations in one batch.
+	MaxBatchCells = 36
+	// BatchConcurrency is how many cells of a batch are sent to ComfyUI
+	// at the same time.
+	BatchConcurrency = 2
+)
+
+// StartTryOnBatchHandler renders every combination of the saved person
+// photos in person_photo_ids and the wardrobe items in clothing_ids, both
+// comma-separated. The generation parameters are the same as for a single
+// try-on and shared by all cells; seed=random picks one seed for the whole
+// grid. Without a prompt, each garment's column is masked by its category.
+// The batch runs in the background and its ID is returned at once.
+func (h *TryOnHandler) StartTryOnBatchHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	personPhotoIds := splitIDs(c.PostForm("person_photo_ids"))
+	clothingIds := splitIDs(c.PostForm("clothing_ids"))
+	if len(personPhotoIds) == 0 || len(clothingIds) == 0 {
+		response.Error(c, apperr.New(apperr.Validation, "person_photo_ids and clothing_ids are required"))
+		return
+	}
+	if len(personPhotoIds)*len(clothingIds) > MaxBatchCells {
+		response.Error(c, apperr.Newf(apperr.Validation, "a batch may have at most %d combinations", MaxBatchCells))
+		return
+	}
+
+	people := make([]photos.PersonPhoto, 0, len(personPhotoIds))
+	for _, id := range personPhotoIds {
+		photo, err := h.Photos.GetPhoto(ctx, userId, id)
+		if errors.Is(err, photos.ErrPhotoNotFound) {
+			response.Error(c, apperr.Newf(apperr.Validation, "person photo %s not found", id))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load person photo: %w", err))
+			return
+		}
+		people = append(people, photo)
+	}
+	garments := make([]wardrobe.ClothingItem, 0, len(clothingIds))
+	for _, id := range clothingIds {
+		item, err := h.Wardrobe.GetItem(ctx, userId, id)
+		if errors.Is(err, wardrobe.ErrItemNotFound) {
+			response.Error(c, apperr.Newf(apperr.Validation, "clothing item %s not found in wardrobe", id))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+			return
+		}
+		garments = append(garments, item)
+	}
+
+	template, err := os.ReadFile(h.workflowPath())
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to read workflow: %w", err))
+		return
+	}
+	var workflow map[string]interface{}
+	if err := json.Unmarshal(template, &workflow); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to parse workflow: %w", err))
+		return
+	}
+	tryOnParams, err := internal.ParseTryOnParams(internal.DefaultTryOnParams(workflow), c.PostForm)
+	if err != nil {
+		response.Error(c, apperr.Wrap(apperr.Validation, err))
+		return
+	}
+
+	// Without an explicit prompt each column gets the prompt of its garment
+	batchParams := galleryParams(tryOnParams)
+	if !tryOnParams.ExplicitPrompt {
+		delete(batchParams, "prompt")
+	}
+
+	// The batch outlives the request, so its context only ends on cancel
+	batchCtx, cancel := context.WithCancel(context.Background())
+	batch := tryonbatch.NewBatch(uuid.NewString(), userId, personPhotoIds, clothingIds, batchParams)
+	batch.Debug, _ = strconv.ParseBool(c.PostForm("debug"))
+	h.Batches.Add(batch, cancel)
+	go h.runBatch(batchCtx, batch, people, garments, template, tryOnParams)
+
+	response.Success(c, http.StatusAccepted, map[string]interface{}{
+		"batch": batch,
+	})
+}
+
+// GetTryOnBatchHandler returns the status of a batch and each of its cells.
+func (h *TryOnHandler) GetTryOnBatchHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.Batches.Get(userId, c.Param("batchId"))
+	if errors.Is(err, tryonbatch.ErrBatchNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on batch not found"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"batch": batch,
+	})
+}
+
+// CancelTryOnBatchHandler stops a batch. Cells that are queued or running
+// are cancelled on ComfyUI; finished cells keep their results.
+func (h *TryOnHandler) CancelTryOnBatchHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.Batches.Cancel(userId, c.Param("batchId"))
+	if errors.Is(err, tryonbatch.ErrBatchNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on batch not found"))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"batch": batch,
+	})
+}
+
+// TryOnBatchContactSheetHandler returns the batch as a single JPEG grid,
+// one row per person and one column per garment. Cells that are not done
+// yet or failed are left grey.
+func (h *TryOnHandler) TryOnBatchContactSheetHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	batchId := c.Param("batchId")
+	batch, err := h.Batches.Get(userId, batchId)
+	if errors.Is(err, tryonbatch.ErrBatchNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on batch not found"))
+		return
+	}
+
+	cells := make([][][]byte, len(batch.PersonPhotoIDs))
+	for row := range cells {
+		cells[row] = make([][]byte, len(batch.ClothingIDs))
+	}
+	for _, cell := range batch.Cells {
+		if cell.Status != tryonbatch.StatusDone {
+			continue
+		}
+		data, err := h.Storage.GetBlob(ctx, cell.ObjectKey)
+		if err != nil {
+			log.Printf("Error loading batch %s cell %d,%d: %v", batchId, cell.Row, cell.Col, err)
+			continue
+		}
+		cells[cell.Row][cell.Col] = data
+	}
+
+	sheet, err := internal.ContactSheet(cells, internal.DefaultContactCellWidth, internal.DefaultContactCellHeight)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to build contact sheet: %w", err))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=tryon_batch_%s.jpg", batchId))
+	c.Data(http.StatusOK, "image/jpeg", sheet)
+}
+
+// runBatch renders the cells of a batch, at most BatchConcurrency at a
+// time, until ctx is cancelled. Each input image is downloaded once and
+// shared by its row or column.
+func (h *TryOnHandler) runBatch(ctx context.Context, batch tryonbatch.Batch, people []photos.PersonPhoto, garments []wardrobe.ClothingItem, template []byte, params internal.TryOnParams) {
+	personPaths := make([]string, len(people))
+	for i, photo := range people {
+		path, err := h.downloadToTemp(ctx, photo.ObjectKey, fmt.Sprintf("person_%s_%d", batch.ID, i))
+		if err != nil {
+			log.Printf("Error loading person photo %s for batch %s: %v", photo.ID, batch.ID, err)
+			continue
+		}
+		personPaths[i] = path
+		defer os.Remove(path)
+	}
+	garmentPaths := make([]string, len(garments))
+	for i, item := range garments {
+		path, err := h.downloadToTemp(ctx, item.ObjectKey, fmt.Sprintf("garment_%s_%d", batch.ID, i))
+		if err != nil {
+			log.Printf("Error loading clothing item %s for batch %s: %v", item.ID, batch.ID, err)
+			continue
+		}
+		garmentPaths[i] = path
+		defer os.Remove(path)
+	}
+
+	sem := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+	for _, cell := range batch.Cells {
+		wg.Add(1)
+		go func(cell tryonbatch.Cell) {
+			defer wg.Done()
+
+			update := func(f func(*tryonbatch.Cell)) {
+				h.Batches.UpdateCell(batch.ID, cell.Row, cell.Col, f)
+			}
+			fail := func(err error) {
+				if ctx.Err() != nil {
+					update(func(c *tryonbatch.Cell) { c.Status = tryonbatch.StatusCancelled })
+					return
+				}
+				log.Printf("Error rendering batch %s cell %d,%d: %v", batch.ID, cell.Row, cell.Col, err)
+				update(func(c *tryonbatch.Cell) {
+					c.Status = tryonbatch.StatusFailed
+					c.Error = apperr.KindOf(err).Code()
+				})
+			}
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				fail(ctx.Err())
+				return
+			}
+			// select picks at random when the batch was cancelled while a
+			// slot freed up
+			if ctx.Err() != nil {
+				fail(ctx.Err())
+				return
+			}
+
+			personPath, garmentPath := personPaths[cell.Row], garmentPaths[cell.Col]
+			if personPath == "" || garmentPath == "" {
+				fail(errors.New("failed to load input image"))
+				return
+			}
+			cellParams := params
+			if !params.ExplicitPrompt {
+				cellParams.Prompt = h.MaskPrompts.For(garments[cell.Col].Attributes)
+			}
+			update(func(c *tryonbatch.Cell) {
+				c.Status = tryonbatch.StatusRunning
+				c.Prompt = cellParams.Prompt
+			})
+
+			renderCtx := ctx
+			if batch.Debug {
+				renderCtx = internal.WithDebugJob(ctx, h.Debug.Job(fmt.Sprintf("%s_%d_%d", batch.ID, cell.Row, cell.Col)))
+			}
+			imageData, cacheHit, err := h.renderTryOn(renderCtx, template, cellParams, personPath, garmentPath)
+			if err != nil {
+				fail(err)
+				return
+			}
+			tryOn, err := h.saveTryOn(ctx, tryons.TryOn{
+				ID:            uuid.NewString(),
+				UserID:        batch.UserID,
+				PersonPhotoID: cell.PersonPhotoID,
+				ClothingID:    cell.ClothingID,
+				Parameters:    galleryParams(cellParams),
+				CacheHit:      cacheHit,
+			}, imageData)
+			if err != nil {
+				fail(fmt.Errorf("failed to save try-on: %w", err))
+				return
+			}
+			update(func(c *tryonbatch.Cell) {
+				c.Status = tryonbatch.StatusDone
+				c.TryOnID = tryOn.ID
+				c.ImageURL = tryOn.ImageURL
+				c.ObjectKey = tryOn.ObjectKey
+				c.CacheHit = cacheHit
+			})
+		}(cell)
+	}
+	wg.Wait()
+}
+
+// splitIDs splits a comma-separated list of IDs, dropping blanks and
+// repeats.
+func splitIDs(raw string) []string {
+	var ids []string
+	for _, id := range strings.Split(raw, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
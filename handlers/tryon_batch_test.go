@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/comfyuitest"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+func TestRunBatchCellErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		steps  []comfyuitest.Step
+		stored bool
+		code   string
+		detail string
+	}{
+		{"render fails", []comfyuitest.Step{
+			comfyuitest.Executing(internal.MaskNode),
+			comfyuitest.ExecutionError(internal.MaskNode, "GroundingDinoSAMSegment (segment anything)", "RuntimeError", "CUDA out of memory"),
+		}, true, "UPSTREAM_FAILED", "CUDA out of memory"},
+		{"input missing", nil, false, "INTERNAL_ERROR", "failed to load input image"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTryOnTest(t)
+			tt.comfy.SetSteps(tc.steps...)
+			if tc.stored {
+				tt.storage.blobs["people/p1.png"] = []byte("person bytes")
+			}
+			tt.storage.blobs["wardrobe/c1.png"] = []byte("garment bytes")
+			template, err := os.ReadFile(tt.handler.workflowPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var workflow map[string]interface{}
+			if err := json.Unmarshal(template, &workflow); err != nil {
+				t.Fatal(err)
+			}
+
+			batch := tryonbatch.NewBatch("b1", testUserID, []string{"p1"}, []string{"c1"}, nil)
+			tt.handler.Batches.Add(batch, func() {})
+			logged := captureLog(t)
+			tt.handler.runBatch(context.Background(), batch,
+				[]photos.PersonPhoto{{ID: "p1", ObjectKey: "people/p1.png"}},
+				[]wardrobe.ClothingItem{{ID: "c1", ObjectKey: "wardrobe/c1.png"}},
+				template, internal.DefaultTryOnParams(workflow))
+
+			got, err := tt.handler.Batches.Get(testUserID, "b1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Clients get the error code; the error itself is only logged
+			cell := got.Cells[0]
+			if cell.Status != tryonbatch.StatusFailed || cell.Error != tc.code {
+				t.Errorf("cell = %s %q, want %s %q", cell.Status, cell.Error, tryonbatch.StatusFailed, tc.code)
+			}
+			if !strings.Contains(logged.String(), tc.detail) {
+				t.Errorf("log %q does not record %q", logged.String(), tc.detail)
+			}
+		})
+	}
+}
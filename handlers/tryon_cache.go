@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+// PurgeTryOnCacheHandler deletes cached try-on renders. With
+// older_than=<duration> (e.g. 72h) only entries older than that are purged.
+func (h *TryOnHandler) PurgeTryOnCacheHandler(ctx context.Context, c *app.RequestContext) {
+	var before time.Time
+	if raw := c.Query("older_than"); raw != "" {
+		age, err := time.ParseDuration(raw)
+		if err != nil || age <= 0 {
+			response.Error(c, apperr.New(apperr.Validation, "older_than must be a positive duration such as 72h"))
+			return
+		}
+		before = time.Now().UTC().Add(-age)
+	}
+
+	purged, err := h.Cache.Purge(ctx, before)
+	if err != nil {
+		response.Error(c, apperr.WithDetails(fmt.Errorf("failed to purge try-on cache: %w", err), map[string]interface{}{
+			"purged": purged,
+		}))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"purged": purged,
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+)
+
+// ListTryOnsHandler lists the user's past try-ons, newest first. With
+// favorite=true only favorites are returned.
+func (h *TryOnHandler) ListTryOnsHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	favoritesOnly := false
+	if raw := c.Query("favorite"); raw != "" {
+		var err error
+		favoritesOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			response.Error(c, apperr.New(apperr.Validation, "favorite must be a boolean"))
+			return
+		}
+	}
+
+	userTryOns, err := h.TryOns.ListTryOns(ctx, userId, favoritesOnly)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load try-ons: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"tryons": userTryOns,
+	})
+}
+
+// GetTryOnHandler returns a single past try-on.
+func (h *TryOnHandler) GetTryOnHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	tryOn, err := h.TryOns.GetTryOn(ctx, userId, c.Param("tryOnId"))
+	if errors.Is(err, tryons.ErrTryOnNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load try-on: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"tryon": tryOn,
+	})
+}
+
+// FavoriteTryOnHandler marks or unmarks a try-on as favorite.
+func (h *TryOnHandler) FavoriteTryOnHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	type FavoriteRequest struct {
+		Favorite *bool `json:"favorite"`
+	}
+	var req FavoriteRequest
+	if err := c.BindAndValidate(&req); err != nil || req.Favorite == nil {
+		response.Error(c, apperr.New(apperr.Validation, "favorite is required"))
+		return
+	}
+
+	tryOn, err := h.TryOns.SetFavorite(ctx, userId, c.Param("tryOnId"), *req.Favorite)
+	if errors.Is(err, tryons.ErrTryOnNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to update try-on: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"tryon": tryOn,
+	})
+}
+
+// DeleteTryOnHandler removes a try-on and its rendered image.
+func (h *TryOnHandler) DeleteTryOnHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	tryOnId := c.Param("tryOnId")
+	tryOn, err := h.TryOns.GetTryOn(ctx, userId, tryOnId)
+	if errors.Is(err, tryons.ErrTryOnNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "try-on not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load try-on: %w", err))
+		return
+	}
+
+	if err := h.Storage.DeleteBlob(ctx, tryOn.ObjectKey); err != nil {
+		log.Printf("Error deleting try-on %s for user %s: %v", tryOnId, userId, err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete try-on: %w", err))
+		return
+	}
+	if err := h.TryOns.DeleteTryOn(ctx, userId, tryOnId); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete try-on: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"tryon_id": tryOnId,
+	})
+}
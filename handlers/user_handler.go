@@ -5,10 +5,15 @@ import (
 	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
 	"github.com/zulfkhar00/instafit_mvp/internal/auth"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
 )
 
-type UserHandler struct{}
+type UserHandler struct {
+	// JWTSecret signs the issued tokens.
+	JWTSecret []byte
+}
 
 // TestAuthHandler generates JWT tokens for testing purposes only.
 func (h *UserHandler) TestAuthHandler(ctx context.Context, c *app.RequestContext) {
@@ -18,24 +23,17 @@ func (h *UserHandler) TestAuthHandler(ctx context.Context, c *app.RequestContext
 
 	var req AuthTestRequest
 	if err := c.BindAndValidate(&req); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"error":   "user_id is required",
-		})
+		response.Error(c, apperr.New(apperr.Validation, "user_id is required"))
 		return
 	}
 
-	token, err := auth.GenerateJWT(req.UserId)
+	token, err := auth.GenerateJWT(h.JWTSecret, req.UserId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "Failed to generate token",
-		})
+		response.Error(c, apperr.New(apperr.Internal, "failed to generate token"))
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"token":   token,
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"token": token,
 	})
 }
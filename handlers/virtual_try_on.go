@@ -3,156 +3,436 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/storage"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/tryoncache"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
 )
 
 const (
-	ComfyUIAPIURL = "http://127.0.0.1:8188/api"
-	WorkflowPath  = "./ImageWorkflow.json"
+	// DefaultWorkflowPath is the try-on workflow template used when
+	// TryOnHandler.WorkflowPath is empty.
+	DefaultWorkflowPath = "./ImageWorkflow.json"
+	// TryOnResultPrefix is the storage prefix for rendered try-on results.
+	TryOnResultPrefix = "tryons"
 )
 
 var (
 	TempDir = getTempDir()
 )
 
-// Handler for virtual try-on endpoint
-func VirtualTryOnHandler(ctx context.Context, c *app.RequestContext) {
-	// Ensure ComfyUI is running
-	if !internal.IsComfyUIRunning() {
-		if err := internal.StartComfyUI(); err != nil {
-			c.String(http.StatusInternalServerError, fmt.Sprintf("Failed to start ComfyUI: %v", err))
-			return
-		}
-
-		// Double-check if it's running
-		if !internal.IsComfyUIRunning() {
-			c.String(http.StatusInternalServerError, "ComfyUI failed to start")
-			return
-		}
-	}
+type TryOnHandler struct {
+	Storage  storage.StorageService
+	Photos   photos.Store
+	Wardrobe wardrobe.Store
+	TryOns   tryons.Store
+	Cache    *tryoncache.Cache
+	Batches  *tryonbatch.Tracker
+	// ComfyUI routes prompts to the least-loaded ComfyUI backend.
+	ComfyUI *internal.Pool
+	// Supervisor runs the local ComfyUI. It is nil when ComfyUI is
+	// managed outside this server.
+	Supervisor *internal.Supervisor
+	// Debug records the artifacts of jobs requested with debug=true. It is
+	// nil when debug recording is not configured.
+	Debug *internal.DebugRecorder
+	// MaskPrompts picks the mask prompt from the garment type when the
+	// request does not give one.
+	MaskPrompts internal.MaskPrompts
+	// Segmenter classifies uploaded garments to pick their mask prompt. It
+	// may be nil, and then the default prompt is used.
+	Segmenter *internal.Segmenter
+	// WorkflowPath is the try-on workflow template, DefaultWorkflowPath
+	// when empty.
+	WorkflowPath string
+}
 
+// Handler for virtual try-on endpoint. The person is either uploaded as
+// person_image, picked from the photo library with person_photo_id, or
+// the user's default photo. The garment is either uploaded as
+// garment_image or picked from the wardrobe with clothing_id. Every result
+// is saved to the user's try-on gallery. Renders are cached by their
+// inputs, and the X-Cache response header reports HIT or MISS. A render
+// is cancelled on ComfyUI if the client disconnects before it finishes.
+//
+// Unless prompt is given, the mask prompt is derived from the garment: from
+// the stored category of a wardrobe item, or by classifying an upload
+// with the segmenter.
+//
+// The generation parameters seed, steps, cfg, mask_grow and threshold can
+// be set directly or through a preset (fast, balanced, best); seed=random
+// picks a new seed. The effective values are echoed as JSON in the
+// X-TryOn-Params response header. With debug=true the workflow and
+// ComfyUI messages are kept as debug artifacts, if that is configured.
+func (h *TryOnHandler) VirtualTryOnHandler(ctx context.Context, c *app.RequestContext) {
 	// Get files from form data
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.String(http.StatusBadRequest, fmt.Sprintf("Failed to parse form: %v", err))
+		response.Error(c, apperr.Newf(apperr.Validation, "failed to parse form: %w", err))
 		return
 	}
 
-	// Get input files
-	personFiles := form.File["person_image"]
-	if len(personFiles) == 0 {
-		c.String(http.StatusBadRequest, "person_image is required")
+	userId, ok := userIDFromContext(c)
+	if !ok {
 		return
 	}
-	personHeader := personFiles[0]
 
-	garmentFiles := form.File["garment_image"]
-	if len(garmentFiles) == 0 {
-		c.String(http.StatusBadRequest, "garment_image is required")
+	fileData, err := os.ReadFile(h.workflowPath())
+	if err != nil {
+		log.Printf("Error reading workflow %s: %v", h.workflowPath(), err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to read workflow: %w", err))
+		return
+	}
+	// Parse the JSON
+	var workflow map[string]interface{}
+	err = json.Unmarshal(fileData, &workflow)
+	if err != nil {
+		log.Printf("Error parsing workflow %s: %v", h.workflowPath(), err)
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to parse workflow: %w", err))
 		return
 	}
-	garmentHeader := garmentFiles[0]
 
-	// Get prompt parameter or use default
-	prompt := c.PostForm("prompt")
-	if prompt == "" {
-		prompt = "shirt"
+	// Generation parameters default to the values in the workflow template
+	tryOnParams, err := internal.ParseTryOnParams(internal.DefaultTryOnParams(workflow), c.PostForm)
+	if err != nil {
+		response.Error(c, apperr.Wrap(apperr.Validation, err))
+		return
 	}
 
 	// Generate session ID
 	sessionID := uuid.New().String()
 
-	// Save uploaded files temporarily
-	personPath := filepath.Join(TempDir, fmt.Sprintf("person_%s%s", sessionID, filepath.Ext(personHeader.Filename)))
-	if err := c.SaveUploadedFile(personHeader, personPath); err != nil {
-		c.String(http.StatusInternalServerError, fmt.Sprintf("Failed to save person image: %v", err))
+	// Save input images temporarily
+	personPath, personPhotoId, ok := h.personImage(ctx, c, form, sessionID)
+	if !ok {
 		return
 	}
 	defer os.Remove(personPath)
 
-	garmentPath := filepath.Join(TempDir, fmt.Sprintf("garment_%s%s", sessionID, filepath.Ext(garmentHeader.Filename)))
-	if err := c.SaveUploadedFile(garmentHeader, garmentPath); err != nil {
-		c.String(http.StatusInternalServerError, fmt.Sprintf("Failed to save garment image: %v", err))
+	// Without an explicit prompt, the mask prompt follows the garment type
+	var garmentPath, clothingId string
+	if garmentFiles := form.File["garment_image"]; len(garmentFiles) > 0 {
+		garmentHeader := garmentFiles[0]
+		garmentPath = filepath.Join(TempDir, fmt.Sprintf("garment_%s%s", sessionID, filepath.Ext(garmentHeader.Filename)))
+		if err := c.SaveUploadedFile(garmentHeader, garmentPath); err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to save garment image: %w", err))
+			return
+		}
+		if !tryOnParams.ExplicitPrompt {
+			tryOnParams.Prompt = h.uploadMaskPrompt(ctx, garmentPath)
+		}
+	} else if clothingId = c.PostForm("clothing_id"); clothingId != "" {
+		cloth, err := h.Wardrobe.GetItem(ctx, userId, clothingId)
+		if errors.Is(err, wardrobe.ErrItemNotFound) {
+			response.Error(c, apperr.New(apperr.Validation, "clothing item not found in wardrobe"))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+			return
+		}
+		garmentPath, err = h.downloadToTemp(ctx, cloth.ObjectKey, "garment_"+sessionID)
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load garment image: %w", err))
+			return
+		}
+		if !tryOnParams.ExplicitPrompt {
+			tryOnParams.Prompt = h.MaskPrompts.For(cloth.Attributes)
+		}
+	} else {
+		response.Error(c, apperr.New(apperr.Validation, "garment_image or clothing_id is required"))
 		return
 	}
 	defer os.Remove(garmentPath)
 
 	log.Printf("Images saved: %s, %s", personPath, garmentPath)
 
-	// RUN COMFYUI WORKFLOW
-	fileData, err := os.ReadFile(WorkflowPath)
+	imageData, cacheHit, err := h.renderTryOn(h.debugContext(ctx, c, sessionID), fileData, tryOnParams, personPath, garmentPath)
+	if ctx.Err() != nil {
+		// The client went away; the prompt has been cancelled on ComfyUI
+		log.Printf("Try-on %s cancelled: %v", sessionID, ctx.Err())
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
+		log.Printf("Error rendering try-on %s: %v", sessionID, err)
+		// The ComfyUI error's kind decides the status
+		response.Error(c, fmt.Errorf("failed to render try-on: %w", err))
 		return
 	}
-	// Parse the JSON
+
+	// Keep the result in the gallery. A storage failure should not cost
+	// the user the render they waited for, so it is only logged.
+	_, err = h.saveTryOn(ctx, tryons.TryOn{
+		ID:            sessionID,
+		UserID:        userId,
+		PersonPhotoID: personPhotoId,
+		ClothingID:    clothingId,
+		Parameters:    galleryParams(tryOnParams),
+		CacheHit:      cacheHit,
+	}, imageData)
+	if err != nil {
+		log.Printf("Error saving try-on %s for user %s: %v", sessionID, userId, err)
+	} else {
+		c.Header("X-TryOn-Id", sessionID)
+	}
+
+	// Set appropriate headers
+	if effective, err := json.Marshal(tryOnParams); err == nil {
+		c.Header("X-TryOn-Params", string(effective))
+	}
+	if cacheHit {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=vton_result_%s%s", sessionID, internal.ImageExtension(imageData)))
+
+	// Send the image in response, in whatever format the workflow saved
+	c.Data(http.StatusOK, http.DetectContentType(imageData), imageData)
+
+	// Clean up temp files
+	os.Remove(personPath)
+	os.Remove(garmentPath)
+}
+
+// renderTryOn runs the workflow template on the given images. An identical
+// earlier render is served from the cache instead, which is reported by
+// the returned bool.
+func (h *TryOnHandler) renderTryOn(ctx context.Context, template []byte, params internal.TryOnParams, personPath, garmentPath string) ([]byte, bool, error) {
+	// Each render gets its own copy of the workflow
 	var workflow map[string]interface{}
-	err = json.Unmarshal(fileData, &workflow)
+	if err := json.Unmarshal(template, &workflow); err != nil {
+		return nil, false, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	// set the masker and try-on parameters
+	params.Apply(workflow)
+	// set the image path of garment image
+	internal.SetWorkflowInput(workflow, internal.GarmentImageNode, "image", garmentPath)
+	// set the image path of person image
+	internal.SetWorkflowInput(workflow, internal.PersonImageNode, "image", personPath)
+
+	cacheKey, err := cacheKeyFor(personPath, garmentPath, template, params.CacheParams())
 	if err != nil {
-		fmt.Printf("Error parsing JSON: %v\n", err)
-		return
+		log.Printf("Error computing try-on cache key: %v", err)
 	}
-	// set the prompt of masker
-	if node21, ok := workflow["21"].(map[string]interface{}); ok {
-		if inputs, ok := node21["inputs"].(map[string]interface{}); ok {
-			inputs["prompt"] = prompt // Change the prompt dynamically
+	if cacheKey != "" {
+		if imageData, ok := h.Cache.Get(ctx, cacheKey); ok {
+			return imageData, true, nil
 		}
 	}
-	// set the image path of garment image
-	if node22, ok := workflow["22"].(map[string]interface{}); ok {
-		if inputs, ok := node22["inputs"].(map[string]interface{}); ok {
-			inputs["image"] = garmentPath
+
+	if err := h.comfyUIReady(ctx); err != nil {
+		return nil, false, err
+	}
+	images, err := h.ComfyUI.GetImages(ctx, workflow)
+	if err != nil {
+		return nil, false, comfyUIError(err)
+	}
+	// Only the output node has the result; other nodes may save images too
+	output := images[internal.OutputNode]
+	if len(output) == 0 || len(output[0]) == 0 {
+		return nil, false, apperr.Newf(apperr.UpstreamFailed, "no image received from ComfyUI output node %s", internal.OutputNode)
+	}
+	imageData := output[0]
+
+	if cacheKey != "" {
+		if err := h.Cache.Put(ctx, cacheKey, imageData); err != nil {
+			log.Printf("Error caching try-on result: %v", err)
 		}
 	}
-	// set the image path of person image
-	if node27, ok := workflow["27"].(map[string]interface{}); ok {
-		if inputs, ok := node27["inputs"].(map[string]interface{}); ok {
-			inputs["image"] = personPath
+	return imageData, false, nil
+}
+
+// comfyUIReady waits for the supervised ComfyUI to come up. Externally
+// managed backends are health-checked by the pool instead.
+func (h *TryOnHandler) comfyUIReady(ctx context.Context) error {
+	if h.Supervisor != nil && !h.ComfyUI.Healthy() {
+		if err := h.Supervisor.WaitReady(ctx); err != nil {
+			return apperr.Newf(apperr.UpstreamUnavailable, "ComfyUI is unavailable: %w", err)
 		}
 	}
+	return nil
+}
+
+// comfyUIError classifies an error of a ComfyUI prompt. Node errors,
+// interruptions and broken connections count as failures of ComfyUI.
+func comfyUIError(err error) error {
+	switch {
+	case errors.Is(err, internal.ErrNoHealthyBackend):
+		return apperr.Wrap(apperr.UpstreamUnavailable, err)
+	case errors.Is(err, internal.ErrPromptTimeout), errors.Is(err, context.DeadlineExceeded):
+		return apperr.Wrap(apperr.Timeout, err)
+	}
+	return apperr.Wrap(apperr.UpstreamFailed, err)
+}
 
-	images, err := internal.GetImages(workflow)
+// saveTryOn uploads a rendered image and records it in the user's gallery.
+// The storage fields, workflow name and creation time of tryOn are filled
+// in here.
+func (h *TryOnHandler) saveTryOn(ctx context.Context, tryOn tryons.TryOn, imageData []byte) (tryons.TryOn, error) {
+	tryOn.ObjectKey = fmt.Sprintf("%s/%s/%s%s", TryOnResultPrefix, tryOn.UserID, tryOn.ID, internal.ImageExtension(imageData))
+	url, err := h.Storage.UploadBlob(ctx, imageData, tryOn.ObjectKey, http.DetectContentType(imageData))
 	if err != nil {
-		log.Fatal(err)
-	}
-	var imageData []byte
-	found := false
-	for _, imageList := range images {
-		if len(imageList) > 0 {
-			imageData = imageList[0]
-			found = true
-			break
-		}
+		return tryons.TryOn{}, err
 	}
-	if !found {
-		log.Fatal("No image received from ComfyUI")
+	tryOn.ImageURL = url
+	tryOn.Workflow = filepath.Base(h.workflowPath())
+	tryOn.CreatedAt = time.Now().UTC()
+	if err := h.TryOns.SaveTryOn(ctx, tryOn); err != nil {
+		return tryons.TryOn{}, err
 	}
+	return tryOn, nil
+}
 
-	// Set appropriate headers
-	c.Header("Content-Type", "image/jpeg")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=vton_result_%s.jpg", sessionID))
+// galleryParams returns the parameters recorded with a try-on, including
+// the preset that chose them.
+func galleryParams(params internal.TryOnParams) map[string]interface{} {
+	recorded := params.CacheParams()
+	if params.Preset != "" {
+		recorded["preset"] = params.Preset
+	}
+	return recorded
+}
 
-	// Send the image in response
-	c.Data(http.StatusOK, "image/jpeg", imageData)
+// debugContext opts a job into debug artifact recording when the request
+// sets debug=true and a recorder is configured.
+func (h *TryOnHandler) debugContext(ctx context.Context, c *app.RequestContext, jobID string) context.Context {
+	if debug, _ := strconv.ParseBool(c.PostForm("debug")); !debug {
+		return ctx
+	}
+	return internal.WithDebugJob(ctx, h.Debug.Job(jobID))
+}
 
-	// Clean up temp files
-	os.Remove(personPath)
-	os.Remove(garmentPath)
+// uploadMaskPrompt classifies an uploaded garment with the segmenter and
+// returns the mask prompt for its type. It falls back to the default
+// prompt when the garment cannot be classified.
+func (h *TryOnHandler) uploadMaskPrompt(ctx context.Context, garmentPath string) string {
+	data, err := os.ReadFile(garmentPath)
+	if err != nil {
+		log.Printf("Error reading garment %s for classification: %v", garmentPath, err)
+		return internal.DefaultMaskPrompt
+	}
+	if h.Segmenter == nil {
+		return internal.DefaultMaskPrompt
+	}
+	segmented, err := h.Segmenter.Segment(ctx, data)
+	if err != nil {
+		log.Printf("Error classifying garment %s: %v", garmentPath, err)
+		return internal.DefaultMaskPrompt
+	}
+	item, ok := internal.PrimaryItem(segmented)
+	if !ok {
+		log.Printf("Segmenter found no garment in %s", garmentPath)
+		return internal.DefaultMaskPrompt
+	}
+	return h.MaskPrompts.For(internal.NormalizeAttributes(item.Metadata, nil))
+}
+
+// personImage saves the person image of a request to the temp directory:
+// the person_image upload, the saved photo named by person_photo_id, or
+// the user's default photo. On failure it writes the error response and
+// returns false.
+func (h *TryOnHandler) personImage(ctx context.Context, c *app.RequestContext, form *multipart.Form, sessionID string) (personPath, personPhotoId string, ok bool) {
+	userId, _ := userIDFromContext(c)
+	if personFiles := form.File["person_image"]; len(personFiles) > 0 {
+		personHeader := personFiles[0]
+		personPath = filepath.Join(TempDir, fmt.Sprintf("person_%s%s", sessionID, filepath.Ext(personHeader.Filename)))
+		if err := c.SaveUploadedFile(personHeader, personPath); err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to save person image: %w", err))
+			return "", "", false
+		}
+	} else {
+		var photo photos.PersonPhoto
+		var err error
+		if photoId := c.PostForm("person_photo_id"); photoId != "" {
+			photo, err = h.Photos.GetPhoto(ctx, userId, photoId)
+			if errors.Is(err, photos.ErrPhotoNotFound) {
+				response.Error(c, apperr.New(apperr.NotFound, "person photo not found"))
+				return "", "", false
+			}
+		} else {
+			photo, err = h.Photos.GetDefaultPhoto(ctx, userId)
+			if errors.Is(err, photos.ErrPhotoNotFound) {
+				response.Error(c, apperr.New(apperr.Validation, "person_image is required when no saved person photo is selected"))
+				return "", "", false
+			}
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load person photo: %w", err))
+			return "", "", false
+		}
+		personPhotoId = photo.ID
+		personPath, err = h.downloadToTemp(ctx, photo.ObjectKey, "person_"+sessionID)
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load person photo: %w", err))
+			return "", "", false
+		}
+	}
+	return personPath, personPhotoId, true
+}
+
+// cacheKeyFor reads both input images and hashes them with the workflow
+// template and the generation parameters.
+func cacheKeyFor(personPath, garmentPath string, workflow []byte, params map[string]interface{}) (string, error) {
+	personData, err := os.ReadFile(personPath)
+	if err != nil {
+		return "", err
+	}
+	garmentData, err := os.ReadFile(garmentPath)
+	if err != nil {
+		return "", err
+	}
+	return tryoncache.Key(personData, garmentData, workflow, params)
+}
+
+// workflowPath returns the path of the try-on workflow template.
+func (h *TryOnHandler) workflowPath() string {
+	if h.WorkflowPath == "" {
+		return DefaultWorkflowPath
+	}
+	return h.WorkflowPath
+}
+
+// downloadToTemp fetches a stored blob into the temp directory, keeping
+// the key's extension so ComfyUI can detect the image type.
+func (h *TryOnHandler) downloadToTemp(ctx context.Context, key, name string) (string, error) {
+	data, err := h.Storage.GetBlob(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(TempDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(TempDir, name+filepath.Ext(key))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
 func getTempDir() string {
 	wd, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Error getting working directory, using the system temp directory: %v", err)
+		wd = os.TempDir()
 	}
 	return filepath.Join(wd, "temp_files")
 }
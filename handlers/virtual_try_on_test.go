@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/comfyuitest"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/tryoncache"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+const testUserID = "user-1"
+
+// memStorage is an in-memory storage.StorageService.
+type memStorage struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+	types map[string]string
+}
+
+func (s *memStorage) UploadBlob(ctx context.Context, data []byte, key, contentType string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = data
+	s.types[key] = contentType
+	return "https://cdn.example.com/" + key, nil
+}
+
+func (s *memStorage) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, errors.New("blob not found")
+	}
+	return data, nil
+}
+
+func (s *memStorage) DeleteBlob(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+type tryOnTest struct {
+	handler *TryOnHandler
+	comfy   *comfyuitest.Server
+	pool    *internal.Pool
+	storage *memStorage
+	tryOns  *tryons.FileStore
+	engine  *route.Engine
+}
+
+// newTryOnTest serves TryOnHandler backed by a fake ComfyUI, in-memory
+// storage and file stores in a temp directory.
+func newTryOnTest(t *testing.T) *tryOnTest {
+	t.Helper()
+	// The workflow template is read relative to the repository root
+	t.Chdir("..")
+	dir := t.TempDir()
+	oldTempDir := TempDir
+	TempDir = filepath.Join(dir, "temp_files")
+	t.Cleanup(func() { TempDir = oldTempDir })
+	if err := os.MkdirAll(TempDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	comfy := comfyuitest.NewServer()
+	t.Cleanup(comfy.Close)
+	pool, err := internal.NewPool([]string{comfy.URL}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.CheckAll(context.Background())
+
+	storageSvc := &memStorage{blobs: make(map[string][]byte), types: make(map[string]string)}
+	photoStore, err := photos.NewFileStore(filepath.Join(dir, "photos.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wardrobeStore, err := wardrobe.NewFileStore(filepath.Join(dir, "wardrobe.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tryOnStore, err := tryons.NewFileStore(filepath.Join(dir, "tryons.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := tryoncache.NewCache(storageSvc, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &TryOnHandler{
+		Storage:     storageSvc,
+		Photos:      photoStore,
+		Wardrobe:    wardrobeStore,
+		TryOns:      tryOnStore,
+		Cache:       cache,
+		Batches:     tryonbatch.NewTracker(0, 0),
+		ComfyUI:     pool,
+		MaskPrompts: internal.DefaultMaskPrompts,
+	}
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.POST("/virtual-tryon", func(ctx context.Context, c *app.RequestContext) {
+		c.Set("userId", testUserID)
+		c.Next(ctx)
+	}, h.VirtualTryOnHandler)
+
+	return &tryOnTest{handler: h, comfy: comfy, pool: pool, storage: storageSvc, tryOns: tryOnStore, engine: engine}
+}
+
+// post sends a try-on request with uploaded person and garment images.
+func (tt *tryOnTest) post(t *testing.T, fields map[string]string) *ut.ResponseRecorder {
+	t.Helper()
+	return tt.postFiles(t, map[string]string{"person_image": "person bytes", "garment_image": "garment bytes"}, fields)
+}
+
+// postFiles sends a try-on request with the given uploads.
+func (tt *tryOnTest) postFiles(t *testing.T, files, fields map[string]string) *ut.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, content := range files {
+		part, err := w.CreateFormFile(name, name+".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	w.Close()
+
+	return ut.PerformRequest(tt.engine, http.MethodPost, "/virtual-tryon",
+		&ut.Body{Body: &body, Len: body.Len()},
+		ut.Header{Key: "Content-Type", Value: w.FormDataContentType()})
+}
+
+func TestVirtualTryOnRendersWithComfyUI(t *testing.T) {
+	tt := newTryOnTest(t)
+
+	resp := tt.post(t, map[string]string{"prompt": "dress", "steps": "20", "seed": "42"}).Result()
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d, body %q", resp.StatusCode(), resp.Body())
+	}
+	if !bytes.Equal(resp.Body(), tt.comfy.Image) {
+		t.Errorf("body is not the rendered image")
+	}
+	var effective internal.TryOnParams
+	if err := json.Unmarshal(resp.Header.Peek("X-TryOn-Params"), &effective); err != nil || effective.Steps != 20 || effective.Seed != 42 {
+		t.Errorf("X-TryOn-Params = %q, want steps 20 and seed 42", resp.Header.Peek("X-TryOn-Params"))
+	}
+	if got := string(resp.Header.Peek("X-Cache")); got != "MISS" {
+		t.Errorf("X-Cache = %q, want MISS", got)
+	}
+
+	prompts := tt.comfy.Prompts()
+	if len(prompts) != 1 {
+		t.Fatalf("ComfyUI got %d prompts, want 1", len(prompts))
+	}
+	workflow := prompts[0].Workflow
+	for _, check := range []struct {
+		node, input string
+		want        interface{}
+	}{
+		{internal.MaskNode, "prompt", "dress"},
+		{internal.TryOnNode, "steps", float64(20)},
+		{internal.TryOnNode, "seed", float64(42)},
+	} {
+		if got := internal.WorkflowInput(workflow, check.node, check.input); got != check.want {
+			t.Errorf("node %s input %s = %v, want %v", check.node, check.input, got, check.want)
+		}
+	}
+	if person, _ := internal.WorkflowInput(workflow, internal.PersonImageNode, "image").(string); !strings.HasPrefix(filepath.Base(person), "person_") {
+		t.Errorf("person image = %q, want the uploaded person", person)
+	}
+
+	tryOnID := string(resp.Header.Peek("X-TryOn-Id"))
+	saved, err := tt.tryOns.GetTryOn(context.Background(), testUserID, tryOnID)
+	if err != nil {
+		t.Fatalf("try-on %q not saved: %v", tryOnID, err)
+	}
+	if stored, _ := tt.storage.GetBlob(context.Background(), saved.ObjectKey); !bytes.Equal(stored, tt.comfy.Image) {
+		t.Errorf("stored result is not the rendered image")
+	}
+
+	// The same inputs are served from the cache without ComfyUI
+	resp = tt.post(t, map[string]string{"prompt": "dress", "steps": "20", "seed": "42"}).Result()
+	if resp.StatusCode() != http.StatusOK || string(resp.Header.Peek("X-Cache")) != "HIT" {
+		t.Errorf("repeat: status = %d, X-Cache = %q, want 200 HIT", resp.StatusCode(), resp.Header.Peek("X-Cache"))
+	}
+	if n := len(tt.comfy.Prompts()); n != 1 {
+		t.Errorf("ComfyUI got %d prompts after a cache hit, want 1", n)
+	}
+}
+
+// savedOutput reports node as having saved an image to disk, which is
+// fetched with /view.
+func savedOutput(node string) comfyuitest.Step {
+	return comfyuitest.Step{Type: "executed", Data: map[string]interface{}{
+		"node":   node,
+		"output": map[string]interface{}{"images": []interface{}{map[string]interface{}{"filename": "saved.png", "type": "output"}}},
+	}}
+}
+
+func TestVirtualTryOnKeepsImageFormat(t *testing.T) {
+	var pngResult, jpegResult bytes.Buffer
+	if err := png.Encode(&pngResult, image.NewGray(image.Rect(0, 0, 3, 3))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegResult, image.NewGray(image.Rect(0, 0, 3, 3)), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name        string
+		result      []byte
+		contentType string
+		extension   string
+	}{
+		{"png", pngResult.Bytes(), "image/png", ".png"},
+		{"jpeg", jpegResult.Bytes(), "image/jpeg", ".jpg"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTryOnTest(t)
+			tt.comfy.SetSteps(comfyuitest.Executing(internal.OutputNode), comfyuitest.Image(tc.result), comfyuitest.Executing(""))
+
+			resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+			if resp.StatusCode() != http.StatusOK {
+				t.Fatalf("status = %d, body %q", resp.StatusCode(), resp.Body())
+			}
+			if got := string(resp.Header.ContentType()); got != tc.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tc.contentType)
+			}
+			if got := string(resp.Header.Peek("Content-Disposition")); !strings.HasSuffix(got, tc.extension) {
+				t.Errorf("Content-Disposition = %q, want a %s file", got, tc.extension)
+			}
+
+			// Both the gallery and the cache keep the rendered format
+			saved, err := tt.tryOns.GetTryOn(context.Background(), testUserID, string(resp.Header.Peek("X-TryOn-Id")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cached string
+			for key := range tt.storage.blobs {
+				if strings.HasPrefix(key, tryoncache.CachePrefix+"/") {
+					cached = key
+				}
+			}
+			for _, key := range []string{saved.ObjectKey, cached} {
+				if !strings.HasSuffix(key, tc.extension) || tt.storage.types[key] != tc.contentType {
+					t.Errorf("stored %q as %q, want a %s file of %s", key, tt.storage.types[key], tc.extension, tc.contentType)
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualTryOnUsesOutputNode(t *testing.T) {
+	var result bytes.Buffer
+	if err := png.Encode(&result, image.NewGray(image.Rect(0, 0, 3, 3))); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		steps []comfyuitest.Step
+		// ok is set when the output node's image is served
+		ok bool
+	}{
+		{"other node saves first", []comfyuitest.Step{
+			comfyuitest.Executing("12"), savedOutput("12"),
+			comfyuitest.Executing(internal.OutputNode), comfyuitest.Image(result.Bytes()),
+		}, true},
+		{"other node saves last", []comfyuitest.Step{
+			comfyuitest.Executing(internal.OutputNode), comfyuitest.Image(result.Bytes()),
+			comfyuitest.Executing("12"), savedOutput("12"),
+		}, true},
+		{"only another node", []comfyuitest.Step{comfyuitest.Executing("12"), savedOutput("12")}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTryOnTest(t)
+			tt.comfy.SetSteps(append(tc.steps, comfyuitest.Executing(""))...)
+			// Map order is random, so try more than once
+			for i := 0; i < 5; i++ {
+				resp := tt.post(t, map[string]string{"prompt": "shirt", "seed": strconv.Itoa(i)}).Result()
+				if !tc.ok {
+					expectError(t, resp, http.StatusBadGateway, "UPSTREAM_FAILED")
+					continue
+				}
+				if resp.StatusCode() != http.StatusOK || !bytes.Equal(resp.Body(), result.Bytes()) {
+					t.Fatalf("status %d, want the output node's image", resp.StatusCode())
+				}
+			}
+		})
+	}
+}
+
+func TestVirtualTryOnSocketDropFails(t *testing.T) {
+	tt := newTryOnTest(t)
+	// The shipped workflow only sends its output over the websocket, so
+	// /history has nothing to recover once the socket drops
+	tt.comfy.SetSteps(
+		comfyuitest.Drop(),
+		comfyuitest.Executing(internal.OutputNode),
+		comfyuitest.Executing(""),
+	)
+
+	logged := captureLog(t)
+	resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+	expectError(t, resp, http.StatusBadGateway, "UPSTREAM_FAILED")
+	expectLoggedOnly(t, resp, logged, "websocket")
+	if got := tt.tryOnCount(t); got != 0 {
+		t.Errorf("%d try-ons saved for a failed render", got)
+	}
+}
+
+func TestVirtualTryOnReportsNodeErrors(t *testing.T) {
+	tt := newTryOnTest(t)
+	tt.comfy.SetSteps(
+		comfyuitest.Executing(internal.MaskNode),
+		comfyuitest.ExecutionError(internal.MaskNode, "GroundingDinoSAMSegment (segment anything)", "RuntimeError", "CUDA out of memory"),
+	)
+
+	logged := captureLog(t)
+	resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+	expectError(t, resp, http.StatusBadGateway, "UPSTREAM_FAILED")
+	expectLoggedOnly(t, resp, logged, "CUDA out of memory")
+	if got := tt.tryOnCount(t); got != 0 {
+		t.Errorf("%d try-ons saved for a failed render", got)
+	}
+}
+
+func TestVirtualTryOnTimesOut(t *testing.T) {
+	tt := newTryOnTest(t)
+	tt.pool.SetTimeout(200 * time.Millisecond)
+	tt.comfy.SetSteps(comfyuitest.Executing(internal.TryOnNode), comfyuitest.Hang())
+
+	logged := captureLog(t)
+	resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+	expectError(t, resp, http.StatusGatewayTimeout, "TIMEOUT")
+	expectLoggedOnly(t, resp, logged, internal.ErrPromptTimeout.Error())
+	// The abandoned prompt is interrupted on ComfyUI
+	tt.comfy.WaitIdle()
+	prompts := tt.comfy.Prompts()
+	if len(prompts) != 1 || !slices.Equal(tt.comfy.Interrupted(), []string{prompts[0].ID}) {
+		t.Errorf("interrupted %v, want the prompt of %v", tt.comfy.Interrupted(), prompts)
+	}
+}
+
+func TestVirtualTryOnComfyUIDown(t *testing.T) {
+	tt := newTryOnTest(t)
+	tt.comfy.SetDown(true)
+	tt.pool.CheckAll(context.Background())
+
+	logged := captureLog(t)
+	resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+	expectError(t, resp, http.StatusServiceUnavailable, "UPSTREAM_UNAVAILABLE")
+	expectLoggedOnly(t, resp, logged, internal.ErrNoHealthyBackend.Error())
+	if n := len(tt.comfy.Prompts()); n != 0 {
+		t.Errorf("ComfyUI got %d prompts while down", n)
+	}
+}
+
+func TestVirtualTryOnRejectsInvalidParams(t *testing.T) {
+	tt := newTryOnTest(t)
+
+	resp := tt.post(t, map[string]string{"prompt": "shirt", "steps": "1000"}).Result()
+	expectError(t, resp, http.StatusBadRequest, "VALIDATION_ERROR")
+	if n := len(tt.comfy.Prompts()); n != 0 {
+		t.Errorf("ComfyUI got %d prompts for an invalid request", n)
+	}
+}
+
+func TestVirtualTryOnMissingWorkflow(t *testing.T) {
+	tt := newTryOnTest(t)
+	t.Chdir(t.TempDir())
+
+	resp := tt.post(t, map[string]string{"prompt": "shirt"}).Result()
+	expectError(t, resp, http.StatusInternalServerError, "INTERNAL_ERROR")
+	// The path of the workflow is only logged
+	if body := string(resp.Body()); strings.Contains(body, "ImageWorkflow.json") {
+		t.Errorf("body %q leaks the internal error", body)
+	}
+}
+
+func TestVirtualTryOnMissingPersonPhoto(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fields map[string]string
+		status int
+		code   string
+		want   string
+	}{
+		{"unknown photo ID", map[string]string{"person_photo_id": "missing"}, http.StatusNotFound, "NOT_FOUND", "person photo not found"},
+		{"no default photo", nil, http.StatusBadRequest, "VALIDATION_ERROR", "person_image is required"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTryOnTest(t)
+			resp := tt.postFiles(t, map[string]string{"garment_image": "garment bytes"}, tc.fields).Result()
+			expectError(t, resp, tc.status, tc.code)
+			if body := string(resp.Body()); !strings.Contains(body, tc.want) {
+				t.Errorf("body %q does not mention %q", body, tc.want)
+			}
+		})
+	}
+}
+
+// expectError checks the status and error code of an error response.
+func expectError(t *testing.T, resp *protocol.Response, status int, code string) {
+	t.Helper()
+	if resp.StatusCode() != status {
+		t.Fatalf("status = %d, want %d; body %q", resp.StatusCode(), status, resp.Body())
+	}
+	var body response.Envelope
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		t.Fatalf("error response is not JSON: %v", err)
+	}
+	if body.Data != nil || body.Error == nil || body.Error.Message == "" || body.Error.Code != code {
+		t.Errorf("error response = %s, want code %s", resp.Body(), code)
+	}
+	if body.RequestID == "" || body.RequestID != string(resp.Header.Peek(response.RequestIDHeader)) {
+		t.Errorf("request_id = %q, header %q", body.RequestID, resp.Header.Peek(response.RequestIDHeader))
+	}
+}
+
+// expectLoggedOnly checks that detail is logged but kept out of the
+// response, along with the ComfyUI backend URL.
+func expectLoggedOnly(t *testing.T, resp *protocol.Response, logged *lockedBuffer, detail string) {
+	t.Helper()
+	if body := string(resp.Body()); strings.Contains(body, detail) || strings.Contains(body, "http://") {
+		t.Errorf("body %q leaks the upstream error", body)
+	}
+	if !strings.Contains(logged.String(), detail) {
+		t.Errorf("log %q does not record %q", logged.String(), detail)
+	}
+}
+
+// lockedBuffer collects log output written from several goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog collects the log output until the test ends.
+func captureLog(t *testing.T) *lockedBuffer {
+	logged := &lockedBuffer{}
+	out := log.Writer()
+	log.SetOutput(logged)
+	t.Cleanup(func() { log.SetOutput(out) })
+	return logged
+}
+
+func (tt *tryOnTest) tryOnCount(t *testing.T) int {
+	t.Helper()
+	saved, err := tt.tryOns.ListTryOns(context.Background(), testUserID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(saved)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+// WardrobeDuplicatesHandler reports groups of near-identical wardrobe items.
+func (h *ClothesHandler) WardrobeDuplicatesHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	maxDistance := internal.DefaultDuplicateDistance
+	if raw := c.Query("max_distance"); raw != "" {
+		d, err := strconv.Atoi(raw)
+		if err != nil || d < 0 || d > 64 {
+			response.Error(c, apperr.New(apperr.Validation, "max_distance must be an integer between 0 and 64"))
+			return
+		}
+		maxDistance = d
+	}
+
+	items, err := h.Wardrobe.ListItems(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wardrobe: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"max_distance": maxDistance,
+		"duplicates":   wardrobe.GroupDuplicates(items, maxDistance),
+	})
+}
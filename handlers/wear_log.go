@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
+)
+
+// RepeatWindowDays is how many days before and after the logged date
+// LogWearHandler looks for the same set of clothes to warn about repeating
+// an outfit.
+const RepeatWindowDays = 7
+
+type WearLogHandler struct {
+	WearLog  wearlog.Store
+	Outfits  outfits.Store
+	Wardrobe wardrobe.Store
+}
+
+// LogWearHandler records what the user wore on a date, given as an outfit,
+// a list of clothing IDs, or both. The date defaults to today (UTC).
+func (h *WearLogHandler) LogWearHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	type LogWearRequest struct {
+		Date        string   `json:"date"`
+		OutfitID    string   `json:"outfit_id"`
+		ClothingIDs []string `json:"clothing_ids"`
+	}
+
+	var req LogWearRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		response.Error(c, apperr.Newf(apperr.Validation, "invalid request body: %w", err))
+		return
+	}
+	if req.Date == "" {
+		req.Date = time.Now().UTC().Format(wearlog.DateLayout)
+	}
+	if _, err := time.Parse(wearlog.DateLayout, req.Date); err != nil {
+		response.Error(c, apperr.New(apperr.Validation, "date must be formatted as YYYY-MM-DD"))
+		return
+	}
+
+	var clothingIds []string
+	if req.OutfitID != "" {
+		outfit, err := h.Outfits.GetOutfit(ctx, userId, req.OutfitID)
+		if errors.Is(err, outfits.ErrOutfitNotFound) {
+			response.Error(c, apperr.New(apperr.Validation, "outfit not found"))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load outfit: %w", err))
+			return
+		}
+		for _, item := range outfit.Items {
+			clothingIds = append(clothingIds, item.ClothingID)
+		}
+	}
+	for _, id := range req.ClothingIDs {
+		if slices.Contains(clothingIds, id) {
+			continue
+		}
+		_, err := h.Wardrobe.GetItem(ctx, userId, id)
+		if errors.Is(err, wardrobe.ErrItemNotFound) {
+			response.Error(c, apperr.Newf(apperr.Validation, "clothing item %s not found in wardrobe", id))
+			return
+		}
+		if err != nil {
+			response.Error(c, apperr.Newf(apperr.Internal, "failed to load clothing item: %w", err))
+			return
+		}
+		clothingIds = append(clothingIds, id)
+	}
+	if len(clothingIds) == 0 {
+		response.Error(c, apperr.New(apperr.Validation, "outfit_id or clothing_ids is required"))
+		return
+	}
+
+	// Look for the same set of clothes worn around the date; entries may
+	// be logged after the fact, so later days count too.
+	date, _ := time.Parse(wearlog.DateLayout, req.Date)
+	from := date.AddDate(0, 0, -RepeatWindowDays).Format(wearlog.DateLayout)
+	to := date.AddDate(0, 0, RepeatWindowDays).Format(wearlog.DateLayout)
+	nearby, err := h.WearLog.ListEntries(ctx, userId, from, to)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wear log: %w", err))
+		return
+	}
+	// The closest match is reported, the earlier one on a tie
+	var repeatOf *wearlog.Entry
+	var repeatDistance time.Duration
+	for i, other := range nearby {
+		if !sameClothes(other.ClothingIDs, clothingIds) {
+			continue
+		}
+		otherDate, _ := time.Parse(wearlog.DateLayout, other.Date)
+		distance := otherDate.Sub(date).Abs()
+		if repeatOf == nil || distance < repeatDistance {
+			repeatOf, repeatDistance = &nearby[i], distance
+		}
+	}
+
+	entry := wearlog.Entry{
+		ID:          uuid.NewString(),
+		UserID:      userId,
+		Date:        req.Date,
+		OutfitID:    req.OutfitID,
+		ClothingIDs: clothingIds,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := h.WearLog.AddEntry(ctx, entry); err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to save wear log entry: %w", err))
+		return
+	}
+
+	data := map[string]interface{}{
+		"entry": entry,
+	}
+	if repeatOf != nil {
+		data["repeat_of"] = repeatOf
+		data["warning"] = fmt.Sprintf("the same clothes were worn on %s", repeatOf.Date)
+	}
+	response.Success(c, http.StatusOK, data)
+}
+
+// WearCalendarHandler returns the user's wear log for a month, given as
+// month=YYYY-MM and defaulting to the current month, grouped by day.
+func (h *WearLogHandler) WearCalendarHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	month := time.Now().UTC()
+	if raw := c.Query("month"); raw != "" {
+		parsed, err := time.Parse("2006-01", raw)
+		if err != nil {
+			response.Error(c, apperr.New(apperr.Validation, "month must be formatted as YYYY-MM"))
+			return
+		}
+		month = parsed
+	}
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+
+	entries, err := h.WearLog.ListEntries(ctx, userId, first.Format(wearlog.DateLayout), last.Format(wearlog.DateLayout))
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wear log: %w", err))
+		return
+	}
+
+	type CalendarDay struct {
+		Date    string          `json:"date"`
+		Entries []wearlog.Entry `json:"entries"`
+	}
+	days := make([]CalendarDay, 0)
+	for _, entry := range entries {
+		if len(days) == 0 || days[len(days)-1].Date != entry.Date {
+			days = append(days, CalendarDay{Date: entry.Date})
+		}
+		days[len(days)-1].Entries = append(days[len(days)-1].Entries, entry)
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"month": first.Format("2006-01"),
+		"days":  days,
+	})
+}
+
+// DeleteWearEntryHandler removes a wear log entry.
+func (h *WearLogHandler) DeleteWearEntryHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	entryId := c.Param("entryId")
+	err := h.WearLog.DeleteEntry(ctx, userId, entryId)
+	if errors.Is(err, wearlog.ErrEntryNotFound) {
+		response.Error(c, apperr.New(apperr.NotFound, "wear log entry not found"))
+		return
+	}
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to delete wear log entry: %w", err))
+		return
+	}
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"entry_id": entryId,
+	})
+}
+
+// WearStatsHandler returns the wear count and last-worn date of every item
+// in the user's wardrobe, least worn first.
+func (h *WearLogHandler) WearStatsHandler(ctx context.Context, c *app.RequestContext) {
+	userId, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.Wardrobe.ListItems(ctx, userId)
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wardrobe: %w", err))
+		return
+	}
+	entries, err := h.WearLog.ListEntries(ctx, userId, "", "")
+	if err != nil {
+		response.Error(c, apperr.Newf(apperr.Internal, "failed to load wear log: %w", err))
+		return
+	}
+
+	stats := wearlog.Stats(entries)
+	result := make([]wearlog.ItemStats, 0, len(items))
+	for _, item := range items {
+		s := stats[item.ID]
+		s.ClothingID = item.ID
+		result = append(result, s)
+	}
+	slices.SortStableFunc(result, wearlog.CompareLeastWorn)
+
+	response.Success(c, http.StatusOK, map[string]interface{}{
+		"items": result,
+	})
+}
+
+// sameClothes reports whether two clothing ID lists hold the same items.
+func sameClothes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
+)
+
+// newWearLogEngine serves LogWearHandler with in-memory stores and a
+// wardrobe holding a shirt, jeans and a jacket.
+func newWearLogEngine(t *testing.T) *route.Engine {
+	t.Helper()
+	wearLogStore, err := wearlog.NewFileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outfitStore, err := outfits.NewFileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wardrobeStore, err := wardrobe.NewFileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"shirt", "jeans", "jacket"} {
+		if err := wardrobeStore.SaveItem(context.Background(), wardrobe.ClothingItem{ID: id, UserID: testUserID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &WearLogHandler{WearLog: wearLogStore, Outfits: outfitStore, Wardrobe: wardrobeStore}
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.POST("/wear-log", func(ctx context.Context, c *app.RequestContext) {
+		c.Set("userId", testUserID)
+		c.Next(ctx)
+	}, h.LogWearHandler)
+	return engine
+}
+
+func TestLogWearRepeatWindow(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// earlier is logged before the checked entry
+		earlier, later string
+		clothes        []string
+		repeatOf       string
+	}{
+		{"same day", "2026-03-10", "2026-03-10", []string{"jeans", "shirt"}, "2026-03-10"},
+		{"inside the window", "2026-03-03", "2026-03-10", []string{"shirt", "jeans"}, "2026-03-03"},
+		{"outside the window", "2026-03-02", "2026-03-10", []string{"shirt", "jeans"}, ""},
+		{"different clothes", "2026-03-09", "2026-03-10", []string{"shirt", "jacket"}, ""},
+		{"subset of the clothes", "2026-03-09", "2026-03-10", []string{"shirt"}, ""},
+		// Days logged after the fact are checked against later entries too
+		{"logged in the past", "2026-03-10", "2026-03-09", []string{"shirt", "jeans"}, "2026-03-10"},
+		{"inside the window after", "2026-03-17", "2026-03-10", []string{"shirt", "jeans"}, "2026-03-17"},
+		{"outside the window after", "2026-03-18", "2026-03-10", []string{"shirt", "jeans"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newWearLogEngine(t)
+			logWear := func(date string, clothes []string) (wearlog.Entry, *wearlog.Entry, string) {
+				t.Helper()
+				body, _ := json.Marshal(map[string]interface{}{"date": date, "clothing_ids": clothes})
+				resp := ut.PerformRequest(engine, http.MethodPost, "/wear-log",
+					&ut.Body{Body: strings.NewReader(string(body)), Len: len(body)},
+					ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+				if resp.StatusCode() != http.StatusOK {
+					t.Fatalf("status = %d; body %s", resp.StatusCode(), resp.Body())
+				}
+				var envelope struct {
+					Data struct {
+						Entry    wearlog.Entry  `json:"entry"`
+						RepeatOf *wearlog.Entry `json:"repeat_of"`
+						Warning  string         `json:"warning"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal(resp.Body(), &envelope); err != nil {
+					t.Fatal(err)
+				}
+				return envelope.Data.Entry, envelope.Data.RepeatOf, envelope.Data.Warning
+			}
+
+			first, repeatOf, _ := logWear(tc.earlier, []string{"shirt", "jeans"})
+			if repeatOf != nil {
+				t.Fatalf("first entry repeats %+v", repeatOf)
+			}
+			_, repeatOf, warning := logWear(tc.later, tc.clothes)
+			if tc.repeatOf == "" {
+				if repeatOf != nil || warning != "" {
+					t.Errorf("repeat_of = %+v, warning %q, want none", repeatOf, warning)
+				}
+				return
+			}
+			if repeatOf == nil || repeatOf.ID != first.ID || repeatOf.Date != tc.repeatOf {
+				t.Fatalf("repeat_of = %+v, want the entry on %s", repeatOf, tc.repeatOf)
+			}
+			if !strings.Contains(warning, tc.repeatOf) {
+				t.Errorf("warning %q does not mention %s", warning, tc.repeatOf)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package apperr classifies errors by what went wrong, so that handlers
+// can answer every failure with a consistent status code and error code.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind is the class of an error.
+type Kind string
+
+const (
+	// Validation means the request itself is invalid.
+	Validation Kind = "validation"
+	// Unauthorized means the request lacks valid credentials.
+	Unauthorized Kind = "unauthorized"
+	// Forbidden means the user may not do this.
+	Forbidden Kind = "forbidden"
+	// NotFound means a resource the request names does not exist.
+	NotFound Kind = "not_found"
+	// UpstreamUnavailable means a service we depend on cannot be reached.
+	UpstreamUnavailable Kind = "upstream_unavailable"
+	// UpstreamFailed means a service we depend on failed the request.
+	UpstreamFailed Kind = "upstream_failed"
+	// Timeout means the work did not finish in time.
+	Timeout Kind = "timeout"
+	// Internal is everything else.
+	Internal Kind = "internal"
+)
+
+// Status returns the HTTP status code errors of the kind are answered with.
+func (k Kind) Status() int {
+	switch k {
+	case Validation:
+		return http.StatusBadRequest
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Forbidden:
+		return http.StatusForbidden
+	case NotFound:
+		return http.StatusNotFound
+	case UpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case UpstreamFailed:
+		return http.StatusBadGateway
+	case Timeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the stable error code clients match on.
+func (k Kind) Code() string {
+	switch k {
+	case Validation:
+		return "VALIDATION_ERROR"
+	case Unauthorized:
+		return "UNAUTHORIZED"
+	case Forbidden:
+		return "FORBIDDEN"
+	case NotFound:
+		return "NOT_FOUND"
+	case UpstreamUnavailable:
+		return "UPSTREAM_UNAVAILABLE"
+	case UpstreamFailed:
+		return "UPSTREAM_FAILED"
+	case Timeout:
+		return "TIMEOUT"
+	}
+	return "INTERNAL_ERROR"
+}
+
+// Error is an error of a known kind. Its message is shown to clients,
+// except for Internal, upstream and Timeout errors.
+type Error struct {
+	Kind Kind
+	// Details are extra fields that help clients handle the error.
+	Details map[string]interface{}
+	err     error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// New returns an error of the given kind with a fixed message.
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, err: errors.New(message)}
+}
+
+// Newf returns an error of the given kind formatted like fmt.Errorf, so
+// %w keeps the cause available to errors.Is and errors.As.
+func Newf(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives err a kind, keeping its message.
+func Wrap(kind Kind, err error) *Error {
+	return &Error{Kind: kind, err: err}
+}
+
+// WithDetails attaches details to err, keeping its kind and message.
+func WithDetails(err error, details map[string]interface{}) *Error {
+	return &Error{Kind: KindOf(err), Details: details, err: err}
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain, or
+// Internal if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
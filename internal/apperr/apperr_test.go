@@ -0,0 +1,80 @@
+package apperr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+)
+
+func TestKind(t *testing.T) {
+	for _, tc := range []struct {
+		kind   apperr.Kind
+		status int
+		code   string
+	}{
+		{apperr.Validation, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{apperr.Unauthorized, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{apperr.Forbidden, http.StatusForbidden, "FORBIDDEN"},
+		{apperr.NotFound, http.StatusNotFound, "NOT_FOUND"},
+		{apperr.UpstreamUnavailable, http.StatusServiceUnavailable, "UPSTREAM_UNAVAILABLE"},
+		{apperr.UpstreamFailed, http.StatusBadGateway, "UPSTREAM_FAILED"},
+		{apperr.Timeout, http.StatusGatewayTimeout, "TIMEOUT"},
+		{apperr.Internal, http.StatusInternalServerError, "INTERNAL_ERROR"},
+		{apperr.Kind("unknown"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	} {
+		t.Run(string(tc.kind), func(t *testing.T) {
+			if tc.kind.Status() != tc.status || tc.kind.Code() != tc.code {
+				t.Errorf("status %d code %s, want %d and %s", tc.kind.Status(), tc.kind.Code(), tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	notFound := apperr.New(apperr.NotFound, "outfit not found")
+	for _, tc := range []struct {
+		name string
+		err  error
+		want apperr.Kind
+	}{
+		{"plain error", errors.New("boom"), apperr.Internal},
+		{"new", notFound, apperr.NotFound},
+		{"wrapped by fmt", fmt.Errorf("loading: %w", notFound), apperr.NotFound},
+		{"wrap", apperr.Wrap(apperr.Timeout, context.DeadlineExceeded), apperr.Timeout},
+		// The outermost kind wins
+		{"rewrapped", apperr.Newf(apperr.Validation, "bad outfit: %w", notFound), apperr.Validation},
+		{"details keep the kind", apperr.WithDetails(notFound, map[string]interface{}{"id": "o1"}), apperr.NotFound},
+		{"details on a plain error", apperr.WithDetails(errors.New("boom"), nil), apperr.Internal},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := apperr.KindOf(tc.err); got != tc.want {
+				t.Errorf("kind = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrorKeepsCause(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  *apperr.Error
+		msg  string
+	}{
+		{"newf", apperr.Newf(apperr.UpstreamFailed, "ComfyUI failed: %w", context.Canceled), "ComfyUI failed: context canceled"},
+		{"wrap", apperr.Wrap(apperr.Timeout, context.Canceled), "context canceled"},
+		{"details", apperr.WithDetails(fmt.Errorf("render: %w", context.Canceled), map[string]interface{}{"node": "24"}), "render: context canceled"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.err.Error() != tc.msg {
+				t.Errorf("message = %q, want %q", tc.err.Error(), tc.msg)
+			}
+			if !errors.Is(tc.err, context.Canceled) {
+				t.Error("cause lost")
+			}
+		})
+	}
+}
@@ -2,18 +2,16 @@ package auth
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userId string) (string, error) {
-	jwtSecretStr := os.Getenv("JWT_SECRET")
-	if jwtSecretStr == "" {
-		return "", errors.New("JWT_SECRET not set in environment")
+// GenerateJWT issues a token for userId signed with jwtSecret.
+func GenerateJWT(jwtSecret []byte, userId string) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", errors.New("JWT secret is empty")
 	}
-	jwtSecret := []byte(jwtSecretStr)
 
 	// Set standard JWT claims
 	claims := jwt.MapClaims{
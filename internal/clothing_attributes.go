@@ -0,0 +1,375 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Garment categories. Each one corresponds to a body slot an item can fill.
+const (
+	CategoryTop       = "top"
+	CategoryBottom    = "bottom"
+	CategoryShoes     = "shoes"
+	CategoryOuterwear = "outerwear"
+	CategoryAccessory = "accessory"
+)
+
+// Formality levels, from least to most formal.
+const (
+	FormalityAthletic    = "athletic"
+	FormalityCasual      = "casual"
+	FormalitySmartCasual = "smart_casual"
+	FormalityBusiness    = "business"
+	FormalityFormal      = "formal"
+)
+
+// Patterns recognised for garments.
+const (
+	PatternSolid      = "solid"
+	PatternMulticolor = "multicolor"
+	PatternStriped    = "striped"
+	PatternChecked    = "checked"
+	PatternFloral     = "floral"
+	PatternDotted     = "dotted"
+	PatternGraphic    = "graphic"
+	PatternPrinted    = "printed"
+)
+
+// Seasons a garment can be worn in.
+const (
+	SeasonSpring = "spring"
+	SeasonSummer = "summer"
+	SeasonFall   = "fall"
+	SeasonWinter = "winter"
+)
+
+var (
+	Categories  = []string{CategoryTop, CategoryBottom, CategoryShoes, CategoryOuterwear, CategoryAccessory}
+	Formalities = []string{FormalityAthletic, FormalityCasual, FormalitySmartCasual, FormalityBusiness, FormalityFormal}
+	Patterns    = []string{PatternSolid, PatternMulticolor, PatternStriped, PatternChecked, PatternFloral, PatternDotted, PatternGraphic, PatternPrinted}
+	Seasons     = []string{SeasonSpring, SeasonSummer, SeasonFall, SeasonWinter}
+)
+
+// ErrUnknownCategory is returned by Validate when no category could be
+// derived from the segmenter labels.
+var ErrUnknownCategory = errors.New("garment category could not be determined")
+
+// ClothingAttributes is the typed, normalized description of a garment.
+// Only values from the controlled vocabularies above are stored; anything
+// else the segmenter emitted is listed in Unknown as "key:value".
+type ClothingAttributes struct {
+	Category    string          `json:"category"`
+	Subcategory string          `json:"subcategory"`
+	Colors      []DominantColor `json:"colors"`
+	Pattern     string          `json:"pattern"`
+	Seasons     []string        `json:"seasons"`
+	Formality   string          `json:"formality"`
+	Confidence  float64         `json:"confidence"`
+	Unknown     []string        `json:"unknown,omitempty"`
+}
+
+// subcategoryLabels maps segmenter "types" labels to category and subcategory.
+var subcategoryLabels = map[string][2]string{
+	"t-shirt":             {CategoryTop, "t-shirt"},
+	"long-sleeve t-shirt": {CategoryTop, "long-sleeve t-shirt"},
+	"sleeveless t-shirt":  {CategoryTop, "tank top"},
+	"polo shirt":          {CategoryTop, "polo"},
+	"tanks & camis":       {CategoryTop, "tank top"},
+	"crop tops":           {CategoryTop, "crop top"},
+	"blouses":             {CategoryTop, "blouse"},
+	"shirts":              {CategoryTop, "shirt"},
+	"sweatshirts":         {CategoryTop, "sweatshirt"},
+	"hoodies":             {CategoryTop, "hoodie"},
+	"sweaters":            {CategoryTop, "sweater"},
+	"sweater vests":       {CategoryTop, "sweater vest"},
+	"cardigan tops":       {CategoryOuterwear, "cardigan"},
+	"sports tops":         {CategoryTop, "sports top"},
+	"bodysuits":           {CategoryTop, "bodysuit"},
+	"jeans":               {CategoryBottom, "jeans"},
+	"trousers":            {CategoryBottom, "trousers"},
+	"pants":               {CategoryBottom, "trousers"},
+	"formal pants":        {CategoryBottom, "trousers"},
+	"chinos":              {CategoryBottom, "chinos"},
+	"cargo pants":         {CategoryBottom, "cargo pants"},
+	"shorts":              {CategoryBottom, "shorts"},
+	"athletic shorts":     {CategoryBottom, "shorts"},
+	"denim shorts":        {CategoryBottom, "shorts"},
+	"bike shorts":         {CategoryBottom, "shorts"},
+	"skirts":              {CategoryBottom, "skirt"},
+	"maxi skirt":          {CategoryBottom, "skirt"},
+	"mini skirt":          {CategoryBottom, "skirt"},
+	"midi skirt":          {CategoryBottom, "skirt"},
+	"leggings":            {CategoryBottom, "leggings"},
+	"joggers":             {CategoryBottom, "joggers"},
+	"sweatpants":          {CategoryBottom, "joggers"},
+	"track pants":         {CategoryBottom, "joggers"},
+	"culottes":            {CategoryBottom, "culottes"},
+	"capris":              {CategoryBottom, "capris"},
+}
+
+// categoryLabels maps segmenter "categories" labels to categories.
+var categoryLabels = map[string]string{
+	"tops":      CategoryTop,
+	"top":       CategoryTop,
+	"bottoms":   CategoryBottom,
+	"bottom":    CategoryBottom,
+	"shoes":     CategoryShoes,
+	"outerwear": CategoryOuterwear,
+	"accessory": CategoryAccessory,
+}
+
+// seasonLabels maps segmenter "seasons" labels to seasons.
+var seasonLabels = map[string]string{
+	"spring": SeasonSpring,
+	"summer": SeasonSummer,
+	"fall":   SeasonFall,
+	"autumn": SeasonFall,
+	"winter": SeasonWinter,
+}
+
+// patternLabels maps segmenter "patterns" labels to patterns.
+var patternLabels = map[string]string{
+	"solid":   PatternSolid,
+	"plain":   PatternSolid,
+	"striped": PatternStriped,
+	"stripes": PatternStriped,
+	"checked": PatternChecked,
+	"plaid":   PatternChecked,
+	"floral":  PatternFloral,
+	"dotted":  PatternDotted,
+	"polka":   PatternDotted,
+	"graphic": PatternGraphic,
+	"printed": PatternPrinted,
+}
+
+// Occasions a user can dress for, as labelled by the segmenter.
+var Occasions = []string{"daily", "work", "date", "formal", "travel", "home", "party", "sport", "special", "school", "beach"}
+
+// formalityStyles and formalityOccasions rank segmenter labels by the
+// formality they imply. The most formal match wins, except that athletic
+// labels only apply when nothing dressier matched.
+var formalityStyles = map[string]string{
+	"formal":          FormalityFormal,
+	"dress-up":        FormalityFormal,
+	"luxury":          FormalitySmartCasual,
+	"business casual": FormalityBusiness,
+	"classic":         FormalitySmartCasual,
+	"chic":            FormalitySmartCasual,
+	"premium":         FormalitySmartCasual,
+	"minimalist":      FormalitySmartCasual,
+	"modern":          FormalityCasual,
+	"casual":          FormalityCasual,
+	"comfortable":     FormalityCasual,
+	"basic":           FormalityCasual,
+	"affordable":      FormalityCasual,
+	"trendy":          FormalityCasual,
+	"street":          FormalityCasual,
+	"hipster":         FormalityCasual,
+	"bohemian":        FormalityCasual,
+	"artistic":        FormalityCasual,
+	"kidcore":         FormalityCasual,
+	"feminine":        FormalityCasual,
+	"sporty":          FormalityAthletic,
+	"athleisure":      FormalityAthletic,
+}
+
+var formalityOccasions = map[string]string{
+	"formal":  FormalityFormal,
+	"special": FormalityFormal,
+	"work":    FormalityBusiness,
+	"date":    FormalitySmartCasual,
+	"party":   FormalitySmartCasual,
+	"daily":   FormalityCasual,
+	"travel":  FormalityCasual,
+	"home":    FormalityCasual,
+	"school":  FormalityCasual,
+	"beach":   FormalityCasual,
+	"sport":   FormalityAthletic,
+}
+
+// OccasionFormality returns the formality an occasion calls for.
+func OccasionFormality(occasion string) (string, bool) {
+	formality, ok := formalityOccasions[occasion]
+	return formality, ok
+}
+
+// solidColorShare is the share a single color family needs for a garment
+// without a pattern label to be treated as solid.
+const solidColorShare = 0.75
+
+// NormalizeAttributes converts the free-form metadata returned by the
+// segmenter, together with colors extracted in Go, into ClothingAttributes.
+// Keys other than the ones read here are ignored.
+func NormalizeAttributes(metadata map[string]interface{}, colors []DominantColor) ClothingAttributes {
+	attrs := ClothingAttributes{
+		Colors:  colors,
+		Seasons: make([]string, 0),
+	}
+	if attrs.Colors == nil {
+		attrs.Colors = make([]DominantColor, 0)
+	}
+	confidence := 1.0
+
+	var categories []string
+	for _, label := range metadataLabels(metadata, "categories") {
+		if category, ok := categoryLabels[label]; ok {
+			categories = appendUnique(categories, category)
+		} else {
+			attrs.Unknown = append(attrs.Unknown, "categories:"+label)
+		}
+	}
+
+	for _, label := range metadataLabels(metadata, "types") {
+		mapped, ok := subcategoryLabels[label]
+		if !ok {
+			attrs.Unknown = append(attrs.Unknown, "types:"+label)
+			continue
+		}
+		// Prefer the first type that agrees with the reported category.
+		if attrs.Subcategory == "" && (len(categories) == 0 || slices.Contains(categories, mapped[0]) || mapped[0] == CategoryOuterwear) {
+			attrs.Category, attrs.Subcategory = mapped[0], mapped[1]
+		}
+	}
+	if attrs.Subcategory == "" {
+		confidence -= 0.2
+	}
+	if attrs.Category == "" && len(categories) > 0 {
+		attrs.Category = categories[0]
+	}
+	if len(categories) > 1 {
+		confidence -= 0.25
+	}
+
+	for _, label := range metadataLabels(metadata, "seasons") {
+		if season, ok := seasonLabels[label]; ok {
+			attrs.Seasons = appendUnique(attrs.Seasons, season)
+		} else {
+			attrs.Unknown = append(attrs.Unknown, "seasons:"+label)
+		}
+	}
+	slices.SortFunc(attrs.Seasons, func(a, b string) int {
+		return slices.Index(Seasons, a) - slices.Index(Seasons, b)
+	})
+
+	for _, label := range metadataLabels(metadata, "patterns") {
+		if pattern, ok := patternLabels[label]; ok {
+			if attrs.Pattern == "" {
+				attrs.Pattern = pattern
+			}
+		} else {
+			attrs.Unknown = append(attrs.Unknown, "patterns:"+label)
+		}
+	}
+	if attrs.Pattern == "" {
+		attrs.Pattern = PatternMulticolor
+		if len(colors) > 0 && colors[0].Share >= solidColorShare {
+			attrs.Pattern = PatternSolid
+		}
+	}
+
+	attrs.Formality = deriveFormality(metadata, &attrs)
+
+	attrs.Confidence = confidence - 0.05*float64(len(attrs.Unknown))
+	if raw, ok := metadata["confidence"].(float64); ok {
+		attrs.Confidence = raw
+	}
+	attrs.Confidence = math.Round(math.Max(0, math.Min(1, attrs.Confidence))*100) / 100
+	return attrs
+}
+
+// Validate checks that every attribute belongs to its controlled vocabulary.
+func (a ClothingAttributes) Validate() error {
+	if a.Category == "" {
+		return ErrUnknownCategory
+	}
+	if !slices.Contains(Categories, a.Category) {
+		return fmt.Errorf("unknown category %q", a.Category)
+	}
+	if a.Formality != "" && !slices.Contains(Formalities, a.Formality) {
+		return fmt.Errorf("unknown formality %q", a.Formality)
+	}
+	if a.Pattern != "" && !slices.Contains(Patterns, a.Pattern) {
+		return fmt.Errorf("unknown pattern %q", a.Pattern)
+	}
+	for _, season := range a.Seasons {
+		if !slices.Contains(Seasons, season) {
+			return fmt.Errorf("unknown season %q", season)
+		}
+	}
+	for _, color := range a.Colors {
+		if !slices.Contains(ColorFamilies, color.Family) {
+			return fmt.Errorf("unknown color family %q", color.Family)
+		}
+	}
+	return nil
+}
+
+// WornInSeason reports whether the garment suits the season. Garments
+// without season labels are treated as all-season.
+func (a ClothingAttributes) WornInSeason(season string) bool {
+	return len(a.Seasons) == 0 || slices.Contains(a.Seasons, season)
+}
+
+func deriveFormality(metadata map[string]interface{}, attrs *ClothingAttributes) string {
+	best := ""
+	athletic := false
+	consider := func(key string, table map[string]string) {
+		for _, label := range metadataLabels(metadata, key) {
+			formality, ok := table[label]
+			if !ok {
+				attrs.Unknown = append(attrs.Unknown, key+":"+label)
+				continue
+			}
+			if formality == FormalityAthletic {
+				athletic = true
+				continue
+			}
+			if slices.Index(Formalities, formality) > slices.Index(Formalities, best) {
+				best = formality
+			}
+		}
+	}
+	consider("styles", formalityStyles)
+	consider("occasions", formalityOccasions)
+
+	switch {
+	case best != "" && best != FormalityCasual:
+		return best
+	case athletic:
+		return FormalityAthletic
+	default:
+		return FormalityCasual
+	}
+}
+
+// metadataLabels reads a metadata value that is either a string or a list
+// of strings, lower-cased and trimmed.
+func metadataLabels(metadata map[string]interface{}, key string) []string {
+	var labels []string
+	switch v := metadata[key].(type) {
+	case string:
+		labels = append(labels, v)
+	case []string:
+		labels = append(labels, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				labels = append(labels, s)
+			}
+		}
+	}
+	for i, label := range labels {
+		labels[i] = strings.ToLower(strings.TrimSpace(label))
+	}
+	return labels
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,234 @@
+package internal_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+func TestNormalizeAttributes(t *testing.T) {
+	solid := []internal.DominantColor{{Hex: "#141e5a", Family: internal.ColorNavy, Share: 0.9}}
+	mixed := []internal.DominantColor{{Family: internal.ColorRed, Share: 0.5}, {Family: internal.ColorWhite, Share: 0.4}}
+
+	for _, tc := range []struct {
+		name     string
+		metadata map[string]interface{}
+		colors   []internal.DominantColor
+		want     internal.ClothingAttributes
+	}{
+		{
+			"full labels",
+			map[string]interface{}{
+				"categories": []interface{}{"tops"},
+				"types":      []interface{}{"t-shirt"},
+				"seasons":    []interface{}{"summer", "spring"},
+				"patterns":   []interface{}{"striped"},
+				"styles":     []interface{}{"casual"},
+				"occasions":  []interface{}{"daily"},
+			},
+			mixed,
+			internal.ClothingAttributes{
+				Category: internal.CategoryTop, Subcategory: "t-shirt", Pattern: internal.PatternStriped,
+				Seasons: []string{internal.SeasonSpring, internal.SeasonSummer}, Formality: internal.FormalityCasual, Confidence: 1,
+			},
+		},
+		{
+			"labels as strings, any case",
+			map[string]interface{}{"categories": " Bottoms ", "types": "JEANS", "seasons": "Autumn"},
+			solid,
+			internal.ClothingAttributes{
+				Category: internal.CategoryBottom, Subcategory: "jeans", Pattern: internal.PatternSolid,
+				Seasons: []string{internal.SeasonFall}, Formality: internal.FormalityCasual, Confidence: 1,
+			},
+		},
+		{
+			"type agreeing with the category wins",
+			map[string]interface{}{"categories": []interface{}{"bottoms"}, "types": []interface{}{"t-shirt", "skirts"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryBottom, Subcategory: "skirt", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 1,
+			},
+		},
+		{
+			"outerwear type overrides the category",
+			map[string]interface{}{"categories": []interface{}{"tops"}, "types": []interface{}{"cardigan tops"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryOuterwear, Subcategory: "cardigan", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 1,
+			},
+		},
+		{
+			"category without a type",
+			map[string]interface{}{"categories": []interface{}{"shoes"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryShoes, Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 0.8,
+			},
+		},
+		{
+			"conflicting categories",
+			map[string]interface{}{"categories": []interface{}{"tops", "bottoms"}, "types": []interface{}{"jeans"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryBottom, Subcategory: "jeans", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 0.75,
+			},
+		},
+		{
+			"unknown labels",
+			map[string]interface{}{
+				"categories": []interface{}{"tops", "capes"},
+				"types":      []interface{}{"shirts", "toga"},
+				"seasons":    []interface{}{"monsoon"},
+				"styles":     []interface{}{"vaporwave"},
+			},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryTop, Subcategory: "shirt", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 0.8,
+				Unknown: []string{"categories:capes", "types:toga", "seasons:monsoon", "styles:vaporwave"},
+			},
+		},
+		{
+			"most formal label wins",
+			map[string]interface{}{"categories": "tops", "types": "shirts", "styles": []interface{}{"sporty", "classic"}, "occasions": []interface{}{"work"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryTop, Subcategory: "shirt", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityBusiness, Confidence: 1,
+			},
+		},
+		{
+			"athletic only without dressier labels",
+			map[string]interface{}{"categories": "bottoms", "types": "joggers", "styles": []interface{}{"sporty", "casual"}},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryBottom, Subcategory: "joggers", Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityAthletic, Confidence: 1,
+			},
+		},
+		{
+			"segmenter confidence wins",
+			map[string]interface{}{"categories": "tops", "types": "toga", "confidence": 0.42},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryTop, Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 0.42, Unknown: []string{"types:toga"},
+			},
+		},
+		{
+			"confidence clamped",
+			map[string]interface{}{"categories": "tops", "confidence": 1.7},
+			nil,
+			internal.ClothingAttributes{
+				Category: internal.CategoryTop, Pattern: internal.PatternMulticolor,
+				Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 1,
+			},
+		},
+		{
+			"no metadata",
+			nil,
+			nil,
+			internal.ClothingAttributes{Pattern: internal.PatternMulticolor, Seasons: []string{}, Formality: internal.FormalityCasual, Confidence: 0.8},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := internal.NormalizeAttributes(tc.metadata, tc.colors)
+			want := tc.want
+			want.Colors = tc.colors
+			if want.Colors == nil {
+				want.Colors = []internal.DominantColor{}
+			}
+			if got.Category != want.Category || got.Subcategory != want.Subcategory || got.Pattern != want.Pattern ||
+				got.Formality != want.Formality || got.Confidence != want.Confidence {
+				t.Errorf("attributes = %+v\nwant         %+v", got, want)
+			}
+			if !slices.Equal(got.Seasons, want.Seasons) || got.Seasons == nil {
+				t.Errorf("seasons = %#v, want %#v", got.Seasons, want.Seasons)
+			}
+			if !slices.Equal(got.Colors, want.Colors) || got.Colors == nil {
+				t.Errorf("colors = %#v, want %#v", got.Colors, want.Colors)
+			}
+			if !slices.Equal(got.Unknown, want.Unknown) {
+				t.Errorf("unknown = %v, want %v", got.Unknown, want.Unknown)
+			}
+		})
+	}
+}
+
+func TestClothingAttributesValidate(t *testing.T) {
+	valid := internal.ClothingAttributes{
+		Category:  internal.CategoryTop,
+		Pattern:   internal.PatternSolid,
+		Seasons:   []string{internal.SeasonSummer},
+		Formality: internal.FormalityCasual,
+		Colors:    []internal.DominantColor{{Family: internal.ColorNavy}},
+	}
+	for _, tc := range []struct {
+		name   string
+		modify func(*internal.ClothingAttributes)
+		want   string
+	}{
+		{"valid", func(a *internal.ClothingAttributes) {}, ""},
+		{"only a category", func(a *internal.ClothingAttributes) {
+			*a = internal.ClothingAttributes{Category: internal.CategoryShoes}
+		}, ""},
+		{"no category", func(a *internal.ClothingAttributes) { a.Category = "" }, internal.ErrUnknownCategory.Error()},
+		{"unknown category", func(a *internal.ClothingAttributes) { a.Category = "hat" }, `unknown category "hat"`},
+		{"unknown formality", func(a *internal.ClothingAttributes) { a.Formality = "black tie" }, `unknown formality "black tie"`},
+		{"unknown pattern", func(a *internal.ClothingAttributes) { a.Pattern = "paisley" }, `unknown pattern "paisley"`},
+		{"unknown season", func(a *internal.ClothingAttributes) { a.Seasons = []string{"summer", "monsoon"} }, `unknown season "monsoon"`},
+		{"unknown color", func(a *internal.ClothingAttributes) { a.Colors[0].Family = "teal" }, `unknown color family "teal"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := valid
+			attrs.Colors = slices.Clone(valid.Colors)
+			tc.modify(&attrs)
+			err := attrs.Validate()
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("Validate() = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	if err := (internal.ClothingAttributes{}).Validate(); !errors.Is(err, internal.ErrUnknownCategory) {
+		t.Errorf("Validate() = %v, want ErrUnknownCategory", err)
+	}
+}
+
+func TestOccasionsAndSeasons(t *testing.T) {
+	for _, occasion := range internal.Occasions {
+		formality, ok := internal.OccasionFormality(occasion)
+		if !ok || !slices.Contains(internal.Formalities, formality) {
+			t.Errorf("occasion %q maps to %q, %v", occasion, formality, ok)
+		}
+	}
+	if _, ok := internal.OccasionFormality("wedding crash"); ok {
+		t.Error("unknown occasion has a formality")
+	}
+
+	for _, tc := range []struct {
+		seasons []string
+		season  string
+		want    bool
+	}{
+		{nil, internal.SeasonWinter, true},
+		{[]string{internal.SeasonSummer}, internal.SeasonSummer, true},
+		{[]string{internal.SeasonSummer}, internal.SeasonWinter, false},
+	} {
+		if got := (internal.ClothingAttributes{Seasons: tc.seasons}).WornInSeason(tc.season); got != tc.want {
+			t.Errorf("%v worn in %s = %v, want %v", tc.seasons, tc.season, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultColorCount is the number of dominant colors kept per garment.
+	DefaultColorCount = 3
+
+	// minColorShare drops colors covering less than this fraction of the
+	// garment, which are usually seams, shadows or segmentation fringes.
+	minColorShare = 0.05
+
+	// maxColorSamples bounds the number of pixels inspected per image.
+	maxColorSamples = 250000
+)
+
+// Color families used to tag garments. These are deliberately coarse so
+// they can drive filters and color-harmony rules.
+const (
+	ColorBlack  = "black"
+	ColorWhite  = "white"
+	ColorGray   = "gray"
+	ColorBeige  = "beige"
+	ColorBrown  = "brown"
+	ColorRed    = "red"
+	ColorOrange = "orange"
+	ColorYellow = "yellow"
+	ColorGreen  = "green"
+	ColorBlue   = "blue"
+	ColorNavy   = "navy"
+	ColorPurple = "purple"
+	ColorPink   = "pink"
+)
+
+// ColorFamilies lists every family ColorFamily can return.
+var ColorFamilies = []string{
+	ColorBlack, ColorWhite, ColorGray, ColorBeige, ColorBrown, ColorRed, ColorOrange,
+	ColorYellow, ColorGreen, ColorBlue, ColorNavy, ColorPurple, ColorPink,
+}
+
+// DominantColor is one of the most common colors of a garment.
+type DominantColor struct {
+	Hex    string  `json:"hex"`
+	Family string  `json:"family"`
+	Share  float64 `json:"share"`
+}
+
+// ExtractColors returns up to n dominant colors of an encoded garment cutout,
+// most common first. Transparent background pixels are ignored.
+func ExtractColors(imgBytes []byte, n int) ([]DominantColor, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	b := img.Bounds()
+	stride := 1
+	for (b.Dx()/stride)*(b.Dy()/stride) > maxColorSamples {
+		stride++
+	}
+
+	// Bucket pixels into a 4-bit-per-channel histogram, keeping channel
+	// sums so each bucket reports its mean color rather than its corner.
+	type bucket struct {
+		count   int
+		r, g, b uint64
+	}
+	buckets := make(map[uint16]*bucket)
+	total := 0
+	for y := b.Min.Y; y < b.Max.Y; y += stride {
+		for x := b.Min.X; x < b.Max.X; x += stride {
+			r, g, bl, a := img.At(x, y).RGBA()
+			if a < alphaThreshold {
+				continue
+			}
+			// Un-premultiply to 8-bit channels.
+			r8, g8, b8 := uint8(r*0xff/a), uint8(g*0xff/a), uint8(bl*0xff/a)
+			key := uint16(r8>>4)<<8 | uint16(g8>>4)<<4 | uint16(b8>>4)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += uint64(r8)
+			bk.g += uint64(g8)
+			bk.b += uint64(b8)
+			total++
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("image has no opaque pixels")
+	}
+
+	// Merge buckets by family, so several shades of one blue do not crowd
+	// out a second color.
+	type family struct {
+		count   int
+		r, g, b uint64
+	}
+	families := make(map[string]*family)
+	for _, bk := range buckets {
+		name := ColorFamily(uint8(bk.r/uint64(bk.count)), uint8(bk.g/uint64(bk.count)), uint8(bk.b/uint64(bk.count)))
+		f, ok := families[name]
+		if !ok {
+			f = &family{}
+			families[name] = f
+		}
+		f.count += bk.count
+		f.r += bk.r
+		f.g += bk.g
+		f.b += bk.b
+	}
+
+	colors := make([]DominantColor, 0, len(families))
+	for name, f := range families {
+		share := float64(f.count) / float64(total)
+		if share < minColorShare {
+			continue
+		}
+		colors = append(colors, DominantColor{
+			Hex:    fmt.Sprintf("#%02x%02x%02x", f.r/uint64(f.count), f.g/uint64(f.count), f.b/uint64(f.count)),
+			Family: name,
+			Share:  math.Round(share*1000) / 1000,
+		})
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if colors[i].Share == colors[j].Share {
+			return colors[i].Family < colors[j].Family
+		}
+		return colors[i].Share > colors[j].Share
+	})
+	if len(colors) > n {
+		colors = colors[:n]
+	}
+	return colors, nil
+}
+
+// ColorFamily maps an RGB color to its named color family.
+func ColorFamily(r, g, b uint8) string {
+	h, s, l := rgbToHSL(r, g, b)
+
+	switch {
+	case l < 0.12:
+		return ColorBlack
+	case l > 0.92 && s < 0.5:
+		return ColorWhite
+	case s < 0.12:
+		if l < 0.25 {
+			return ColorBlack
+		}
+		if l > 0.85 {
+			return ColorWhite
+		}
+		return ColorGray
+	}
+
+	switch {
+	case h < 15 || h >= 345:
+		if l > 0.7 {
+			return ColorPink
+		}
+		if l < 0.3 && s < 0.5 {
+			return ColorBrown
+		}
+		return ColorRed
+	case h < 45:
+		if l < 0.45 {
+			return ColorBrown
+		}
+		if l > 0.75 || (s < 0.5 && l > 0.6) {
+			return ColorBeige
+		}
+		return ColorOrange
+	case h < 70:
+		if l > 0.8 || (s < 0.45 && l > 0.55) {
+			return ColorBeige
+		}
+		if l < 0.35 {
+			return ColorBrown
+		}
+		return ColorYellow
+	case h < 170:
+		return ColorGreen
+	case h < 255:
+		if l < 0.3 {
+			return ColorNavy
+		}
+		return ColorBlue
+	case h < 290:
+		return ColorPurple
+	default:
+		return ColorPink
+	}
+}
+
+// rgbToHSL converts 8-bit RGB to hue in degrees and saturation/lightness in [0, 1].
+func rgbToHSL(r8, g8, b8 uint8) (h, s, l float64) {
+	r, g, b := float64(r8)/255, float64(g8)/255, float64(b8)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	l = (maxC + minC) / 2
+	d := maxC - minC
+	if d == 0 {
+		return 0, 0, l
+	}
+
+	s = d / (1 - math.Abs(2*l-1))
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
@@ -0,0 +1,132 @@
+package internal_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+func TestColorFamily(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		r, g, b uint8
+		want    string
+	}{
+		{"black", 0, 0, 0, internal.ColorBlack},
+		{"charcoal", 40, 40, 40, internal.ColorBlack},
+		{"white", 255, 255, 255, internal.ColorWhite},
+		{"off-white", 245, 245, 240, internal.ColorWhite},
+		{"gray", 128, 128, 128, internal.ColorGray},
+		{"red", 200, 20, 20, internal.ColorRed},
+		{"pink", 255, 182, 193, internal.ColorPink},
+		{"dull dark red", 70, 40, 35, internal.ColorBrown},
+		{"orange", 255, 140, 0, internal.ColorOrange},
+		{"tan", 210, 180, 140, internal.ColorBeige},
+		{"chocolate", 100, 60, 20, internal.ColorBrown},
+		{"yellow", 240, 220, 30, internal.ColorYellow},
+		{"green", 30, 160, 60, internal.ColorGreen},
+		{"blue", 30, 100, 220, internal.ColorBlue},
+		{"navy", 20, 30, 90, internal.ColorNavy},
+		{"purple", 120, 40, 170, internal.ColorPurple},
+		{"magenta", 220, 40, 160, internal.ColorPink},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := internal.ColorFamily(tc.r, tc.g, tc.b)
+			if got != tc.want {
+				t.Errorf("ColorFamily(%d, %d, %d) = %s, want %s", tc.r, tc.g, tc.b, got, tc.want)
+			}
+			if !slices.Contains(internal.ColorFamilies, got) {
+				t.Errorf("%s is not listed in ColorFamilies", got)
+			}
+		})
+	}
+}
+
+// band is a share of the rows of a test garment, top to bottom.
+type band struct {
+	rows int
+	c    color.NRGBA
+}
+
+// bandedPNG stacks bands of 10-pixel-wide rows into a PNG.
+func bandedPNG(t *testing.T, bands ...band) []byte {
+	t.Helper()
+	height := 0
+	for _, b := range bands {
+		height += b.rows
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 10, height))
+	y := 0
+	for _, b := range bands {
+		for end := y + b.rows; y < end; y++ {
+			for x := 0; x < 10; x++ {
+				img.Set(x, y, b.c)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractColors(t *testing.T) {
+	red := color.NRGBA{R: 200, G: 20, B: 20, A: 255}
+	darkRed := color.NRGBA{R: 170, G: 10, B: 10, A: 255}
+	navy := color.NRGBA{R: 20, G: 30, B: 90, A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	green := color.NRGBA{R: 30, G: 160, B: 60, A: 255}
+	clear := color.NRGBA{}
+
+	for _, tc := range []struct {
+		name   string
+		img    []byte
+		n      int
+		want   []string
+		shares []float64
+	}{
+		{"one color", bandedPNG(t, band{10, navy}), 3, []string{internal.ColorNavy}, []float64{1}},
+		{"most common first", bandedPNG(t, band{3, white}, band{7, navy}), 3, []string{internal.ColorNavy, internal.ColorWhite}, []float64{0.7, 0.3}},
+		{"background ignored", bandedPNG(t, band{30, clear}, band{5, red}, band{5, navy}, band{30, clear}), 3, []string{internal.ColorNavy, internal.ColorRed}, []float64{0.5, 0.5}},
+		{"shades merged", bandedPNG(t, band{3, red}, band{3, darkRed}, band{4, navy}), 3, []string{internal.ColorRed, internal.ColorNavy}, []float64{0.6, 0.4}},
+		{"specks dropped", bandedPNG(t, band{1, green}, band{49, white}), 3, []string{internal.ColorWhite}, []float64{0.98}},
+		{"limited to n", bandedPNG(t, band{4, red}, band{3, navy}, band{3, white}), 2, []string{internal.ColorRed, internal.ColorNavy}, []float64{0.4, 0.3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			colors, err := internal.ExtractColors(tc.img, tc.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var families []string
+			var shares []float64
+			for _, c := range colors {
+				families = append(families, c.Family)
+				shares = append(shares, c.Share)
+				if len(c.Hex) != 7 || c.Hex[0] != '#' {
+					t.Errorf("hex = %q", c.Hex)
+				}
+			}
+			if !slices.Equal(families, tc.want) || !slices.Equal(shares, tc.shares) {
+				t.Errorf("colors = %v %v, want %v %v", families, shares, tc.want, tc.shares)
+			}
+		})
+	}
+
+	if colors, _ := internal.ExtractColors(bandedPNG(t, band{1, navy}), 1); colors[0].Hex != "#141e5a" {
+		t.Errorf("hex = %s, want the mean color #141e5a", colors[0].Hex)
+	}
+	for name, img := range map[string][]byte{
+		"not an image":      []byte("not an image"),
+		"fully transparent": bandedPNG(t, band{4, clear}),
+	} {
+		if _, err := internal.ExtractColors(img, 3); err == nil {
+			t.Errorf("%s: extracted colors without an error", name)
+		}
+	}
+}
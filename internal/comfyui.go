@@ -2,82 +2,154 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
-	"sync"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
-const (
-	ComfyUIPath   = "/Users/zmaukey/ComfyUI"
-	ComfyUIAPIURL = "127.0.0.1:8188"
-)
+// ComfyUIAPIURL is the host and port of the local ComfyUI instance.
+const ComfyUIAPIURL = "127.0.0.1:8188"
 
-var clientID = uuid.NewString()
+// DefaultComfyUIURL is the base URL of the local ComfyUI instance.
+const DefaultComfyUIURL = "http://" + ComfyUIAPIURL
 
-var (
-	comfyUICmd *exec.Cmd
-	cmdMutex   sync.Mutex
-)
+// cancelTimeout bounds the requests that cancel an abandoned prompt.
+const cancelTimeout = 5 * time.Second
 
-// Start ComfyUI as a background process
-func StartComfyUI() error {
-	cmdMutex.Lock()
-	defer cmdMutex.Unlock()
-
-	env := os.Environ()
-	env = append(env,
-		"HTTP_PROXY=",
-		"HTTPS_PROXY=",
-		"http_proxy=",
-		"https_proxy=",
-		"NO_PROXY=*",
-		"no_proxy=*")
-
-	log.Println("Starting ComfyUI...")
-	comfyUICmd = exec.Command("python", "main.py", "--listen", "127.0.0.1")
-	comfyUICmd.Env = env
-	comfyUICmd.Dir = ComfyUIPath
-	comfyUICmd.Stdout = os.Stdout
-	comfyUICmd.Stderr = os.Stderr
-
-	if err := comfyUICmd.Start(); err != nil {
-		return fmt.Errorf("failed to start ComfyUI: %v", err)
-	}
-
-	// Wait for ComfyUI to initialize
-	time.Sleep(10 * time.Second)
-	log.Println("ComfyUI started")
-	return nil
+// ComfyUIClient talks to one ComfyUI instance.
+type ComfyUIClient struct {
+	// BaseURL is the instance's HTTP address, e.g. http://127.0.0.1:8188.
+	BaseURL string
+	// Timeout bounds how long GetImages waits for a prompt.
+	Timeout time.Duration
+	http    *http.Client
+}
+
+// NewComfyUIClient creates a client for the ComfyUI instance at baseURL.
+func NewComfyUIClient(baseURL string) *ComfyUIClient {
+	return &ComfyUIClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Timeout: DefaultPromptTimeout,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SystemStats checks that the instance answers /system_stats.
+func (c *ComfyUIClient) SystemStats(ctx context.Context) error {
+	_, err := c.getJSON(ctx, "/system_stats", nil)
+	return err
 }
 
-// Check if ComfyUI is running
-func IsComfyUIRunning() bool {
-	resp, err := http.Get(ComfyUIAPIURL + "/system_stats")
+// QueueDepth returns the number of running and pending prompts.
+func (c *ComfyUIClient) QueueDepth(ctx context.Context) (int, error) {
+	var queue struct {
+		Running []json.RawMessage `json:"queue_running"`
+		Pending []json.RawMessage `json:"queue_pending"`
+	}
+	if _, err := c.getJSON(ctx, "/queue", &queue); err != nil {
+		return 0, err
+	}
+	return len(queue.Running) + len(queue.Pending), nil
+}
+
+// getJSON fetches path and decodes the body into v unless v is nil.
+func (c *ComfyUIClient) getJSON(ctx context.Context, path string, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
 	if err != nil {
-		return false
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
-	return resp.StatusCode == http.StatusOK
+	if resp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("GET %s: unexpected status %s", path, resp.Status)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return resp, fmt.Errorf("GET %s: %v", path, err)
+		}
+	}
+	return resp, nil
+}
+
+// postJSON posts v as JSON to path and returns the response, which the
+// caller must close.
+func (c *ComfyUIClient) postJSON(ctx context.Context, path string, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.http.Do(req)
 }
 
-func queuePrompt(prompt map[string]interface{}) (string, error) {
+// CancelPrompt stops a prompt: it is interrupted if it is running and
+// deleted from the queue if it is still pending. Other prompts are left
+// alone.
+func (c *ComfyUIClient) CancelPrompt(ctx context.Context, promptID string) error {
+	var queue struct {
+		Running [][]interface{} `json:"queue_running"`
+		Pending [][]interface{} `json:"queue_pending"`
+	}
+	if _, err := c.getJSON(ctx, "/queue", &queue); err != nil {
+		return err
+	}
+	// Queue entries are [number, prompt_id, prompt, extra_data, outputs]
+	inQueue := func(entries [][]interface{}) bool {
+		for _, entry := range entries {
+			if len(entry) > 1 && entry[1] == promptID {
+				return true
+			}
+		}
+		return false
+	}
+
+	var resp *http.Response
+	var err error
+	switch {
+	case inQueue(queue.Running):
+		resp, err = c.postJSON(ctx, "/interrupt", map[string]interface{}{"prompt_id": promptID})
+	case inQueue(queue.Pending):
+		resp, err = c.postJSON(ctx, "/queue", map[string]interface{}{"delete": []string{promptID}})
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to cancel prompt %s: %s", promptID, resp.Status)
+	}
+	return nil
+}
+
+func (c *ComfyUIClient) queuePrompt(ctx context.Context, prompt map[string]interface{}, clientID string) (string, error) {
 	payload := map[string]interface{}{
 		"prompt":    prompt,
 		"client_id": clientID,
 	}
-	body, _ := json.Marshal(payload)
-	resp, err := http.Post("http://"+ComfyUIAPIURL+"/prompt", "application/json", bytes.NewBuffer(body))
+	resp, err := c.postJSON(ctx, "/prompt", payload)
 	if err != nil {
 		return "", err
 	}
@@ -85,94 +157,136 @@ func queuePrompt(prompt map[string]interface{}) (string, error) {
 
 	var result map[string]interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
-	return result["prompt_id"].(string), nil
+	promptID, ok := result["prompt_id"].(string)
+	if !ok {
+		return "", fmt.Errorf("ComfyUI rejected the prompt: %v", result["error"])
+	}
+	return promptID, nil
 }
 
-func GetImages(prompt map[string]interface{}) (map[string][][]byte, error) {
+// GetImages queues a prompt and collects the images of its output nodes,
+// keyed by node ID. A failing node is reported as a *NodeError. If the
+// websocket drops, the results are fetched from /history instead, unless
+// the prompt sends images over the websocket, which only it carries. When ctx
+// is done or the client's timeout passes, the prompt is cancelled on
+// ComfyUI and the websocket closed.
+func (c *ComfyUIClient) GetImages(ctx context.Context, prompt map[string]interface{}) (map[string][][]byte, error) {
+	debugJob := debugJobFrom(ctx)
+	debugJob.RecordJSON("workflow.json", prompt)
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
 
-	// Marshal to JSON with indentation for readability
-	jsonData, err := json.MarshalIndent(prompt, "", "  ")
+	// Each call listens on its own client ID so that concurrent prompts do
+	// not receive each other's images. The socket is opened before the
+	// prompt is queued so that no message is missed.
+	clientID := uuid.NewString()
+	u, err := url.Parse(c.BaseURL)
 	if err != nil {
-		fmt.Printf("Error marshalling JSON: %v\n", err)
 		return nil, err
 	}
-	// Write to file
-	err = os.WriteFile("generatede_workflow.json", jsonData, 0644)
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path += "/ws"
+	u.RawQuery = "clientId=" + clientID
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
 	if err != nil {
-		fmt.Printf("Error writing to file: %v\n", err)
 		return nil, err
 	}
+	defer conn.Close()
+
+	// Closing the connection unblocks the read loop on cancellation
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-finished:
+		}
+	}()
 
-	promptID, err := queuePrompt(prompt)
+	promptID, err := c.queuePrompt(ctx, prompt, clientID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Connect to WebSocket
-	u := url.URL{Scheme: "ws", Host: ComfyUIAPIURL, Path: "/ws", RawQuery: "clientId=" + clientID}
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
-	if err != nil {
-		return nil, err
+	// Images are sent by the SaveImageWebsocket nodes
+	outputNodes := make(map[string]bool)
+	for id, node := range prompt {
+		if n, ok := node.(map[string]interface{}); ok && n["class_type"] == "SaveImageWebsocket" {
+			outputNodes[id] = true
+		}
 	}
-	defer conn.Close()
 
-	outputImages := make(map[string][][]byte)
-	var currentNode string
 	receivedMsgs := make([]map[string]interface{}, 0)
-
-	for {
-		msgType, msg, err := conn.ReadMessage()
-		if err != nil {
-			break
+	outputImages, err := c.collect(ctx, conn, promptID, outputNodes, &receivedMsgs)
+	if errors.Is(err, errSocketDropped) && ctx.Err() == nil {
+		if len(outputNodes) > 0 {
+			// SaveImageWebsocket images are never saved, so /history cannot
+			// recover them; stop the render nobody will receive
+			c.cancelAbandoned(promptID)
+			nodes := slices.Sorted(maps.Keys(outputNodes))
+			err = fmt.Errorf("%w and output node %s only sends its images over the websocket", err, nodes[0])
+		} else {
+			log.Printf("%v while waiting for prompt %s, falling back to /history", err, promptID)
+			outputImages, err = c.imagesFromHistory(ctx, promptID, err)
 		}
-
-		if msgType == websocket.TextMessage {
-			var message map[string]interface{}
-			json.Unmarshal(msg, &message)
-
-			receivedMsgs = append(receivedMsgs, message)
-
-			// if message["type"] == "progress" {
-			// 	continue
-			// }
-
-			// if message["type"] == "execution_success" {
-			// 	continue
-			// }
-
-			if message["type"] == "executing" {
-				data := message["data"].(map[string]interface{})
-				if data["prompt_id"] == promptID {
-					if data["node"] == nil {
-						break // Done
-					}
-					currentNode = data["node"].(string)
-				}
-			}
-		} else if msgType == websocket.BinaryMessage {
-			receivedMsgs = append(receivedMsgs, map[string]interface{}{"img": msg})
-			if currentNode == "30" {
-				outputImages[currentNode] = append(outputImages[currentNode], msg[8:]) // Strip 8-byte prefix
-			}
+	}
+	if ctx.Err() != nil {
+		c.cancelAbandoned(promptID)
+		if parent.Err() != nil {
+			err = parent.Err()
+		} else {
+			err = fmt.Errorf("%w after %s (prompt %s)", ErrPromptTimeout, c.Timeout, promptID)
 		}
+		outputImages = nil
 	}
 
-	// Marshal to JSON with indentation for readability
-	jsonData, err = json.MarshalIndent(receivedMsgs, "", "  ")
+	result := map[string]interface{}{"backend": c.BaseURL, "prompt_id": promptID}
 	if err != nil {
-		fmt.Printf("Error marshalling JSON: %v\n", err)
-		return nil, err
+		result["error"] = err.Error()
+	}
+	debugJob.RecordJSON("result.json", result)
+	debugJob.RecordJSON("messages.json", receivedMsgs)
+	for node, images := range outputImages {
+		for i, image := range images {
+			debugJob.RecordBytes(fmt.Sprintf("output_%s_%d%s", node, i, ImageExtension(image)), image)
+		}
 	}
-	// Write to file
-	err = os.WriteFile("received_images.json", jsonData, 0644)
+
 	if err != nil {
-		fmt.Printf("Error writing to file: %v\n", err)
 		return nil, err
 	}
-
 	return outputImages, nil
 }
 
+// ImageExtension guesses the file extension of encoded image data, as
+// sniffed by http.DetectContentType.
+func ImageExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".bin"
+}
+
+// cancelAbandoned cancels a prompt nobody is waiting for anymore. It does
+// not use the caller's context, which is usually the one already done.
+func (c *ComfyUIClient) cancelAbandoned(promptID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+	if err := c.CancelPrompt(ctx, promptID); err != nil {
+		log.Printf("Error cancelling ComfyUI prompt %s on %s: %v", promptID, c.BaseURL, err)
+		return
+	}
+	log.Printf("Cancelled ComfyUI prompt %s on %s", promptID, c.BaseURL)
+}
+
 func saveImage(imgBytes []byte, filename string) error {
 	img, _, err := image.Decode(bytes.NewReader(imgBytes))
 	if err != nil {
@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckInterval is how often the pool checks its backends.
+const DefaultHealthCheckInterval = 5 * time.Second
+
+// healthCheckTimeout bounds a single backend check.
+const healthCheckTimeout = 3 * time.Second
+
+// ErrNoHealthyBackend is returned when no ComfyUI backend can take a prompt.
+var ErrNoHealthyBackend = errors.New("no healthy ComfyUI backend")
+
+// BackendStatus is a snapshot of one backend of a Pool.
+type BackendStatus struct {
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+	// QueueDepth is the queue length at the last check plus the prompts
+	// sent to the backend since.
+	QueueDepth int       `json:"queue_depth"`
+	LastError  string    `json:"last_error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type backend struct {
+	client *ComfyUIClient
+	// healthy is false until the first successful check.
+	healthy    bool
+	queueDepth int
+	// dispatched counts prompts sent since the last check, which the
+	// reported queue depth does not include yet.
+	dispatched int
+	lastErr    error
+	checkedAt  time.Time
+}
+
+func (b *backend) load() int {
+	return b.queueDepth + b.dispatched
+}
+
+// Pool spreads prompts over several ComfyUI instances. It checks every
+// backend periodically with /system_stats and /queue, takes failing
+// backends out of rotation and puts them back once they recover.
+type Pool struct {
+	interval time.Duration
+
+	mu       sync.Mutex
+	backends []*backend
+	started  bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewPool creates a pool of the ComfyUI instances at urls. A non-positive
+// interval takes DefaultHealthCheckInterval. Call Start to begin checks.
+func NewPool(urls []string, interval time.Duration) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no ComfyUI backends configured")
+	}
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	p := &Pool{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, u := range urls {
+		p.backends = append(p.backends, &backend{client: NewComfyUIClient(u)})
+	}
+	return p, nil
+}
+
+// SetTimeout sets how long prompts may run on every backend.
+func (p *Pool) SetTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, b := range p.backends {
+		b.client.Timeout = timeout
+	}
+}
+
+// Start checks every backend once and keeps checking them in the
+// background until Stop. Starting a started pool does nothing.
+func (p *Pool) Start() {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return
+	}
+	p.started = true
+	p.mu.Unlock()
+
+	p.CheckAll(context.Background())
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.CheckAll(context.Background())
+			}
+		}
+	}()
+}
+
+// Stop ends the background health checks. It may be called more than
+// once, and on a pool that was never started.
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		p.mu.Lock()
+		started := p.started
+		p.mu.Unlock()
+		if started {
+			<-p.done
+		}
+	})
+}
+
+// CheckAll checks every backend concurrently and updates its health and
+// queue depth.
+func (p *Pool) CheckAll(ctx context.Context) {
+	p.mu.Lock()
+	backends := append([]*backend(nil), p.backends...)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, b := range backends {
+		wg.Add(1)
+		go func(b *backend) {
+			defer wg.Done()
+			p.check(ctx, b)
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) check(ctx context.Context, b *backend) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	err := b.client.SystemStats(ctx)
+	depth := 0
+	if err == nil {
+		depth, err = b.client.QueueDepth(ctx)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wasHealthy := b.healthy
+	b.healthy = err == nil
+	b.lastErr = err
+	b.checkedAt = time.Now().UTC()
+	if err == nil {
+		b.queueDepth = depth
+		b.dispatched = 0
+	}
+	switch {
+	case wasHealthy && !b.healthy:
+		log.Printf("ComfyUI backend %s removed from pool: %v", b.client.BaseURL, err)
+	case !wasHealthy && b.healthy:
+		log.Printf("ComfyUI backend %s added to pool", b.client.BaseURL)
+	}
+}
+
+// Acquire picks the least-loaded healthy backend and counts a prompt
+// against it. When none is healthy the backends are checked once more
+// before giving up with ErrNoHealthyBackend.
+func (p *Pool) Acquire(ctx context.Context) (*ComfyUIClient, error) {
+	if client := p.pick(); client != nil {
+		return client, nil
+	}
+	p.CheckAll(ctx)
+	if client := p.pick(); client != nil {
+		return client, nil
+	}
+	return nil, ErrNoHealthyBackend
+}
+
+func (p *Pool) pick() *ComfyUIClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *backend
+	for _, b := range p.backends {
+		if b.healthy && (best == nil || b.load() < best.load()) {
+			best = b
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.dispatched++
+	return best.client
+}
+
+// MarkFailed takes a backend out of rotation after a request to it failed
+// to connect. The next successful health check puts it back.
+func (p *Pool) MarkFailed(client *ComfyUIClient, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, b := range p.backends {
+		if b.client == client && b.healthy {
+			b.healthy = false
+			b.lastErr = err
+			log.Printf("ComfyUI backend %s removed from pool: %v", client.BaseURL, err)
+		}
+	}
+}
+
+// GetImages runs a prompt on the least-loaded healthy backend.
+func (p *Pool) GetImages(ctx context.Context, prompt map[string]interface{}) (map[string][][]byte, error) {
+	client, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	images, err := client.GetImages(ctx, prompt)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		// Take the backend out of rotation right away if it is down
+		if pingErr := client.SystemStats(ctx); pingErr != nil {
+			p.MarkFailed(client, pingErr)
+		}
+		return nil, fmt.Errorf("ComfyUI backend %s: %w", client.BaseURL, err)
+	}
+	return images, nil
+}
+
+// Healthy reports whether any backend is in rotation.
+func (p *Pool) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, b := range p.backends {
+		if b.healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// Status returns a snapshot of every backend.
+func (p *Pool) Status() []BackendStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]BackendStatus, 0, len(p.backends))
+	for _, b := range p.backends {
+		status := BackendStatus{
+			URL:        b.client.BaseURL,
+			Healthy:    b.healthy,
+			QueueDepth: b.load(),
+			CheckedAt:  b.checkedAt,
+		}
+		if b.lastErr != nil {
+			status.LastError = b.lastErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
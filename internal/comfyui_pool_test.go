@@ -0,0 +1,205 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/comfyuitest"
+)
+
+func TestPoolStop(t *testing.T) {
+	fake := comfyuitest.NewServer()
+	defer fake.Close()
+
+	for _, tc := range []struct {
+		name  string
+		start bool
+		stops int
+	}{
+		{"never started", false, 1},
+		{"started", true, 1},
+		{"stopped twice", true, 2},
+		{"stopped concurrently", true, 5},
+		{"never started, stopped twice", false, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, err := internal.NewPool([]string{fake.URL}, time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.start {
+				pool.Start()
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				var wg sync.WaitGroup
+				for i := 0; i < tc.stops; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						pool.Stop()
+					}()
+				}
+				wg.Wait()
+			}()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Stop did not return")
+			}
+		})
+	}
+}
+
+// newFakePool starts n fake ComfyUI servers and a checked pool over them.
+func newFakePool(t *testing.T, n int) (*internal.Pool, []*comfyuitest.Server) {
+	t.Helper()
+	var fakes []*comfyuitest.Server
+	var urls []string
+	for i := 0; i < n; i++ {
+		fake := comfyuitest.NewServer()
+		t.Cleanup(fake.Close)
+		fakes = append(fakes, fake)
+		urls = append(urls, fake.URL)
+	}
+	pool, err := internal.NewPool(urls, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool, fakes
+}
+
+// index returns which fake a client talks to.
+func index(t *testing.T, fakes []*comfyuitest.Server, client *internal.ComfyUIClient) int {
+	t.Helper()
+	for i, fake := range fakes {
+		if fake.URL == client.BaseURL {
+			return i
+		}
+	}
+	t.Fatalf("client for unknown backend %s", client.BaseURL)
+	return -1
+}
+
+func TestPoolAcquireLeastLoaded(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		backends int
+		down     []int
+		acquires int
+		// want is the number of prompts each backend is given
+		want    []int
+		wantErr error
+	}{
+		{"ties go to the first", 3, nil, 1, []int{1, 0, 0}, nil},
+		{"spread evenly", 3, nil, 7, []int{3, 2, 2}, nil},
+		{"skips unhealthy backends", 3, []int{1}, 4, []int{2, 0, 2}, nil},
+		{"none healthy", 2, []int{0, 1}, 1, []int{0, 0}, internal.ErrNoHealthyBackend},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, fakes := newFakePool(t, tc.backends)
+			for _, i := range tc.down {
+				fakes[i].SetDown(true)
+			}
+			pool.CheckAll(context.Background())
+
+			got := make([]int, tc.backends)
+			for i := 0; i < tc.acquires; i++ {
+				client, err := pool.Acquire(context.Background())
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tc.wantErr)
+				}
+				if err == nil {
+					got[index(t, fakes, client)]++
+				}
+			}
+			for i, status := range pool.Status() {
+				if status.QueueDepth != tc.want[i] || got[i] != tc.want[i] {
+					t.Errorf("backend %d given %d prompts, queue depth %d, want %d", i, got[i], status.QueueDepth, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPoolHealth(t *testing.T) {
+	ctx := context.Background()
+	pool, fakes := newFakePool(t, 2)
+	if pool.Healthy() {
+		t.Error("pool is healthy before the first check")
+	}
+	pool.CheckAll(ctx)
+
+	healthy := func() []bool {
+		var h []bool
+		for _, status := range pool.Status() {
+			h = append(h, status.Healthy)
+		}
+		return h
+	}
+
+	for _, tc := range []struct {
+		name   string
+		change func()
+		want   []bool
+	}{
+		{"both up", func() {}, []bool{true, true}},
+		{"check finds a backend down", func() {
+			fakes[1].SetDown(true)
+			pool.CheckAll(ctx)
+		}, []bool{true, false}},
+		// Recovery needs a check; prompts keep going to the healthy backend
+		{"recovered but not checked", func() {
+			fakes[1].SetDown(false)
+			for i := 0; i < 3; i++ {
+				client, err := pool.Acquire(ctx)
+				if err != nil || index(t, fakes, client) != 0 {
+					t.Fatalf("acquired %v, %v, want backend 0", client, err)
+				}
+			}
+		}, []bool{true, false}},
+		{"check adds it back", func() { pool.CheckAll(ctx) }, []bool{true, true}},
+		{"failed request", func() {
+			client, err := pool.Acquire(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pool.MarkFailed(client, errors.New("connection refused"))
+		}, []bool{false, true}},
+		// With nothing healthy, Acquire checks again before giving up
+		{"acquire rechecks", func() {
+			for _, fake := range fakes {
+				fake.SetDown(true)
+			}
+			pool.CheckAll(ctx)
+			if pool.Healthy() {
+				t.Fatal("pool is healthy with every backend down")
+			}
+			for _, fake := range fakes {
+				fake.SetDown(false)
+			}
+			if _, err := pool.Acquire(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}, []bool{true, true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.change()
+			if got := healthy(); !slices.Equal(got, tc.want) {
+				t.Errorf("healthy = %v, want %v", got, tc.want)
+			}
+			for i, status := range pool.Status() {
+				if !status.Healthy && status.LastError == "" {
+					t.Errorf("backend %d is unhealthy without an error", i)
+				}
+			}
+		})
+	}
+}
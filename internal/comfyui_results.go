@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultPromptTimeout bounds how long GetImages waits for a prompt.
+const DefaultPromptTimeout = 10 * time.Minute
+
+// historyPollInterval is how often /history is polled after the websocket
+// dropped.
+const historyPollInterval = time.Second
+
+// Binary websocket messages start with a 4-byte event type; preview images
+// follow it with a 4-byte image format.
+const (
+	binaryEventPreviewImage = 1
+	imageFormatJPEG         = 1
+	imageFormatPNG          = 2
+)
+
+var (
+	// ErrPromptInterrupted is returned when a prompt was interrupted on
+	// ComfyUI by someone else.
+	ErrPromptInterrupted = errors.New("ComfyUI prompt was interrupted")
+	// ErrPromptTimeout is returned when a prompt does not finish within
+	// the client's timeout.
+	ErrPromptTimeout = errors.New("timed out waiting for ComfyUI")
+
+	// errSocketDropped is returned by collect when the websocket closes
+	// before the prompt finished.
+	errSocketDropped = errors.New("ComfyUI websocket closed")
+)
+
+// NodeError is an execution error of one node of a prompt.
+type NodeError struct {
+	PromptID      string
+	NodeID        string
+	NodeType      string
+	ExceptionType string
+	Message       string
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("ComfyUI node %s (%s) failed: %s: %s", e.NodeID, e.NodeType, e.ExceptionType, e.Message)
+}
+
+// nodeErrorFrom builds a NodeError from execution_error message data.
+func nodeErrorFrom(promptID string, data map[string]interface{}) *NodeError {
+	text := func(key string) string {
+		s, _ := data[key].(string)
+		return s
+	}
+	return &NodeError{
+		PromptID:      promptID,
+		NodeID:        text("node_id"),
+		NodeType:      text("node_type"),
+		ExceptionType: text("exception_type"),
+		Message:       text("exception_message"),
+	}
+}
+
+// collect reads the websocket until the prompt finishes. Images are taken
+// from the binary messages of SaveImageWebsocket nodes and from the
+// executed messages of nodes that save files. Every message is appended
+// to received for debugging, binary ones by size only.
+func (c *ComfyUIClient) collect(ctx context.Context, conn *websocket.Conn, promptID string, outputNodes map[string]bool, received *[]map[string]interface{}) (map[string][][]byte, error) {
+	outputImages := make(map[string][][]byte)
+	cachedOutputs := make(map[string]bool)
+	var currentNode string
+
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			return outputImages, fmt.Errorf("%w: %v", errSocketDropped, err)
+		}
+
+		if msgType == websocket.BinaryMessage {
+			*received = append(*received, map[string]interface{}{"binary_bytes": len(msg), "node": currentNode})
+			if !outputNodes[currentNode] {
+				// Sampler previews and other nodes' images
+				continue
+			}
+			if len(msg) < 8 || binary.BigEndian.Uint32(msg[:4]) != binaryEventPreviewImage {
+				log.Printf("Ignoring unexpected binary message from node %s of prompt %s", currentNode, promptID)
+				continue
+			}
+			if format := binary.BigEndian.Uint32(msg[4:8]); format != imageFormatJPEG && format != imageFormatPNG {
+				log.Printf("Ignoring image of unknown format %d from node %s of prompt %s", format, currentNode, promptID)
+				continue
+			}
+			outputImages[currentNode] = append(outputImages[currentNode], msg[8:])
+			continue
+		}
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		var message struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(msg, &message); err != nil {
+			log.Printf("Ignoring malformed ComfyUI message: %v", err)
+			continue
+		}
+		*received = append(*received, map[string]interface{}{"type": message.Type, "data": message.Data})
+		// Apart from status updates, messages belong to a prompt
+		if id, ok := message.Data["prompt_id"]; ok && id != promptID {
+			continue
+		}
+
+		switch message.Type {
+		case "executing":
+			node, _ := message.Data["node"].(string)
+			if node == "" {
+				return finishImages(outputImages, cachedOutputs)
+			}
+			currentNode = node
+		case "execution_success":
+			return finishImages(outputImages, cachedOutputs)
+		case "execution_cached":
+			nodes, _ := message.Data["nodes"].([]interface{})
+			for _, n := range nodes {
+				if node, ok := n.(string); ok && outputNodes[node] {
+					cachedOutputs[node] = true
+				}
+			}
+		case "executed":
+			node, _ := message.Data["node"].(string)
+			output, _ := message.Data["output"].(map[string]interface{})
+			images, err := c.outputImages(ctx, output)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch output of node %s: %v", node, err)
+			}
+			outputImages[node] = append(outputImages[node], images...)
+		case "execution_error":
+			return nil, nodeErrorFrom(promptID, message.Data)
+		case "execution_interrupted":
+			return nil, ErrPromptInterrupted
+		case "status", "execution_start", "progress", "progress_state":
+			// Nothing to collect
+		default:
+			log.Printf("Ignoring ComfyUI message %q for prompt %s", message.Type, promptID)
+		}
+	}
+}
+
+// finishImages reports output nodes that ComfyUI served from its cache:
+// they did not run, so they sent no image.
+func finishImages(images map[string][][]byte, cachedOutputs map[string]bool) (map[string][][]byte, error) {
+	for node := range cachedOutputs {
+		if len(images[node]) == 0 {
+			return nil, fmt.Errorf("ComfyUI output node %s was cached and sent no image", node)
+		}
+	}
+	return images, nil
+}
+
+// imagesFromHistory polls /history/{promptID} until the prompt finished
+// and fetches its saved images with /view. When the prompt saved no
+// images, dropErr is returned.
+func (c *ComfyUIClient) imagesFromHistory(ctx context.Context, promptID string, dropErr error) (map[string][][]byte, error) {
+	ticker := time.NewTicker(historyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var history map[string]struct {
+			Outputs map[string]map[string]interface{} `json:"outputs"`
+			Status  struct {
+				StatusStr string `json:"status_str"`
+				Completed bool   `json:"completed"`
+				// Messages are [type, data] pairs
+				Messages [][]json.RawMessage `json:"messages"`
+			} `json:"status"`
+		}
+		_, err := c.getJSON(ctx, "/history/"+url.PathEscape(promptID), &history)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if entry, ok := history[promptID]; err == nil && ok {
+			if entry.Status.StatusStr == "error" {
+				return nil, historyError(promptID, entry.Status.Messages)
+			}
+			if entry.Status.Completed {
+				images := make(map[string][][]byte)
+				for node, output := range entry.Outputs {
+					nodeImages, err := c.outputImages(ctx, output)
+					if err != nil {
+						return nil, fmt.Errorf("failed to fetch output of node %s: %v", node, err)
+					}
+					if len(nodeImages) > 0 {
+						images[node] = nodeImages
+					}
+				}
+				if len(images) == 0 {
+					return nil, fmt.Errorf("%w and the prompt saved no images to recover", dropErr)
+				}
+				return images, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// historyError returns the execution error recorded in a prompt's history.
+func historyError(promptID string, messages [][]json.RawMessage) error {
+	for _, m := range messages {
+		if len(m) != 2 {
+			continue
+		}
+		var msgType string
+		var data map[string]interface{}
+		if json.Unmarshal(m[0], &msgType) != nil || json.Unmarshal(m[1], &data) != nil {
+			continue
+		}
+		switch msgType {
+		case "execution_error":
+			return nodeErrorFrom(promptID, data)
+		case "execution_interrupted":
+			return ErrPromptInterrupted
+		}
+	}
+	return fmt.Errorf("ComfyUI prompt %s failed", promptID)
+}
+
+// outputImages fetches the images listed in a node output, as found in
+// executed messages and /history, with /view.
+func (c *ComfyUIClient) outputImages(ctx context.Context, output map[string]interface{}) ([][]byte, error) {
+	entries, _ := output["images"].([]interface{})
+	var images [][]byte
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		query := url.Values{}
+		for _, key := range []string{"filename", "subfolder", "type"} {
+			if v, ok := entry[key].(string); ok {
+				query.Set(key, v)
+			}
+		}
+		data, err := c.view(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// view downloads an image with /view.
+func (c *ComfyUIClient) view(ctx context.Context, query url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/view?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /view: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,324 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Supervisor defaults.
+const (
+	DefaultComfyUICommand = "python"
+	DefaultReadyTimeout   = 2 * time.Minute
+	DefaultMinBackoff     = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultStopTimeout    = 10 * time.Second
+
+	readyPollInterval = 500 * time.Millisecond
+	// stableRunTime is how long ComfyUI must stay up before a crash
+	// restarts it with the minimum backoff again.
+	stableRunTime  = time.Minute
+	comfyLogPrefix = "[comfyui] "
+)
+
+// DefaultComfyUIArgs are the arguments ComfyUI is started with when none
+// are configured.
+var DefaultComfyUIArgs = []string{"main.py", "--listen", "127.0.0.1"}
+
+// ErrSupervisorStopped is returned by WaitReady once the supervisor stops.
+var ErrSupervisorStopped = errors.New("ComfyUI supervisor stopped")
+
+// SupervisorConfig configures how ComfyUI is run. Zero values take the
+// defaults above.
+type SupervisorConfig struct {
+	// Dir is the ComfyUI checkout the command runs in.
+	Dir     string
+	Command string
+	Args    []string
+	// URL is the base URL polled for readiness, e.g. http://127.0.0.1:8188.
+	URL string
+	// ReadyTimeout bounds how long a start may take before the process is
+	// killed and restarted, and how long WaitReady waits.
+	ReadyTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	StopTimeout  time.Duration
+}
+
+// Supervisor runs ComfyUI as a child process, restarting it with
+// exponential backoff whenever it exits or fails to become ready.
+type Supervisor struct {
+	cfg    SupervisorConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	ready    bool
+	stopping bool
+	// changed is closed and replaced whenever ready or stopping changes.
+	changed chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSupervisor creates a Supervisor; call Start to launch ComfyUI.
+func NewSupervisor(cfg SupervisorConfig) *Supervisor {
+	if cfg.Command == "" {
+		cfg.Command = DefaultComfyUICommand
+	}
+	if len(cfg.Args) == 0 {
+		cfg.Args = DefaultComfyUIArgs
+	}
+	if cfg.URL == "" {
+		cfg.URL = DefaultComfyUIURL
+	}
+	if cfg.ReadyTimeout <= 0 {
+		cfg.ReadyTimeout = DefaultReadyTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+	}
+	if cfg.StopTimeout <= 0 {
+		cfg.StopTimeout = DefaultStopTimeout
+	}
+	return &Supervisor{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 2 * time.Second},
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start checks the configuration and launches ComfyUI in the background.
+func (s *Supervisor) Start() error {
+	info, err := os.Stat(s.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("invalid ComfyUI path: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid ComfyUI path: %s is not a directory", s.cfg.Dir)
+	}
+	go s.run()
+	return nil
+}
+
+// Ready reports whether ComfyUI is currently up.
+func (s *Supervisor) Ready() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ready
+}
+
+// WaitReady blocks until ComfyUI is ready, the supervisor stops, ctx is
+// done or the ready timeout passes, whichever is first.
+func (s *Supervisor) WaitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ReadyTimeout)
+	defer cancel()
+
+	for {
+		s.mu.Lock()
+		ready, stopping, changed := s.ready, s.stopping, s.changed
+		s.mu.Unlock()
+		if ready {
+			return nil
+		}
+		if stopping {
+			return ErrSupervisorStopped
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("ComfyUI is not ready: %w", ctx.Err())
+		}
+	}
+}
+
+// Stop interrupts ComfyUI and waits for it to exit, killing it after the
+// stop timeout or when ctx is done.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		<-s.done
+		return nil
+	}
+	s.stopping = true
+	close(s.stop)
+	cmd := s.cmd
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		log.Println("Stopping ComfyUI...")
+		cmd.Process.Signal(os.Interrupt)
+	}
+
+	timer := time.NewTimer(s.cfg.StopTimeout)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+		log.Println("ComfyUI stopped")
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	if cmd != nil && cmd.Process != nil {
+		log.Println("ComfyUI did not stop in time, killing it")
+		cmd.Process.Kill()
+	}
+	<-s.done
+	return nil
+}
+
+// run starts ComfyUI and restarts it until Stop is called.
+func (s *Supervisor) run() {
+	defer close(s.done)
+
+	backoff := s.cfg.MinBackoff
+	for {
+		started := time.Now()
+		err := s.runOnce()
+
+		s.mu.Lock()
+		s.cmd = nil
+		s.ready = false
+		s.notifyLocked()
+		stopping := s.stopping
+		s.mu.Unlock()
+		if stopping {
+			return
+		}
+
+		if time.Since(started) >= stableRunTime {
+			backoff = s.cfg.MinBackoff
+		}
+		log.Printf("ComfyUI exited: %v; restarting in %s", err, backoff)
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.cfg.MaxBackoff)
+	}
+}
+
+// runOnce runs one ComfyUI process until it exits.
+func (s *Supervisor) runOnce() error {
+	env := os.Environ()
+	env = append(env,
+		"HTTP_PROXY=",
+		"HTTPS_PROXY=",
+		"http_proxy=",
+		"https_proxy=",
+		"NO_PROXY=*",
+		"no_proxy=*")
+
+	cmd := exec.Command(s.cfg.Command, s.cfg.Args...)
+	cmd.Env = env
+	cmd.Dir = s.cfg.Dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return ErrSupervisorStopped
+	}
+	log.Printf("Starting ComfyUI: %s %v in %s", s.cfg.Command, s.cfg.Args, s.cfg.Dir)
+	if err := cmd.Start(); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to start ComfyUI: %v", err)
+	}
+	s.cmd = cmd
+	s.mu.Unlock()
+
+	var logs sync.WaitGroup
+	logs.Add(2)
+	go forwardLogs(stdout, &logs)
+	go forwardLogs(stderr, &logs)
+
+	exited := make(chan struct{})
+	go s.awaitReady(cmd, exited)
+
+	// Wait must only be called once the pipes are drained
+	logs.Wait()
+	err = cmd.Wait()
+	close(exited)
+	return err
+}
+
+// awaitReady polls /system_stats until ComfyUI answers. A process that is
+// not ready within the ready timeout is killed so that run restarts it.
+func (s *Supervisor) awaitReady(cmd *exec.Cmd, exited <-chan struct{}) {
+	deadline := time.NewTimer(s.cfg.ReadyTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exited:
+			return
+		case <-deadline.C:
+			log.Printf("ComfyUI not ready after %s, killing it", s.cfg.ReadyTimeout)
+			cmd.Process.Kill()
+			return
+		case <-ticker.C:
+		}
+
+		resp, err := s.client.Get(s.cfg.URL + "/system_stats")
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.cmd == cmd {
+			s.ready = true
+			s.notifyLocked()
+		}
+		s.mu.Unlock()
+		log.Println("ComfyUI ready")
+		return
+	}
+}
+
+// notifyLocked wakes up WaitReady callers. s.mu must be held.
+func (s *Supervisor) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// forwardLogs copies ComfyUI output to the server log line by line.
+func forwardLogs(r io.Reader, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		log.Print(comfyLogPrefix + scanner.Text())
+	}
+	// Keep draining if a line was too long, so ComfyUI never blocks
+	io.Copy(io.Discard, r)
+}
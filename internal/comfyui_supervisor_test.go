@@ -0,0 +1,183 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// statsServer answers /system_stats with status.
+func statsServer(t *testing.T, status int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// shell runs script with sh. Every start is recorded in the file starts
+// of the working directory, as a Unix time in nanoseconds.
+func shell(script string) (string, []string) {
+	return "sh", []string{"-c", "date +%s%N >> starts; " + script}
+}
+
+// starts waits for n starts to be recorded and returns their times.
+func starts(t *testing.T, dir string, n int) []time.Time {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		data, _ := os.ReadFile(filepath.Join(dir, "starts"))
+		lines := strings.Fields(string(data))
+		if len(lines) >= n {
+			var times []time.Time
+			for _, line := range lines[:n] {
+				ns, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					t.Fatalf("start time %q: %v", line, err)
+				}
+				times = append(times, time.Unix(0, ns))
+			}
+			return times
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d starts, want %d", len(lines), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSupervisorStartChecksDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.py")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		dir  string
+	}{
+		{"missing", filepath.Join(t.TempDir(), "ComfyUI")},
+		{"not a directory", file},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := internal.NewSupervisor(internal.SupervisorConfig{Dir: tc.dir})
+			if err := s.Start(); err == nil || !strings.Contains(err.Error(), "invalid ComfyUI path") {
+				t.Errorf("error = %v, want the path rejected", err)
+			}
+		})
+	}
+}
+
+func TestSupervisorRestartsWithBackoff(t *testing.T) {
+	const minBackoff, maxBackoff = 50 * time.Millisecond, 100 * time.Millisecond
+	for _, tc := range []struct {
+		name   string
+		script string
+		status int
+		// delay is how long each run lasts before the backoff starts, set as
+		// the ready timeout
+		delay time.Duration
+	}{
+		{"crashes", "exit 1", http.StatusOK, 0},
+		{"never ready", "exec sleep 30", http.StatusServiceUnavailable, 200 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			command, args := shell(tc.script)
+			s := internal.NewSupervisor(internal.SupervisorConfig{
+				Dir:          dir,
+				Command:      command,
+				Args:         args,
+				URL:          statsServer(t, tc.status),
+				ReadyTimeout: tc.delay,
+				MinBackoff:   minBackoff,
+				MaxBackoff:   maxBackoff,
+			})
+			if err := s.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer s.Stop(context.Background())
+
+			times := starts(t, dir, 4)
+			// The backoff doubles up to the maximum. The script logs its
+			// start a little after the process is spawned, so allow for a
+			// slow shell.
+			const slack = 20 * time.Millisecond
+			for i, backoff := range []time.Duration{minBackoff, 2 * minBackoff, maxBackoff} {
+				if gap := times[i+1].Sub(times[i]); gap < tc.delay+backoff-slack {
+					t.Errorf("restart %d after %s, want at least %s", i+1, gap, tc.delay+backoff)
+				}
+			}
+			if s.Ready() {
+				t.Error("supervisor reports ComfyUI ready")
+			}
+		})
+	}
+}
+
+func TestSupervisorReadyAndStop(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		script string
+		// killed is set when the process ignores the interrupt and must be
+		// killed after the stop timeout
+		killed bool
+	}{
+		{"interrupted", "exec sleep 30", false},
+		{"killed", `trap "" INT; while :; do sleep 0.01; done`, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			command, args := shell(tc.script)
+			const stopTimeout = 200 * time.Millisecond
+			s := internal.NewSupervisor(internal.SupervisorConfig{
+				Dir:          dir,
+				Command:      command,
+				Args:         args,
+				URL:          statsServer(t, http.StatusOK),
+				ReadyTimeout: 5 * time.Second,
+				StopTimeout:  stopTimeout,
+			})
+			if err := s.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.WaitReady(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !s.Ready() {
+				t.Error("Ready is false after WaitReady")
+			}
+
+			begin := time.Now()
+			if err := s.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if took := time.Since(begin); (took >= stopTimeout) != tc.killed {
+				t.Errorf("stop took %s, want killed after %s: %v", took, stopTimeout, tc.killed)
+			}
+			if s.Ready() {
+				t.Error("Ready is true after Stop")
+			}
+			if err := s.WaitReady(context.Background()); !errors.Is(err, internal.ErrSupervisorStopped) {
+				t.Errorf("WaitReady error = %v, want ErrSupervisorStopped", err)
+			}
+			// Stopping again returns at once
+			if err := s.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := os.ReadFile(filepath.Join(dir, "starts"))
+			if n := len(strings.Fields(string(data))); n != 1 {
+				t.Errorf("started %d times, want once", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/comfyuitest"
+)
+
+func TestGetImagesAfterSocketDrop(t *testing.T) {
+	fake := comfyuitest.NewServer()
+	defer fake.Close()
+	client := internal.NewComfyUIClient(fake.URL)
+
+	for _, tc := range []struct {
+		name string
+		// classes are the class types of the output nodes "1" and "2"
+		classes []string
+		want    string
+		// cancelled is set when the render is abandoned at once rather
+		// than waited for; it then runs until interrupted
+		cancelled bool
+	}{
+		{"saved to disk", []string{"SaveImage"}, "", false},
+		{"websocket only", []string{"SaveImageWebsocket"}, "output node 1 only sends its images over the websocket", true},
+		{"mixed outputs", []string{"SaveImage", "SaveImageWebsocket"}, "output node 2 only sends its images over the websocket", true},
+		{"no outputs", []string{"PreviewImage"}, "saved no images", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt := make(map[string]interface{})
+			steps := []comfyuitest.Step{comfyuitest.Drop()}
+			for i, class := range tc.classes {
+				id := string(rune('1' + i))
+				prompt[id] = map[string]interface{}{"class_type": class, "inputs": map[string]interface{}{}}
+				steps = append(steps, comfyuitest.Executing(id))
+			}
+			if tc.cancelled {
+				steps = append(steps, comfyuitest.Hang())
+			}
+			fake.SetSteps(append(steps, comfyuitest.Executing(""))...)
+
+			interrupted := len(fake.Interrupted())
+			images, err := client.GetImages(context.Background(), prompt)
+			if cancelled := len(fake.Interrupted()) > interrupted; cancelled != tc.cancelled {
+				t.Errorf("prompt cancelled: %v, want %v", cancelled, tc.cancelled)
+			}
+			if tc.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.want) || !strings.Contains(err.Error(), "websocket closed") {
+					t.Fatalf("error = %v, want the dropped socket and %q", err, tc.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := images["1"]; len(got) != 1 || !bytes.Equal(got[0], fake.Image) {
+				t.Errorf("images = %v, want the image of node 1 from /view", images)
+			}
+		})
+	}
+}
+
+func TestCancelPrompt(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// pending lists the prompt as waiting in the queue
+		pending bool
+		// finished lets the prompt complete before it is cancelled
+		finished bool
+		// other cancels another prompt instead
+		other                bool
+		interrupted, deleted bool
+	}{
+		{name: "running", interrupted: true},
+		{name: "pending", pending: true, deleted: true},
+		{name: "finished", finished: true},
+		{name: "other prompt", other: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := comfyuitest.NewServer()
+			defer fake.Close()
+			fake.SetPending(tc.pending)
+			steps := []comfyuitest.Step{comfyuitest.Executing("1")}
+			if !tc.finished {
+				steps = append(steps, comfyuitest.Hang())
+			}
+			fake.SetSteps(append(steps, comfyuitest.Executing(""))...)
+			client := internal.NewComfyUIClient(fake.URL)
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := client.GetImages(context.Background(), map[string]interface{}{"1": map[string]interface{}{"class_type": "SaveImage"}})
+				done <- err
+			}()
+			if tc.finished {
+				<-done
+				fake.WaitIdle()
+			}
+			var promptID string
+			for deadline := time.Now().Add(5 * time.Second); promptID == ""; time.Sleep(10 * time.Millisecond) {
+				if prompts := fake.Prompts(); len(prompts) > 0 {
+					promptID = prompts[0].ID
+				} else if time.Now().After(deadline) {
+					t.Fatal("prompt never queued")
+				}
+			}
+
+			cancelID := promptID
+			if tc.other {
+				cancelID = "another-prompt"
+			}
+			if err := client.CancelPrompt(context.Background(), cancelID); err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Contains(fake.Interrupted(), promptID); got != tc.interrupted {
+				t.Errorf("interrupted %v, want the prompt interrupted: %v", fake.Interrupted(), tc.interrupted)
+			}
+			if got := slices.Contains(fake.Deleted(), promptID); got != tc.deleted {
+				t.Errorf("deleted %v, want the prompt deleted: %v", fake.Deleted(), tc.deleted)
+			}
+
+			if tc.interrupted || tc.deleted {
+				// The waiting client is told the prompt was stopped
+				if err := <-done; err == nil || !strings.Contains(err.Error(), "interrupted") {
+					t.Errorf("GetImages error = %v, want the interruption", err)
+				}
+			} else if tc.other {
+				client.CancelPrompt(context.Background(), promptID)
+				<-done
+			}
+		})
+	}
+}
+
+func TestGetImagesCancelled(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pending bool
+	}{
+		{"running", false},
+		{"pending", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := comfyuitest.NewServer()
+			defer fake.Close()
+			fake.SetPending(tc.pending)
+			fake.SetSteps(comfyuitest.Executing("1"), comfyuitest.Hang())
+			client := internal.NewComfyUIClient(fake.URL)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				for len(fake.Prompts()) == 0 {
+					time.Sleep(10 * time.Millisecond)
+				}
+				cancel()
+			}()
+			if _, err := client.GetImages(ctx, map[string]interface{}{"1": map[string]interface{}{"class_type": "SaveImage"}}); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+
+			// The abandoned prompt is stopped on ComfyUI
+			fake.WaitIdle()
+			stopped := fake.Interrupted()
+			if tc.pending {
+				stopped = fake.Deleted()
+			}
+			if prompts := fake.Prompts(); len(prompts) != 1 || !slices.Equal(stopped, []string{prompts[0].ID}) {
+				t.Errorf("stopped %v, want the prompt of %v", stopped, prompts)
+			}
+		})
+	}
+}
+
+func TestGetImagesCollect(t *testing.T) {
+	fake := comfyuitest.NewServer()
+	defer fake.Close()
+	client := internal.NewComfyUIClient(fake.URL)
+	result, preview := []byte("result"), []byte("preview")
+	executed := comfyuitest.Step{Type: "executed", Data: map[string]interface{}{
+		"node":   "1",
+		"output": map[string]interface{}{"images": []interface{}{map[string]interface{}{"filename": "saved.png", "type": "output"}}},
+	}}
+	nodeError := comfyuitest.ExecutionError("2", "KSampler", "RuntimeError", "CUDA out of memory")
+	failed := "node 2 (KSampler) failed: RuntimeError: CUDA out of memory"
+
+	for _, tc := range []struct {
+		name string
+		// output is the class of node "1"; node "2" is a sampler
+		output string
+		steps  []comfyuitest.Step
+		want   []byte
+		// wantErr is the error GetImages must fail with, or a part of
+		// its message
+		wantErr interface{}
+	}{
+		{"websocket image", "SaveImageWebsocket", []comfyuitest.Step{
+			comfyuitest.Executing("2"), comfyuitest.Image(preview),
+			comfyuitest.Executing("1"), comfyuitest.Image(result), comfyuitest.Executing(""),
+		}, result, nil},
+		{"saved image", "SaveImage", []comfyuitest.Step{comfyuitest.Executing("1"), executed, comfyuitest.Executing("")}, fake.Image, nil},
+		{"execution_success", "SaveImageWebsocket", []comfyuitest.Step{
+			comfyuitest.Executing("1"), comfyuitest.Image(result), {Type: "execution_success"},
+		}, result, nil},
+		{"other prompt's error", "SaveImageWebsocket", []comfyuitest.Step{
+			{Type: "execution_error", Data: map[string]interface{}{"prompt_id": "another-prompt"}},
+			comfyuitest.Executing("1"), comfyuitest.Image(result), comfyuitest.Executing(""),
+		}, result, nil},
+		{"cached other node", "SaveImageWebsocket", []comfyuitest.Step{
+			{Type: "execution_cached", Data: map[string]interface{}{"nodes": []interface{}{"2"}}},
+			comfyuitest.Executing("1"), comfyuitest.Image(result), comfyuitest.Executing(""),
+		}, result, nil},
+		{"cached output", "SaveImageWebsocket", []comfyuitest.Step{
+			{Type: "execution_cached", Data: map[string]interface{}{"nodes": []interface{}{"1", "2"}}},
+			comfyuitest.Executing(""),
+		}, nil, "output node 1 was cached"},
+		{"execution_error", "SaveImageWebsocket", []comfyuitest.Step{comfyuitest.Executing("2"), nodeError}, nil, failed},
+		{"execution_interrupted", "SaveImageWebsocket", []comfyuitest.Step{comfyuitest.Executing("2"), {Type: "execution_interrupted"}}, nil, internal.ErrPromptInterrupted},
+		{"error after socket drop", "SaveImage", []comfyuitest.Step{
+			comfyuitest.Drop(), comfyuitest.Executing("2"), nodeError,
+		}, nil, failed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake.SetSteps(tc.steps...)
+			images, err := client.GetImages(context.Background(), map[string]interface{}{
+				"1": map[string]interface{}{"class_type": tc.output, "inputs": map[string]interface{}{}},
+				"2": map[string]interface{}{"class_type": "KSampler", "inputs": map[string]interface{}{}},
+			})
+			switch want := tc.wantErr.(type) {
+			case error:
+				if !errors.Is(err, want) {
+					t.Fatalf("error = %v, want %v", err, want)
+				}
+				return
+			case string:
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Fatalf("error = %v, want %q", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Sampler previews are not outputs
+			if len(images) != 1 || len(images["1"]) != 1 || !bytes.Equal(images["1"][0], tc.want) {
+				t.Errorf("images = %q, want %q from node 1", images, tc.want)
+			}
+		})
+	}
+}
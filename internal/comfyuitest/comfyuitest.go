@@ -0,0 +1,552 @@
+// Package comfyuitest provides a fake ComfyUI server for tests. It
+// implements the parts of the ComfyUI API the server uses and answers
+// every prompt by replaying a scripted sequence of websocket messages.
+package comfyuitest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Binary websocket messages carry an event type and an image format
+// before the image data.
+const (
+	binaryEventPreviewImage = 1
+	imageFormatPNG          = 2
+)
+
+// Step is one step of a script: a text message, an image, a pause, a
+// dropped socket or a hang.
+type Step struct {
+	// Type and Data make up a text message. A prompt_id is added to Data
+	// unless it has one.
+	Type string
+	Data map[string]interface{}
+	// Image is sent as a binary preview image message.
+	Image []byte
+	// Delay pauses before the step.
+	Delay time.Duration
+	// Drop closes the websocket; the script runs on without it.
+	Drop bool
+	// Hang stops the script until the prompt is interrupted or deleted.
+	Hang bool
+}
+
+// Script returns the steps run for a queued prompt.
+type Script func(prompt map[string]interface{}) []Step
+
+// Executing reports that a node started. An empty node ends the prompt.
+func Executing(node string) Step {
+	data := map[string]interface{}{"node": node}
+	if node == "" {
+		data["node"] = nil
+	}
+	return Step{Type: "executing", Data: data}
+}
+
+// Image sends an image as the output of the node executing last.
+func Image(data []byte) Step {
+	return Step{Image: data}
+}
+
+// ExecutionError reports that a node failed.
+func ExecutionError(node, nodeType, exceptionType, message string) Step {
+	return Step{Type: "execution_error", Data: map[string]interface{}{
+		"node_id":           node,
+		"node_type":         nodeType,
+		"exception_type":    exceptionType,
+		"exception_message": message,
+	}}
+}
+
+// Drop closes the websocket.
+func Drop() Step {
+	return Step{Drop: true}
+}
+
+// Hang waits until the prompt is interrupted or deleted from the queue.
+func Hang() Step {
+	return Step{Hang: true}
+}
+
+// Render is the script of a successful prompt: every SaveImageWebsocket
+// node of the prompt sends image.
+func Render(image []byte) Script {
+	return func(prompt map[string]interface{}) []Step {
+		steps := []Step{{Type: "execution_start", Data: map[string]interface{}{}}}
+		for id, node := range prompt {
+			if n, ok := node.(map[string]interface{}); ok && n["class_type"] == "SaveImageWebsocket" {
+				steps = append(steps, Executing(id), Image(image))
+			}
+		}
+		return append(steps, Executing(""))
+	}
+}
+
+// Prompt is a prompt the server received.
+type Prompt struct {
+	ID       string
+	ClientID string
+	Workflow map[string]interface{}
+}
+
+// historyEntry is the /history record of a finished prompt.
+type historyEntry struct {
+	Outputs map[string]interface{} `json:"outputs"`
+	Status  struct {
+		StatusStr string          `json:"status_str"`
+		Completed bool            `json:"completed"`
+		Messages  [][]interface{} `json:"messages"`
+	} `json:"status"`
+}
+
+// Server is a fake ComfyUI instance. Its URL is the base URL of the API.
+type Server struct {
+	*httptest.Server
+
+	// Image is the canned image sent for outputs and served by /view.
+	Image []byte
+
+	mu          sync.Mutex
+	script      Script
+	down        bool
+	pending     bool
+	conns       map[string]*socket
+	prompts     []Prompt
+	running     map[string]chan struct{}
+	history     map[string]*historyEntry
+	interrupted []string
+	deleted     []string
+	uploads     map[string][]byte
+	closing     chan struct{}
+	scripts     sync.WaitGroup
+}
+
+// socket is a client's websocket; writes must not interleave.
+type socket struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// NewServer starts a fake ComfyUI that renders every prompt with Render
+// and Image until SetScript is called. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Image:   DefaultImage(),
+		conns:   make(map[string]*socket),
+		running: make(map[string]chan struct{}),
+		history: make(map[string]*historyEntry),
+		uploads: make(map[string][]byte),
+		closing: make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prompt", s.handlePrompt)
+	mux.HandleFunc("/ws", s.handleWS)
+	mux.HandleFunc("/history/", s.handleHistory)
+	mux.HandleFunc("/view", s.handleView)
+	mux.HandleFunc("/upload/image", s.handleUpload)
+	mux.HandleFunc("/system_stats", s.handleSystemStats)
+	mux.HandleFunc("/queue", s.handleQueue)
+	mux.HandleFunc("/interrupt", s.handleInterrupt)
+	s.Server = httptest.NewServer(s.available(mux))
+	return s
+}
+
+// Close stops hanging scripts and shuts the server down.
+func (s *Server) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	for _, c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	s.scripts.Wait()
+	s.Server.Close()
+}
+
+// SetScript sets the script run for prompts queued from now on.
+func (s *Server) SetScript(script Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = script
+}
+
+// SetSteps runs the same steps for every prompt.
+func (s *Server) SetSteps(steps ...Step) {
+	s.SetScript(func(map[string]interface{}) []Step { return steps })
+}
+
+// SetDown makes every endpoint answer 503 while down is true.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// SetPending makes /queue list unfinished prompts as pending instead of
+// running, as if they were waiting behind other prompts.
+func (s *Server) SetPending(pending bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = pending
+}
+
+// Prompts returns the prompts received so far.
+func (s *Server) Prompts() []Prompt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Prompt(nil), s.prompts...)
+}
+
+// Interrupted returns the IDs of the prompts stopped with /interrupt.
+func (s *Server) Interrupted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.interrupted...)
+}
+
+// Deleted returns the IDs of the prompts deleted from the queue.
+func (s *Server) Deleted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.deleted...)
+}
+
+// Upload returns an image uploaded with /upload/image.
+func (s *Server) Upload(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.uploads[name]
+	return data, ok
+}
+
+// WaitIdle waits until no prompt is running.
+func (s *Server) WaitIdle() {
+	s.scripts.Wait()
+}
+
+func (s *Server) available(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		down := s.down
+		s.mu.Unlock()
+		if down {
+			http.Error(w, "ComfyUI is down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Prompt   map[string]interface{} `json:"prompt"`
+		ClientID string                 `json:"client_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Prompt) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": map[string]interface{}{"type": "invalid_prompt", "message": "invalid prompt"},
+		})
+		return
+	}
+
+	id := uuid.NewString()
+	stop := make(chan struct{})
+	s.mu.Lock()
+	s.prompts = append(s.prompts, Prompt{ID: id, ClientID: body.ClientID, Workflow: body.Prompt})
+	s.running[id] = stop
+	script := s.script
+	if script == nil {
+		script = Render(s.Image)
+	}
+	steps := script(body.Prompt)
+	s.scripts.Add(1)
+	s.mu.Unlock()
+
+	go s.run(id, body.ClientID, body.Prompt, steps, stop)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"prompt_id": id, "number": len(s.Prompts()) - 1})
+}
+
+// run replays steps to the client and records the prompt's history.
+func (s *Server) run(id, clientID string, prompt map[string]interface{}, steps []Step, stop chan struct{}) {
+	defer s.scripts.Done()
+
+	entry := &historyEntry{Outputs: make(map[string]interface{})}
+	entry.Status.StatusStr = "success"
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.history[id] = entry
+		s.mu.Unlock()
+	}()
+
+	currentNode := ""
+	for _, step := range steps {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-stop:
+				s.interrupt(id, clientID, entry)
+				return
+			case <-s.closing:
+				return
+			}
+		}
+		switch {
+		case step.Hang:
+			select {
+			case <-stop:
+				s.interrupt(id, clientID, entry)
+			case <-s.closing:
+			}
+			return
+		case step.Drop:
+			s.mu.Lock()
+			if c := s.conns[clientID]; c != nil {
+				c.conn.Close()
+				delete(s.conns, clientID)
+			}
+			s.mu.Unlock()
+		case step.Image != nil:
+			msg := make([]byte, 8, 8+len(step.Image))
+			binary.BigEndian.PutUint32(msg[:4], binaryEventPreviewImage)
+			binary.BigEndian.PutUint32(msg[4:8], imageFormatPNG)
+			s.send(clientID, websocket.BinaryMessage, append(msg, step.Image...))
+		default:
+			data := make(map[string]interface{}, len(step.Data)+1)
+			for k, v := range step.Data {
+				data[k] = v
+			}
+			if _, ok := data["prompt_id"]; !ok && step.Type != "status" {
+				data["prompt_id"] = id
+			}
+			if step.Type == "executing" {
+				currentNode, _ = data["node"].(string)
+				if n, ok := prompt[currentNode].(map[string]interface{}); ok && n["class_type"] == "SaveImage" {
+					entry.Outputs[currentNode] = map[string]interface{}{"images": []interface{}{
+						map[string]interface{}{"filename": fmt.Sprintf("fake_%s_%s.png", id, currentNode), "subfolder": "", "type": "output"},
+					}}
+				}
+			}
+			if step.Type == "execution_error" {
+				entry.Status.StatusStr = "error"
+				entry.Status.Messages = append(entry.Status.Messages, []interface{}{step.Type, data})
+			}
+			msg, _ := json.Marshal(map[string]interface{}{"type": step.Type, "data": data})
+			s.send(clientID, websocket.TextMessage, msg)
+		}
+	}
+	entry.Status.Completed = entry.Status.StatusStr == "success"
+}
+
+// interrupt tells the client that a prompt was stopped.
+func (s *Server) interrupt(id, clientID string, entry *historyEntry) {
+	data := map[string]interface{}{"prompt_id": id}
+	entry.Status.StatusStr = "error"
+	entry.Status.Messages = append(entry.Status.Messages, []interface{}{"execution_interrupted", data})
+	msg, _ := json.Marshal(map[string]interface{}{"type": "execution_interrupted", "data": data})
+	s.send(clientID, websocket.TextMessage, msg)
+}
+
+// send writes a message to a client's websocket, if it is connected.
+func (s *Server) send(clientID string, msgType int, msg []byte) {
+	s.mu.Lock()
+	c := s.conns[clientID]
+	s.mu.Unlock()
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteMessage(msgType, msg)
+}
+
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	clientID := r.URL.Query().Get("clientId")
+	if clientID == "" {
+		clientID = uuid.NewString()
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &socket{conn: conn}
+	s.mu.Lock()
+	s.conns[clientID] = c
+	s.mu.Unlock()
+
+	status, _ := json.Marshal(map[string]interface{}{
+		"type": "status",
+		"data": map[string]interface{}{"status": map[string]interface{}{"exec_info": map[string]interface{}{"queue_remaining": 0}}, "sid": clientID},
+	})
+	c.mu.Lock()
+	conn.WriteMessage(websocket.TextMessage, status)
+	c.mu.Unlock()
+
+	// Read until the client goes away
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	s.mu.Lock()
+	if s.conns[clientID] == c {
+		delete(s.conns, clientID)
+	}
+	s.mu.Unlock()
+	conn.Close()
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/history/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := map[string]interface{}{}
+	if entry, ok := s.history[id]; ok {
+		history[id] = entry
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("filename")
+	if name == "" {
+		http.Error(w, "filename is required", http.StatusBadRequest)
+		return
+	}
+	data := s.Image
+	if upload, ok := s.Upload(name); ok && r.URL.Query().Get("type") == "input" {
+		data = upload
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Write(data)
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.uploads[header.Filename] = data
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": header.Filename, "subfolder": r.FormValue("subfolder"), "type": "input"})
+}
+
+func (s *Server) handleSystemStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"system":  map[string]interface{}{"os": "fake", "comfyui_version": "fake"},
+		"devices": []interface{}{},
+	})
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var body struct {
+			Delete []string `json:"delete"`
+			Clear  bool     `json:"clear"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		for _, id := range body.Delete {
+			if stop, ok := s.running[id]; ok {
+				s.deleted = append(s.deleted, id)
+				close(stop)
+				delete(s.running, id)
+			}
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.mu.Lock()
+	unfinished := make([]interface{}, 0, len(s.running))
+	for _, p := range s.prompts {
+		if _, ok := s.running[p.ID]; ok {
+			unfinished = append(unfinished, []interface{}{len(unfinished), p.ID, p.Workflow, map[string]interface{}{}, []string{}})
+		}
+	}
+	running, pending := unfinished, []interface{}{}
+	if s.pending {
+		running, pending = pending, running
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"queue_running": running, "queue_pending": pending})
+}
+
+func (s *Server) handleInterrupt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		PromptID string `json:"prompt_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	for id, stop := range s.running {
+		// Without a prompt_id everything running is interrupted
+		if body.PromptID == "" || body.PromptID == id {
+			s.interrupted = append(s.interrupted, id)
+			close(stop)
+			delete(s.running, id)
+		}
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// DefaultImage returns the canned image: a small solid PNG.
+func DefaultImage() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 80, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
@@ -0,0 +1,372 @@
+// Package config loads the settings of the server once at startup.
+//
+// Settings come, from lowest to highest precedence, from the defaults
+// below, the optional YAML file named by CONFIG_FILE, and the environment,
+// which is first filled from .env.<APP_ENV> or .env. Every missing or
+// invalid setting is reported in a single error, so a misconfigured
+// server fails at startup rather than on the first request that needs it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server. The yaml tags are the keys
+// of the YAML file; the environment variable of each setting is listed in
+// its comment.
+type Config struct {
+	// Env is APP_ENV, dev by default. It selects the .env file.
+	Env string `yaml:"-"`
+
+	Server    Server    `yaml:"server"`
+	Auth      Auth      `yaml:"auth"`
+	R2        R2        `yaml:"r2"`
+	ComfyUI   ComfyUI   `yaml:"comfyui"`
+	Segmenter Segmenter `yaml:"segmenter"`
+	Debug     Debug     `yaml:"debug_artifacts"`
+	Weather   Weather   `yaml:"weather"`
+	Data      Data      `yaml:"data"`
+	// MaskPromptsPath is MASK_PROMPTS_PATH, a JSON file overriding the
+	// garment type to mask prompt table.
+	MaskPromptsPath string `yaml:"mask_prompts_path"`
+}
+
+// Server configures the HTTP server.
+type Server struct {
+	// Port is PORT.
+	Port int `yaml:"port"`
+}
+
+// Auth configures authentication.
+type Auth struct {
+	// JWTSecret is JWT_SECRET, which signs and verifies tokens.
+	JWTSecret string `yaml:"jwt_secret"`
+	// AdminUserIDs is the comma-separated ADMIN_USER_IDS.
+	AdminUserIDs []string `yaml:"admin_user_ids"`
+}
+
+// R2 configures the Cloudflare R2 bucket blobs are stored in.
+type R2 struct {
+	// AccountID is R2_ACCOUNT_ID.
+	AccountID string `yaml:"account_id"`
+	// AccessKeyID is R2_ACCESS_KEY_ID.
+	AccessKeyID string `yaml:"access_key_id"`
+	// SecretAccessKey is R2_SECRET_ACCESS_KEY.
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// Bucket is R2_BUCKET_NAME.
+	Bucket string `yaml:"bucket"`
+	// PublicURL is R2_PUBLIC_URL, the base URL blobs are served from.
+	PublicURL string `yaml:"public_url"`
+}
+
+// ComfyUI configures the ComfyUI backends try-ons are rendered on.
+type ComfyUI struct {
+	// Path is COMFYUI_PATH. When set, ComfyUI is run from this checkout
+	// and supervised; otherwise it is expected to be running already.
+	Path string `yaml:"path"`
+	// Command and Args are COMFYUI_COMMAND and the space-separated
+	// COMFYUI_ARGS ComfyUI is started with.
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// URLs is the comma-separated COMFYUI_URLS prompts are spread over.
+	URLs []string `yaml:"urls"`
+	// URL is COMFYUI_URL, where the supervised ComfyUI is polled for
+	// readiness. It defaults to the first of URLs.
+	URL string `yaml:"url"`
+	// PromptTimeout is COMFYUI_PROMPT_TIMEOUT, how long a prompt may run.
+	PromptTimeout time.Duration `yaml:"prompt_timeout"`
+	// WorkflowPath is WORKFLOW_PATH, the try-on workflow template.
+	WorkflowPath string `yaml:"workflow_path"`
+}
+
+// Segmenter configures the garment segmentation service.
+type Segmenter struct {
+	// URL is SEGMENTER_URL.
+	URL string `yaml:"url"`
+}
+
+// Debug configures the debug artifacts of try-ons.
+type Debug struct {
+	// Dir is DEBUG_ARTIFACTS_DIR. Artifacts are only recorded when set.
+	Dir string `yaml:"dir"`
+	// MaxJobs is DEBUG_ARTIFACTS_MAX_JOBS.
+	MaxJobs int `yaml:"max_jobs"`
+	// MaxAge is DEBUG_ARTIFACTS_MAX_AGE.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// Weather configures the weather service recommendations use.
+type Weather struct {
+	// APIURL is WEATHER_API_URL. Without it recommendations by location
+	// are rejected.
+	APIURL string `yaml:"api_url"`
+}
+
+// Data configures the JSON files records are kept in.
+type Data struct {
+	// Wardrobe is WARDROBE_DB_PATH.
+	Wardrobe string `yaml:"wardrobe"`
+	// Outfits is OUTFITS_DB_PATH.
+	Outfits string `yaml:"outfits"`
+	// WearLog is WEAR_LOG_DB_PATH.
+	WearLog string `yaml:"wear_log"`
+	// PersonPhotos is PERSON_PHOTOS_DB_PATH.
+	PersonPhotos string `yaml:"person_photos"`
+	// TryOns is TRYONS_DB_PATH.
+	TryOns string `yaml:"tryons"`
+	// TryOnCache is TRYON_CACHE_PATH.
+	TryOnCache string `yaml:"tryon_cache"`
+}
+
+// Defaults returns the configuration used for settings that are not set.
+func Defaults() Config {
+	return Config{
+		Env:    "dev",
+		Server: Server{Port: 8080},
+		ComfyUI: ComfyUI{
+			URLs:          []string{internal.DefaultComfyUIURL},
+			PromptTimeout: internal.DefaultPromptTimeout,
+			WorkflowPath:  "./ImageWorkflow.json",
+		},
+		Segmenter: Segmenter{URL: internal.DefaultSegmenterURL},
+		Debug: Debug{
+			MaxJobs: internal.DefaultDebugMaxJobs,
+			MaxAge:  internal.DefaultDebugMaxAge,
+		},
+		Data: Data{
+			Wardrobe:     "./data/wardrobe.json",
+			Outfits:      "./data/outfits.json",
+			WearLog:      "./data/wear_log.json",
+			PersonPhotos: "./data/person_photos.json",
+			TryOns:       "./data/tryons.json",
+			TryOnCache:   "./data/tryon_cache.json",
+		},
+	}
+}
+
+// Load fills the environment from .env.<APP_ENV>, or .env when that does
+// not exist, and loads the configuration. Variables already set in the
+// environment are not overridden by the files.
+func Load() (*Config, error) {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "dev"
+	}
+	envFile := fmt.Sprintf(".env.%s", env)
+	if _, err := os.Stat(envFile); err != nil {
+		envFile = ".env"
+	}
+	if _, err := os.Stat(envFile); err == nil {
+		log.Printf("Loading environment from %s", envFile)
+		if err := godotenv.Load(envFile); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", envFile, err)
+		}
+	} else {
+		log.Printf("No .env.%s or .env file found, using the environment only", env)
+	}
+	return FromEnv(os.LookupEnv)
+}
+
+// FromEnv loads the configuration from the YAML file named by CONFIG_FILE,
+// if any, and the variables lookup returns, and validates it.
+func FromEnv(lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Defaults()
+	l := &loader{lookup: lookup}
+
+	if path, ok := l.get("CONFIG_FILE"); ok {
+		if err := cfg.readYAML(path); err != nil {
+			// Later problems could stem from the broken file
+			return nil, err
+		}
+	}
+
+	l.string("APP_ENV", &cfg.Env)
+	l.int("PORT", &cfg.Server.Port)
+	l.string("JWT_SECRET", &cfg.Auth.JWTSecret)
+	l.list("ADMIN_USER_IDS", &cfg.Auth.AdminUserIDs)
+	l.string("R2_ACCOUNT_ID", &cfg.R2.AccountID)
+	l.string("R2_ACCESS_KEY_ID", &cfg.R2.AccessKeyID)
+	l.string("R2_SECRET_ACCESS_KEY", &cfg.R2.SecretAccessKey)
+	l.string("R2_BUCKET_NAME", &cfg.R2.Bucket)
+	l.string("R2_PUBLIC_URL", &cfg.R2.PublicURL)
+	l.string("COMFYUI_PATH", &cfg.ComfyUI.Path)
+	l.string("COMFYUI_COMMAND", &cfg.ComfyUI.Command)
+	if raw, ok := l.get("COMFYUI_ARGS"); ok {
+		cfg.ComfyUI.Args = strings.Fields(raw)
+	}
+	l.list("COMFYUI_URLS", &cfg.ComfyUI.URLs)
+	l.string("COMFYUI_URL", &cfg.ComfyUI.URL)
+	if cfg.ComfyUI.URL == "" && len(cfg.ComfyUI.URLs) > 0 {
+		cfg.ComfyUI.URL = cfg.ComfyUI.URLs[0]
+	}
+	l.duration("COMFYUI_PROMPT_TIMEOUT", &cfg.ComfyUI.PromptTimeout)
+	l.string("WORKFLOW_PATH", &cfg.ComfyUI.WorkflowPath)
+	l.string("SEGMENTER_URL", &cfg.Segmenter.URL)
+	l.string("MASK_PROMPTS_PATH", &cfg.MaskPromptsPath)
+	l.string("DEBUG_ARTIFACTS_DIR", &cfg.Debug.Dir)
+	l.int("DEBUG_ARTIFACTS_MAX_JOBS", &cfg.Debug.MaxJobs)
+	l.duration("DEBUG_ARTIFACTS_MAX_AGE", &cfg.Debug.MaxAge)
+	l.string("WEATHER_API_URL", &cfg.Weather.APIURL)
+	l.string("WARDROBE_DB_PATH", &cfg.Data.Wardrobe)
+	l.string("OUTFITS_DB_PATH", &cfg.Data.Outfits)
+	l.string("WEAR_LOG_DB_PATH", &cfg.Data.WearLog)
+	l.string("PERSON_PHOTOS_DB_PATH", &cfg.Data.PersonPhotos)
+	l.string("TRYONS_DB_PATH", &cfg.Data.TryOns)
+	l.string("TRYON_CACHE_PATH", &cfg.Data.TryOnCache)
+
+	problems := append(l.problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return &cfg, nil
+}
+
+// readYAML overlays the settings of a YAML file. Unknown keys are errors,
+// so that misspelt settings are not silently ignored.
+func (c *Config) readYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// problems lists the missing and invalid settings.
+func (c *Config) problems() []string {
+	var problems []string
+	required := func(name, value string) {
+		if value == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+	httpURL := func(name, value string) {
+		if value == "" {
+			return
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s must be an http or https URL, got %q", name, value))
+		}
+	}
+	positive := func(name string, value int64) {
+		if value <= 0 {
+			problems = append(problems, name+" must be positive")
+		}
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	required("JWT_SECRET", c.Auth.JWTSecret)
+	required("R2_ACCOUNT_ID", c.R2.AccountID)
+	required("R2_ACCESS_KEY_ID", c.R2.AccessKeyID)
+	required("R2_SECRET_ACCESS_KEY", c.R2.SecretAccessKey)
+	required("R2_BUCKET_NAME", c.R2.Bucket)
+	required("R2_PUBLIC_URL", c.R2.PublicURL)
+	httpURL("R2_PUBLIC_URL", c.R2.PublicURL)
+
+	if len(c.ComfyUI.URLs) == 0 {
+		problems = append(problems, "COMFYUI_URLS needs at least one URL")
+	}
+	for _, u := range c.ComfyUI.URLs {
+		httpURL("COMFYUI_URLS", u)
+	}
+	httpURL("COMFYUI_URL", c.ComfyUI.URL)
+	positive("COMFYUI_PROMPT_TIMEOUT", int64(c.ComfyUI.PromptTimeout))
+	if c.ComfyUI.Path != "" {
+		if info, err := os.Stat(c.ComfyUI.Path); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("COMFYUI_PATH %q is not a directory", c.ComfyUI.Path))
+		}
+	}
+	required("WORKFLOW_PATH", c.ComfyUI.WorkflowPath)
+	if c.ComfyUI.WorkflowPath != "" {
+		if _, err := os.Stat(c.ComfyUI.WorkflowPath); err != nil {
+			problems = append(problems, fmt.Sprintf("WORKFLOW_PATH: %v", err))
+		}
+	}
+
+	required("SEGMENTER_URL", c.Segmenter.URL)
+	httpURL("SEGMENTER_URL", c.Segmenter.URL)
+	httpURL("WEATHER_API_URL", c.Weather.APIURL)
+	positive("DEBUG_ARTIFACTS_MAX_JOBS", int64(c.Debug.MaxJobs))
+	positive("DEBUG_ARTIFACTS_MAX_AGE", int64(c.Debug.MaxAge))
+
+	required("WARDROBE_DB_PATH", c.Data.Wardrobe)
+	required("OUTFITS_DB_PATH", c.Data.Outfits)
+	required("WEAR_LOG_DB_PATH", c.Data.WearLog)
+	required("PERSON_PHOTOS_DB_PATH", c.Data.PersonPhotos)
+	required("TRYONS_DB_PATH", c.Data.TryOns)
+	required("TRYON_CACHE_PATH", c.Data.TryOnCache)
+	return problems
+}
+
+// loader overrides settings with environment variables, collecting the
+// values that do not parse. Empty variables count as unset.
+type loader struct {
+	lookup   func(string) (string, bool)
+	problems []string
+}
+
+func (l *loader) get(name string) (string, bool) {
+	value, ok := l.lookup(name)
+	value = strings.TrimSpace(value)
+	return value, ok && value != ""
+}
+
+func (l *loader) string(name string, dst *string) {
+	if value, ok := l.get(name); ok {
+		*dst = value
+	}
+}
+
+func (l *loader) int(name string, dst *int) {
+	if raw, ok := l.get(name); ok {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s must be an integer, got %q", name, raw))
+			return
+		}
+		*dst = value
+	}
+}
+
+func (l *loader) duration(name string, dst *time.Duration) {
+	if raw, ok := l.get(name); ok {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s must be a duration such as 90s or 10m, got %q", name, raw))
+			return
+		}
+		*dst = value
+	}
+}
+
+// list sets a comma-separated list, dropping empty items.
+func (l *loader) list(name string, dst *[]string) {
+	if raw, ok := l.get(name); ok {
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		*dst = values
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validEnv returns the required settings and a workflow file that exists.
+func validEnv(t *testing.T) map[string]string {
+	t.Helper()
+	workflow := filepath.Join(t.TempDir(), "workflow.json")
+	if err := os.WriteFile(workflow, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"JWT_SECRET":           "secret",
+		"R2_ACCOUNT_ID":        "account",
+		"R2_ACCESS_KEY_ID":     "key",
+		"R2_SECRET_ACCESS_KEY": "key-secret",
+		"R2_BUCKET_NAME":       "bucket",
+		"R2_PUBLIC_URL":        "https://cdn.example.com",
+		"WORKFLOW_PATH":        workflow,
+	}
+}
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestFromEnvDefaults(t *testing.T) {
+	cfg, err := FromEnv(lookup(validEnv(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := Defaults()
+	if cfg.Server.Port != 8080 || !reflect.DeepEqual(cfg.ComfyUI.URLs, defaults.ComfyUI.URLs) ||
+		cfg.Segmenter.URL != defaults.Segmenter.URL || cfg.Data != defaults.Data {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if cfg.Auth.JWTSecret != "secret" || cfg.R2.Bucket != "bucket" {
+		t.Errorf("environment not applied: %+v", cfg)
+	}
+}
+
+func TestFromEnvReportsEveryProblem(t *testing.T) {
+	_, err := FromEnv(lookup(map[string]string{
+		"PORT":                    "http",
+		"R2_PUBLIC_URL":           "cdn.example.com",
+		"COMFYUI_URLS":            " , ",
+		"COMFYUI_PROMPT_TIMEOUT":  "10",
+		"DEBUG_ARTIFACTS_MAX_AGE": "-1h",
+		"WORKFLOW_PATH":           filepath.Join(t.TempDir(), "missing.json"),
+	}))
+	if err == nil {
+		t.Fatal("invalid configuration loaded")
+	}
+	for _, want := range []string{
+		"PORT must be an integer",
+		"COMFYUI_PROMPT_TIMEOUT must be a duration",
+		"JWT_SECRET is required",
+		"R2_ACCOUNT_ID is required",
+		"R2_ACCESS_KEY_ID is required",
+		"R2_SECRET_ACCESS_KEY is required",
+		"R2_BUCKET_NAME is required",
+		"R2_PUBLIC_URL must be an http or https URL",
+		"COMFYUI_URLS needs at least one URL",
+		"WORKFLOW_PATH",
+		"DEBUG_ARTIFACTS_MAX_AGE must be positive",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestFromEnvYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+server:
+  port: 9090
+auth:
+  admin_user_ids: [alice, bob]
+comfyui:
+  urls: ["http://gpu-1:8188", "http://gpu-2:8188"]
+  prompt_timeout: 5m
+  args: [main.py, --listen, 0.0.0.0]
+debug_artifacts:
+  dir: /tmp/debug
+  max_jobs: 10
+`
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := validEnv(t)
+	env["CONFIG_FILE"] = path
+	// The environment overrides the file
+	env["PORT"] = "9191"
+
+	cfg, err := FromEnv(lookup(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 9191 {
+		t.Errorf("port = %d, want the environment's 9191", cfg.Server.Port)
+	}
+	if !reflect.DeepEqual(cfg.Auth.AdminUserIDs, []string{"alice", "bob"}) {
+		t.Errorf("admin user IDs = %v", cfg.Auth.AdminUserIDs)
+	}
+	if len(cfg.ComfyUI.URLs) != 2 || cfg.ComfyUI.PromptTimeout != 5*time.Minute || len(cfg.ComfyUI.Args) != 3 {
+		t.Errorf("comfyui = %+v", cfg.ComfyUI)
+	}
+	if cfg.Debug.Dir != "/tmp/debug" || cfg.Debug.MaxJobs != 10 || cfg.Debug.MaxAge != Defaults().Debug.MaxAge {
+		t.Errorf("debug = %+v", cfg.Debug)
+	}
+
+	// Misspelt keys are rejected rather than ignored
+	if err := os.WriteFile(path, []byte("server:\n  prot: 9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromEnv(lookup(env)); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("error = %v, want the unknown key reported", err)
+	}
+}
+
+func TestFromEnvComfyUIURL(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"default", nil, Defaults().ComfyUI.URLs[0]},
+		{"first of the pool", map[string]string{"COMFYUI_URLS": "http://gpu-1:8188,http://gpu-2:8188"}, "http://gpu-1:8188"},
+		{"explicit", map[string]string{"COMFYUI_URLS": "http://gpu-1:8188", "COMFYUI_URL": "http://127.0.0.1:8190"}, "http://127.0.0.1:8190"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := validEnv(t)
+			for name, value := range tc.env {
+				env[name] = value
+			}
+			cfg, err := FromEnv(lookup(env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ComfyUI.URL != tc.want {
+				t.Errorf("url = %q, want %q", cfg.ComfyUI.URL, tc.want)
+			}
+		})
+	}
+
+	env := validEnv(t)
+	env["COMFYUI_URL"] = "localhost:8188"
+	if _, err := FromEnv(lookup(env)); err == nil || !strings.Contains(err.Error(), "COMFYUI_URL must be an http or https URL") {
+		t.Errorf("error = %v, want the invalid URL reported", err)
+	}
+}
+
+func TestLoadEnvFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		env   string
+		files map[string]string
+		// secret is JWT_SECRET in the environment, if set
+		secret string
+		want   string
+	}{
+		{"environment file", "test", map[string]string{".env.test": "from-test", ".env": "from-default"}, "", "from-test"},
+		{"default file", "staging", map[string]string{".env.test": "from-test", ".env": "from-default"}, "", "from-default"},
+		{"dev by default", "", map[string]string{".env.dev": "from-dev", ".env": "from-default"}, "", "from-dev"},
+		{"environment wins", "test", map[string]string{".env.test": "from-test"}, "from-env", "from-env"},
+		{"no file", "test", nil, "from-env", "from-env"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			for name, secret := range tc.files {
+				if err := os.WriteFile(name, []byte("JWT_SECRET="+secret+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env := validEnv(t)
+			env["APP_ENV"] = tc.env
+			env["JWT_SECRET"] = tc.secret
+			for name, value := range env {
+				// Setenv restores the variable after the test, even
+				// when it is unset here or set by the file
+				t.Setenv(name, value)
+				if value == "" {
+					os.Unsetenv(name)
+				}
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Auth.JWTSecret != tc.want {
+				t.Errorf("JWT secret = %q, want %q", cfg.Auth.JWTSecret, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+)
+
+// Layout of contact sheets.
+const (
+	DefaultContactCellWidth  = 256
+	DefaultContactCellHeight = 384
+	contactSheetGap          = 8
+)
+
+var (
+	contactSheetBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	contactSheetMissing    = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+)
+
+// ContactSheet stitches encoded images into a JPEG grid. cells is indexed
+// by row, then column; rows may be ragged. Every image is scaled to fit
+// its cell keeping its aspect ratio, and missing or undecodable images are
+// drawn as grey placeholders so the grid positions stay meaningful.
+func ContactSheet(cells [][][]byte, cellWidth, cellHeight int) ([]byte, error) {
+	if cellWidth <= 0 || cellHeight <= 0 {
+		return nil, fmt.Errorf("invalid cell size %dx%d", cellWidth, cellHeight)
+	}
+	rows, cols := len(cells), 0
+	for _, row := range cells {
+		cols = max(cols, len(row))
+	}
+	if rows == 0 || cols == 0 {
+		return nil, fmt.Errorf("contact sheet has no cells")
+	}
+
+	width := cols*cellWidth + (cols+1)*contactSheetGap
+	height := rows*cellHeight + (rows+1)*contactSheetGap
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(contactSheetBackground), image.Point{}, draw.Src)
+
+	for r := 0; r < rows; r++ {
+		for col := 0; col < cols; col++ {
+			x := contactSheetGap + col*(cellWidth+contactSheetGap)
+			y := contactSheetGap + r*(cellHeight+contactSheetGap)
+			cell := image.Rect(x, y, x+cellWidth, y+cellHeight)
+
+			var img image.Image
+			if col < len(cells[r]) && len(cells[r][col]) > 0 {
+				img, _, _ = image.Decode(bytes.NewReader(cells[r][col]))
+			}
+			if img == nil {
+				draw.Draw(sheet, cell, image.NewUniform(contactSheetMissing), image.Point{}, draw.Src)
+				continue
+			}
+			drawFitted(sheet, cell, img)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sheet, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawFitted scales src to fit inside cell, centred, averaging the source
+// pixels that fall into each destination pixel.
+func drawFitted(dst *image.RGBA, cell image.Rectangle, src image.Image) {
+	b := src.Bounds()
+	if b.Empty() {
+		return
+	}
+	scale := min(float64(cell.Dx())/float64(b.Dx()), float64(cell.Dy())/float64(b.Dy()))
+	w := max(1, int(float64(b.Dx())*scale))
+	h := max(1, int(float64(b.Dy())*scale))
+	offX := cell.Min.X + (cell.Dx()-w)/2
+	offY := cell.Min.Y + (cell.Dy()-h)/2
+
+	for y := 0; y < h; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/h
+		sy1 := max(sy0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			sx0 := b.Min.X + x*b.Dx()/w
+			sx1 := max(sx0+1, b.Min.X+(x+1)*b.Dx()/w)
+
+			var sr, sg, sb, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					// Composite on white so transparent areas match the sheet.
+					r, g, bl, a := src.At(sx, sy).RGBA()
+					sr += r + (0xffff - a)
+					sg += g + (0xffff - a)
+					sb += bl + (0xffff - a)
+					n++
+				}
+			}
+			dst.SetRGBA(offX+x, offY+y, color.RGBA{
+				R: uint8(sr / n >> 8),
+				G: uint8(sg / n >> 8),
+				B: uint8(sb / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package internal_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// solidPNG encodes a w×h image of one color.
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sample is the color expected at x, y inside the cell at row, col.
+type sample struct {
+	row, col, x, y int
+	want           color.RGBA
+}
+
+func TestContactSheet(t *testing.T) {
+	const cellW, cellH, gap = 40, 60, 8
+	var (
+		red   = color.RGBA{0xff, 0, 0, 0xff}
+		blue  = color.RGBA{0, 0, 0xff, 0xff}
+		white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+		grey  = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	)
+	wide := solidPNG(t, 80, 40, red)
+	tall := solidPNG(t, 10, 60, blue)
+	transparent := solidPNG(t, 40, 60, color.NRGBA{})
+
+	for _, tc := range []struct {
+		name       string
+		cells      [][][]byte
+		rows, cols int
+		samples    []sample
+	}{
+		{"fitted images", [][][]byte{{wide, tall}}, 1, 2, []sample{
+			{0, 0, 20, 30, red},
+			{0, 0, 20, 5, white}, // above the wide image
+			{0, 1, 20, 30, blue},
+			{0, 1, 3, 30, white}, // beside the tall image
+		}},
+		{"placeholders", [][][]byte{{nil, []byte("not an image")}, {wide}}, 2, 2, []sample{
+			{0, 0, 20, 30, grey},
+			{0, 1, 20, 30, grey},
+			{1, 0, 20, 30, red},
+			{1, 1, 20, 30, grey}, // ragged row
+		}},
+		{"transparent image", [][][]byte{{transparent}}, 1, 1, []sample{
+			{0, 0, 20, 30, white},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := internal.ContactSheet(tc.cells, cellW, cellH)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sheet, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("contact sheet is not a JPEG: %v", err)
+			}
+			wantW, wantH := tc.cols*cellW+(tc.cols+1)*gap, tc.rows*cellH+(tc.rows+1)*gap
+			if b := sheet.Bounds(); b.Dx() != wantW || b.Dy() != wantH {
+				t.Fatalf("sheet is %dx%d, want %dx%d", b.Dx(), b.Dy(), wantW, wantH)
+			}
+			for _, s := range tc.samples {
+				x := gap + s.col*(cellW+gap) + s.x
+				y := gap + s.row*(cellH+gap) + s.y
+				if got := sheet.At(x, y); !near(got, s.want) {
+					t.Errorf("cell %d,%d at %d,%d = %v, want %v", s.row, s.col, s.x, s.y, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+// near allows for JPEG compression.
+func near(got color.Color, want color.RGBA) bool {
+	r, g, b, _ := got.RGBA()
+	diff := func(a uint32, b uint8) bool {
+		d := int(a>>8) - int(b)
+		return d > -24 && d < 24
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+func TestContactSheetErrors(t *testing.T) {
+	img := solidPNG(t, 4, 4, color.Black)
+	for _, tc := range []struct {
+		name          string
+		cells         [][][]byte
+		width, height int
+	}{
+		{"no rows", nil, 40, 60},
+		{"empty rows", [][][]byte{{}, {}}, 40, 60},
+		{"zero width", [][][]byte{{img}}, 0, 60},
+		{"negative height", [][][]byte{{img}}, 40, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := internal.ContactSheet(tc.cells, tc.width, tc.height); err == nil {
+				t.Error("contact sheet built")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Debug artifact retention defaults.
+const (
+	DefaultDebugMaxJobs = 50
+	DefaultDebugMaxAge  = 24 * time.Hour
+)
+
+// debugJobIDPattern restricts job IDs to safe directory names.
+var debugJobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DebugRecorder writes the artifacts of opted-in jobs, such as the
+// submitted workflow and the ComfyUI messages, to one directory per job.
+// Only the newest maxJobs directories younger than maxAge are kept.
+type DebugRecorder struct {
+	dir     string
+	maxJobs int
+	maxAge  time.Duration
+	mu      sync.Mutex
+}
+
+// NewDebugRecorder creates a recorder writing below dir. Non-positive
+// limits take the defaults.
+func NewDebugRecorder(dir string, maxJobs int, maxAge time.Duration) (*DebugRecorder, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("debug artifact directory is empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create debug artifact directory: %w", err)
+	}
+	if maxJobs <= 0 {
+		maxJobs = DefaultDebugMaxJobs
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultDebugMaxAge
+	}
+	return &DebugRecorder{dir: dir, maxJobs: maxJobs, maxAge: maxAge}, nil
+}
+
+// Job starts recording a job, pruning old jobs first. It returns nil, which
+// records nothing, when r is nil or the job cannot be recorded.
+func (r *DebugRecorder) Job(jobID string) *DebugJob {
+	if r == nil {
+		return nil
+	}
+	if !debugJobIDPattern.MatchString(jobID) {
+		log.Printf("Not recording debug artifacts for invalid job ID %q", jobID)
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	dir := filepath.Join(r.dir, jobID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Error creating debug artifacts for job %s: %v", jobID, err)
+		return nil
+	}
+	return &DebugJob{dir: dir}
+}
+
+// pruneLocked removes job directories past the age limit, then the oldest
+// ones past the count limit, leaving room for one more. r.mu must be held.
+func (r *DebugRecorder) pruneLocked() {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		log.Printf("Error listing debug artifacts: %v", err)
+		return
+	}
+
+	type job struct {
+		path    string
+		modTime time.Time
+	}
+	var jobs []job
+	cutoff := time.Now().Add(-r.maxAge)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(r.dir, entry.Name())
+		if info.ModTime().Before(cutoff) {
+			os.RemoveAll(path)
+			continue
+		}
+		jobs = append(jobs, job{path: path, modTime: info.ModTime()})
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].modTime.After(jobs[j].modTime) })
+	for i := r.maxJobs - 1; i >= 0 && i < len(jobs); i++ {
+		os.RemoveAll(jobs[i].path)
+	}
+}
+
+// DebugJob records the artifacts of one job. A nil *DebugJob records
+// nothing, so callers need not check whether debugging is on.
+type DebugJob struct {
+	dir string
+}
+
+// RecordJSON writes v as indented JSON to the named artifact.
+func (j *DebugJob) RecordJSON(name string, v interface{}) {
+	if j == nil {
+		return
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding debug artifact %s: %v", name, err)
+		return
+	}
+	j.RecordBytes(name, data)
+}
+
+// RecordBytes writes data to the named artifact.
+func (j *DebugJob) RecordBytes(name string, data []byte) {
+	if j == nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, filepath.Base(name)), data, 0600); err != nil {
+		log.Printf("Error writing debug artifact %s: %v", name, err)
+	}
+}
+
+type debugJobKey struct{}
+
+// WithDebugJob returns a context whose ComfyUI calls record to job.
+func WithDebugJob(ctx context.Context, job *DebugJob) context.Context {
+	if job == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, debugJobKey{}, job)
+}
+
+// debugJobFrom returns the job recording ctx, or nil.
+func debugJobFrom(ctx context.Context) *DebugJob {
+	job, _ := ctx.Value(debugJobKey{}).(*DebugJob)
+	return job
+}
@@ -0,0 +1,111 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+func TestDebugRecorderPrunes(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name    string
+		maxJobs int
+		maxAge  time.Duration
+		// ages are the ages of the existing job directories, named by index
+		ages []time.Duration
+		kept []string
+	}{
+		{"within limits", 5, time.Hour, []time.Duration{time.Minute, 2 * time.Minute}, []string{"0", "1", "new"}},
+		{"room for the new job", 3, time.Hour, []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute}, []string{"1", "2", "new"}},
+		{"past the age", 5, time.Hour, []time.Duration{time.Minute, 2 * time.Hour, 61 * time.Minute}, []string{"0", "new"}},
+		{"age then count", 2, time.Hour, []time.Duration{2 * time.Hour, 2 * time.Minute, time.Minute}, []string{"2", "new"}},
+		{"only the new job", 1, time.Hour, []time.Duration{time.Minute}, []string{"new"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, age := range tc.ages {
+				job := filepath.Join(dir, string(rune('0'+i)))
+				if err := os.Mkdir(job, 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(job, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Files next to the jobs are left alone
+			if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			recorder, err := internal.NewDebugRecorder(dir, tc.maxJobs, tc.maxAge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job := recorder.Job("new"); job == nil {
+				t.Fatal("new job not recorded")
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept []string
+			for _, entry := range entries {
+				kept = append(kept, entry.Name())
+			}
+			if want := append(slices.Clone(tc.kept), "notes.txt"); !slices.Equal(kept, want) {
+				t.Errorf("kept %v, want %v", kept, want)
+			}
+		})
+	}
+}
+
+func TestDebugRecorderJob(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := internal.NewDebugRecorder(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		recorder *internal.DebugRecorder
+		jobID    string
+		recorded bool
+	}{
+		{"recorded", recorder, "job_1-a", true},
+		{"disabled", nil, "job", false},
+		{"empty ID", recorder, "", false},
+		{"path traversal", recorder, "../escape", false},
+		{"separator", recorder, "a/b", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			job := tc.recorder.Job(tc.jobID)
+			if (job != nil) != tc.recorded {
+				t.Fatalf("job = %v, want recorded: %v", job, tc.recorded)
+			}
+			// A nil job records nothing without failing
+			job.RecordJSON("workflow.json", map[string]int{"steps": 20})
+			job.RecordBytes("../../image.png", []byte("png"))
+			if !tc.recorded {
+				return
+			}
+			for _, name := range []string{"workflow.json", "image.png"} {
+				if _, err := os.Stat(filepath.Join(dir, tc.jobID, name)); err != nil {
+					t.Errorf("artifact %s: %v", name, err)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); err == nil {
+		t.Error("job directory created outside the recorder")
+	}
+
+	if _, err := internal.NewDebugRecorder("", 0, 0); err == nil {
+		t.Error("recorder created without a directory")
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+)
+
+const (
+	// DefaultDuplicateDistance is the largest Hamming distance between two
+	// difference hashes for the garments to be treated as the same item.
+	DefaultDuplicateDistance = 10
+
+	// alphaThreshold is the minimum alpha for a pixel to count as garment
+	// rather than transparent background left by the segmenter.
+	alphaThreshold = 0x8000
+)
+
+// DifferenceHash computes a 64-bit dHash of an encoded image. The image is
+// cropped to its non-transparent bounds and flattened onto white first, so
+// the same garment cut out of two different photos hashes closely.
+func DifferenceHash(imgBytes []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	bounds := opaqueBounds(img)
+	if bounds.Empty() {
+		return 0, fmt.Errorf("image has no opaque pixels")
+	}
+
+	// Downscale to 9x8 grayscale by averaging each cell, then compare
+	// horizontally adjacent cells.
+	const w, h = 9, 8
+	var gray [h][w]float64
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/h
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/w
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					sum += luminanceOnWhite(img, px, py)
+				}
+			}
+			gray[y][x] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// HammingDistance returns the number of differing bits between two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// opaqueBounds returns the smallest rectangle containing every pixel whose
+// alpha is above alphaThreshold.
+func opaqueBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	minX, minY, maxX, maxY := b.Max.X, b.Max.Y, b.Min.X-1, b.Min.Y-1
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a < alphaThreshold {
+				continue
+			}
+			minX, maxX = min(minX, x), max(maxX, x)
+			minY, maxY = min(minY, y), max(maxY, y)
+		}
+	}
+	if maxX < minX {
+		// image.Rect would swap the inverted bounds into a non-empty one
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
+
+// luminanceOnWhite returns the luma of a pixel composited over white, in [0, 1].
+func luminanceOnWhite(img image.Image, x, y int) float64 {
+	r, g, b, a := img.At(x, y).RGBA()
+	// Colors are alpha-premultiplied, so adding the uncovered share of white
+	// composites the pixel onto a white background.
+	bg := 0xffff - a
+	lum := 0.299*float64(r+bg) + 0.587*float64(g+bg) + 0.114*float64(b+bg)
+	return lum / 0xffff
+}
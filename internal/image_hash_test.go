@@ -0,0 +1,102 @@
+package internal_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// pattern is the luminance of a garment at normalized coordinates.
+type pattern func(u, v float64) float64
+
+func waves(u, v float64) float64   { return 0.5 + 0.4*math.Sin(7*u+3*v)*math.Cos(5*v) }
+func circles(u, v float64) float64 { return 0.5 + 0.4*math.Cos(18*math.Hypot(u-0.3, v-0.6)) }
+
+// cutoutPNG draws a garment with pattern p into rect of a transparent
+// canvas, the way the segmenter returns garments.
+func cutoutPNG(t *testing.T, p pattern, canvas, rect image.Rectangle) []byte {
+	t.Helper()
+	img := image.NewNRGBA(canvas)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			u := (float64(x-rect.Min.X) + 0.5) / float64(rect.Dx())
+			v := (float64(y-rect.Min.Y) + 0.5) / float64(rect.Dy())
+			l := uint8(255 * p(u, v))
+			img.Set(x, y, color.NRGBA{R: l, G: l, B: l, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDifferenceHash(t *testing.T) {
+	original := cutoutPNG(t, waves, image.Rect(0, 0, 90, 80), image.Rect(0, 0, 90, 80))
+	for _, tc := range []struct {
+		name string
+		img  []byte
+		// duplicate is whether the image should hash within
+		// DefaultDuplicateDistance of the original
+		duplicate bool
+	}{
+		{"same image", original, true},
+		{"padded with transparency", cutoutPNG(t, waves, image.Rect(0, 0, 200, 160), image.Rect(37, 20, 127, 100)), true},
+		{"rescaled", cutoutPNG(t, waves, image.Rect(0, 0, 180, 160), image.Rect(0, 0, 180, 160)), true},
+		{"rescaled and padded", cutoutPNG(t, waves, image.Rect(0, 0, 100, 100), image.Rect(10, 20, 55, 60)), true},
+		{"different garment", cutoutPNG(t, circles, image.Rect(0, 0, 90, 80), image.Rect(0, 0, 90, 80)), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := internal.DifferenceHash(original)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := internal.DifferenceHash(tc.img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := internal.HammingDistance(a, b)
+			if (d <= internal.DefaultDuplicateDistance) != tc.duplicate {
+				t.Errorf("distance = %d, want duplicate: %v", d, tc.duplicate)
+			}
+		})
+	}
+}
+
+func TestDifferenceHashErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		img  []byte
+	}{
+		{"not an image", []byte("not an image")},
+		{"fully transparent", cutoutPNG(t, waves, image.Rect(0, 0, 10, 10), image.Rectangle{})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := internal.DifferenceHash(tc.img); err == nil {
+				t.Error("hashed without an error")
+			}
+		})
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0b1011, 0b0001, 2},
+		{0, math.MaxUint64, 64},
+		{1 << 63, 1, 2},
+	} {
+		if got := internal.HammingDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("HammingDistance(%b, %b) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -2,29 +2,160 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// The segmenter contract: images are posted as the multipart field
+// SegmentFileField to SegmentPath, and each garment found is returned as a
+// SegmentedItem of a SegmentResponse.
+const (
+	DefaultSegmenterURL = "http://127.0.0.1:8000"
+	SegmentPath         = "/segment/"
+	SegmentFileField    = "file"
+)
+
+// RequiredSegmentMetadata are the metadata keys every segmented item must
+// have. Their values are a label or a list of labels.
+var RequiredSegmentMetadata = []string{"categories", "types"}
+
+// OptionalSegmentMetadata are the other label keys NormalizeAttributes
+// reads. When present they must be a label or a list of labels too.
+var OptionalSegmentMetadata = []string{"seasons", "patterns", "styles", "occasions"}
+
+// SegmentResponse is the body of a successful segmenter response.
+type SegmentResponse struct {
+	SegmentedImages []SegmentedItem `json:"segmented_images"`
+}
+
+// SegmentedItem is one garment cut out of the posted image.
+type SegmentedItem struct {
+	Filename string `json:"filename"`
+	// Image is the encoded garment image in standard base64.
+	Image    string                 `json:"image"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// Validate checks a response against the contract and reports every
+// violation at once.
+func (r SegmentResponse) Validate() error {
+	if r.SegmentedImages == nil {
+		return errors.New("segmented_images is missing")
+	}
+	var problems []string
+	for i, item := range r.SegmentedImages {
+		if err := item.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("segmented_images[%d]: %v", i, err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Validate checks that the item has a filename, a base64 image that
+// decodes, and well-formed metadata.
+func (item SegmentedItem) Validate() error {
+	var problems []string
+	if item.Filename == "" {
+		problems = append(problems, "filename is empty")
+	}
+	if _, err := item.Decode(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if item.Metadata == nil {
+		problems = append(problems, "metadata is missing")
+	}
+	for _, key := range RequiredSegmentMetadata {
+		if _, ok := item.Metadata[key]; !ok {
+			problems = append(problems, fmt.Sprintf("metadata.%s is missing", key))
+		}
+	}
+	for _, key := range append(RequiredSegmentMetadata, OptionalSegmentMetadata...) {
+		if v, ok := item.Metadata[key]; ok && !isLabels(v) {
+			problems = append(problems, fmt.Sprintf("metadata.%s is not a label or a list of labels", key))
+		}
+	}
+	if v, ok := item.Metadata["confidence"]; ok {
+		if c, isNumber := v.(float64); !isNumber || c < 0 || c > 1 {
+			problems = append(problems, "metadata.confidence is not a number between 0 and 1")
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Decode returns the item's image bytes, checking that they are an image.
+func (item SegmentedItem) Decode() ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(item.Image)
+	if err != nil {
+		return nil, fmt.Errorf("image is not valid base64: %v", err)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("image is not a supported image: %v", err)
+	}
+	return data, nil
+}
+
+// isLabels reports whether a decoded JSON value is a string or a list of
+// strings.
+func isLabels(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 type SegmentedImage struct {
 	Image    []byte                 `json:"image"`
 	ID       string                 `json:"id"`
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
-func Segment_clothes(inputImage []byte) ([]SegmentedImage, error) {
+// Segmenter is a client of the segmentation service.
+type Segmenter struct {
+	// BaseURL is the service's address, e.g. http://127.0.0.1:8000.
+	BaseURL string
+	http    *http.Client
+}
 
-	url := "http://127.0.0.1:8000/segment/"
+// NewSegmenter creates a client for the segmenter at baseURL.
+func NewSegmenter(baseURL string) *Segmenter {
+	return &Segmenter{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Segment cuts the garments out of an image. A response that breaks the
+// contract is an error.
+func (s *Segmenter) Segment(ctx context.Context, inputImage []byte) ([]SegmentedImage, error) {
 	var requestBody bytes.Buffer
 	writer := multipart.NewWriter(&requestBody)
 	// Attach the image file
-	part, err := writer.CreateFormFile("file", "input.jpg")
+	part, err := writer.CreateFormFile(SegmentFileField, "input.jpg")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %v", err)
 	}
@@ -34,14 +165,13 @@ func Segment_clothes(inputImage []byte) ([]SegmentedImage, error) {
 	}
 	writer.Close()
 	// Create HTTP request
-	req, err := http.NewRequest("POST", url, &requestBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", s.BaseURL+SegmentPath, &requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.http.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %v", err)
 	}
@@ -55,25 +185,17 @@ func Segment_clothes(inputImage []byte) ([]SegmentedImage, error) {
 		return nil, fmt.Errorf("bad response from server: %s", string(respBody))
 	}
 	// Parse JSON response
-	var parsedResponse struct {
-		SegmentedImages []struct {
-			Filename string                 `json:"filename"`
-			Image    string                 `json:"image"`
-			Metadata map[string]interface{} `json:"metadata"`
-		} `json:"segmented_images"`
-	}
+	var parsedResponse SegmentResponse
 	err = json.Unmarshal(respBody, &parsedResponse)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %v", err)
 	}
-	// Decode images from base64 to bytes
+	if err := parsedResponse.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid segmenter response: %v", err)
+	}
 	results := make([]SegmentedImage, 0, len(parsedResponse.SegmentedImages))
 	for _, item := range parsedResponse.SegmentedImages {
-		imgBytes, err := base64.StdEncoding.DecodeString(item.Image)
-		if err != nil {
-			fmt.Printf("base64 decode error: %v", err)
-			return nil, fmt.Errorf("base64 decode error: %v", err)
-		}
+		imgBytes, _ := item.Decode()
 		results = append(results, SegmentedImage{
 			Image:    imgBytes,
 			ID:       uuid.NewString(),
@@ -83,3 +205,46 @@ func Segment_clothes(inputImage []byte) ([]SegmentedImage, error) {
 
 	return results, nil
 }
+
+// PrimaryItem picks the garment a photo of one garment shows out of the
+// items cut from it: the one with the highest metadata confidence, and
+// among equally confident ones, or without confidences, the one covering
+// the most opaque pixels. It returns false when there are no items.
+func PrimaryItem(items []SegmentedImage) (SegmentedImage, bool) {
+	if len(items) == 0 {
+		return SegmentedImage{}, false
+	}
+	best, bestConfidence, bestArea := 0, confidenceOf(items[0]), opaqueArea(items[0].Image)
+	for i := 1; i < len(items); i++ {
+		confidence, area := confidenceOf(items[i]), opaqueArea(items[i].Image)
+		if confidence > bestConfidence || (confidence == bestConfidence && area > bestArea) {
+			best, bestConfidence, bestArea = i, confidence, area
+		}
+	}
+	return items[best], true
+}
+
+// confidenceOf returns an item's metadata confidence, or 0 without one.
+func confidenceOf(item SegmentedImage) float64 {
+	c, _ := item.Metadata["confidence"].(float64)
+	return c
+}
+
+// opaqueArea counts the opaque pixels of an encoded image, or returns 0
+// if it does not decode.
+func opaqueArea(data []byte) int {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0
+	}
+	b := img.Bounds()
+	area := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a >= alphaThreshold {
+				area++
+			}
+		}
+	}
+	return area
+}
@@ -0,0 +1,301 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/segmentertest"
+)
+
+// testPhoto returns a PNG whose top half is red and bottom half blue.
+func testPhoto(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 220, A: 255}
+			if y >= 30 {
+				c = color.RGBA{B: 220, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// postSegment sends a raw contract request to a segmenter.
+func postSegment(t *testing.T, baseURL, field string, data []byte) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile(field, "input.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+	resp, err := http.Post(baseURL+internal.SegmentPath, w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestFakeSegmenterHonoursContract(t *testing.T) {
+	fake := segmentertest.NewServer()
+	defer fake.Close()
+
+	resp := postSegment(t, fake.URL, internal.SegmentFileField, testPhoto(t))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var parsed internal.SegmentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.Validate(); err != nil {
+		t.Fatalf("response breaks the contract: %v", err)
+	}
+	if len(parsed.SegmentedImages) != len(segmentertest.DefaultMetadata) {
+		t.Fatalf("got %d segments, want %d", len(parsed.SegmentedImages), len(segmentertest.DefaultMetadata))
+	}
+
+	// Bands are cut top to bottom, so the top is red and the bottom blue
+	wantColors := []color.RGBA{{R: 220, A: 255}, {B: 220, A: 255}}
+	for i, item := range parsed.SegmentedImages {
+		data, err := item.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(40, 30) {
+			t.Errorf("segment %d size = %v, want 40x30", i, got)
+		}
+		if got := color.RGBAModel.Convert(img.At(5, 5)); got != wantColors[i] {
+			t.Errorf("segment %d color = %v, want %v", i, got, wantColors[i])
+		}
+	}
+}
+
+func TestFakeSegmenterRejectsBadRequests(t *testing.T) {
+	fake := segmentertest.NewServer()
+	defer fake.Close()
+
+	for _, tc := range []struct {
+		name   string
+		field  string
+		data   []byte
+		status int
+	}{
+		{"wrong field", "image", testPhoto(t), http.StatusUnprocessableEntity},
+		{"not an image", internal.SegmentFileField, []byte("not an image"), http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := postSegment(t, fake.URL, tc.field, tc.data)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.status)
+			}
+			var detail struct {
+				Detail string `json:"detail"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil || detail.Detail == "" {
+				t.Errorf("error body has no detail: %v", err)
+			}
+		})
+	}
+}
+
+func TestSegmentResponseValidate(t *testing.T) {
+	valid := func() internal.SegmentedItem {
+		resp, err := segmentertest.Segment(image.NewRGBA(image.Rect(0, 0, 4, 4)), segmentertest.DefaultMetadata[:1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.SegmentedImages[0]
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*internal.SegmentedItem)
+		want   string
+	}{
+		{"valid", func(*internal.SegmentedItem) {}, ""},
+		{"single labels", func(item *internal.SegmentedItem) {
+			item.Metadata = map[string]interface{}{"categories": "tops", "types": "shirts", "confidence": 0.8}
+		}, ""},
+		{"empty filename", func(item *internal.SegmentedItem) { item.Filename = "" }, "filename is empty"},
+		{"bad base64", func(item *internal.SegmentedItem) { item.Image = "not base64!" }, "not valid base64"},
+		{"url-safe base64", func(item *internal.SegmentedItem) {
+			data, _ := base64.StdEncoding.DecodeString(item.Image)
+			item.Image = base64.RawURLEncoding.EncodeToString(data)
+		}, "not valid base64"},
+		{"not an image", func(item *internal.SegmentedItem) {
+			item.Image = base64.StdEncoding.EncodeToString([]byte("plain text"))
+		}, "not a supported image"},
+		{"no metadata", func(item *internal.SegmentedItem) { item.Metadata = nil }, "metadata is missing"},
+		{"missing categories", func(item *internal.SegmentedItem) {
+			item.Metadata = map[string]interface{}{"types": []interface{}{"jeans"}}
+		}, "metadata.categories is missing"},
+		{"missing types", func(item *internal.SegmentedItem) {
+			item.Metadata = map[string]interface{}{"categories": []interface{}{"bottoms"}}
+		}, "metadata.types is missing"},
+		{"non-string label", func(item *internal.SegmentedItem) {
+			item.Metadata["seasons"] = []interface{}{"summer", 3.0}
+		}, "metadata.seasons is not a label"},
+		{"confidence out of range", func(item *internal.SegmentedItem) {
+			item.Metadata["confidence"] = 1.5
+		}, "metadata.confidence"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			item := valid()
+			meta := make(map[string]interface{}, len(item.Metadata))
+			for k, v := range item.Metadata {
+				meta[k] = v
+			}
+			item.Metadata = meta
+			tc.modify(&item)
+
+			// Round-trip through JSON as the client sees it
+			encoded, err := json.Marshal(internal.SegmentResponse{SegmentedImages: []internal.SegmentedItem{item}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parsed internal.SegmentResponse
+			if err := json.Unmarshal(encoded, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			err = parsed.Validate()
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+				t.Errorf("error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+
+	var missing internal.SegmentResponse
+	if err := json.Unmarshal([]byte(`{"images": []}`), &missing); err != nil {
+		t.Fatal(err)
+	}
+	if err := missing.Validate(); err == nil {
+		t.Error("response without segmented_images validated")
+	}
+}
+
+func TestSegmenterClient(t *testing.T) {
+	fake := segmentertest.NewServer()
+	defer fake.Close()
+	segmenter := internal.NewSegmenter(fake.URL)
+
+	segments, err := segmenter.Segment(context.Background(), testPhoto(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	for i, want := range []string{"t-shirt", "jeans"} {
+		if _, _, err := image.Decode(bytes.NewReader(segments[i].Image)); err != nil {
+			t.Errorf("segment %d is not an image: %v", i, err)
+		}
+		attrs := internal.NormalizeAttributes(segments[i].Metadata, nil)
+		if attrs.Subcategory != want || len(attrs.Unknown) != 0 {
+			t.Errorf("segment %d attributes = %+v, want subcategory %q and no unknown labels", i, attrs, want)
+		}
+	}
+
+	// Server errors and contract violations are errors
+	if _, err := segmenter.Segment(context.Background(), []byte("not an image")); err == nil {
+		t.Error("segmenting a non-image succeeded")
+	}
+	fake.SetMetadata(map[string]interface{}{"categories": []interface{}{"tops"}})
+	if _, err := segmenter.Segment(context.Background(), testPhoto(t)); err == nil || !strings.Contains(err.Error(), "metadata.types is missing") {
+		t.Errorf("error = %v, want a contract violation", err)
+	}
+	if n := fake.Requests(); n != 2 {
+		t.Errorf("fake segmented %d images, want 2", n)
+	}
+}
+
+func TestSegmenterClientCancelled(t *testing.T) {
+	fake := segmentertest.NewServer()
+	defer fake.Close()
+	segmenter := internal.NewSegmenter(fake.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := segmenter.Segment(ctx, testPhoto(t)); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("error = %v, want the request cancelled", err)
+	}
+	if n := fake.Requests(); n != 0 {
+		t.Errorf("fake segmented %d images after cancellation", n)
+	}
+}
+
+// cutout returns a 10x10 PNG whose first opaque pixels are opaque and the
+// rest transparent.
+func cutout(t *testing.T, opaque int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for i := 0; i < opaque; i++ {
+		img.Set(i%10, i/10, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPrimaryItem(t *testing.T) {
+	item := func(id string, opaque int, confidence interface{}) internal.SegmentedImage {
+		metadata := map[string]interface{}{}
+		if confidence != nil {
+			metadata["confidence"] = confidence
+		}
+		return internal.SegmentedImage{ID: id, Image: cutout(t, opaque), Metadata: metadata}
+	}
+	for _, tc := range []struct {
+		name  string
+		items []internal.SegmentedImage
+		want  string
+	}{
+		{"one item", []internal.SegmentedImage{item("a", 10, nil)}, "a"},
+		{"largest without confidences", []internal.SegmentedImage{item("small", 10, nil), item("large", 60, nil), item("mid", 30, nil)}, "large"},
+		{"most confident", []internal.SegmentedImage{item("large", 90, 0.4), item("sure", 20, 0.9)}, "sure"},
+		{"largest of equal confidence", []internal.SegmentedImage{item("small", 20, 0.8), item("large", 40, 0.8)}, "large"},
+		{"confidence beats none", []internal.SegmentedImage{item("large", 90, nil), item("scored", 10, 0.2)}, "scored"},
+		{"first of a tie", []internal.SegmentedImage{item("first", 50, nil), item("second", 50, nil)}, "first"},
+		{"undecodable counts as empty", []internal.SegmentedImage{{ID: "broken", Image: []byte("x")}, item("ok", 1, nil)}, "ok"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := internal.PrimaryItem(tc.items)
+			if !ok || got.ID != tc.want {
+				t.Errorf("PrimaryItem = %q, %v, want %q", got.ID, ok, tc.want)
+			}
+		})
+	}
+
+	if _, ok := internal.PrimaryItem(nil); ok {
+		t.Error("PrimaryItem of no items succeeded")
+	}
+}
@@ -0,0 +1,92 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestSaveLoad(t *testing.T) {
+	// Parent directories are created as needed
+	path := filepath.Join(t.TempDir(), "nested", "dir", "records.json")
+	want := []record{{"a", 1}, {"b", 2}}
+	if err := Save(path, want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+
+	var got []record
+	if err := Load(path, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+
+	// Saving again replaces the file
+	if err := Save(path, []record{{"c", 3}}); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := Load(path, &got); err != nil || len(got) != 1 || got[0].Name != "c" {
+		t.Errorf("loaded %v, %v after replacing", got, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		content *string
+		wantErr string
+	}{
+		{"missing file", nil, ""},
+		{"empty list", ptr("[]"), ""},
+		{"invalid JSON", ptr("[{"), "failed to parse"},
+		{"wrong type", ptr(`{"name": 1}`), "failed to parse"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_")+".json")
+			if tc.content != nil {
+				if err := os.WriteFile(path, []byte(*tc.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			untouched := []record{{"kept", 1}}
+			err := Load(path, &untouched)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.content == nil && (len(untouched) != 1 || untouched[0].Name != "kept") {
+				t.Errorf("missing file changed the value to %v", untouched)
+			}
+		})
+	}
+
+	// A directory cannot be read as a file
+	if err := Load(dir, &[]record{}); err == nil || !strings.Contains(err.Error(), "failed to read") {
+		t.Errorf("error = %v, want a read error", err)
+	}
+}
+
+func TestSaveErrors(t *testing.T) {
+	if err := Save(filepath.Join(t.TempDir(), "f.json"), map[string]interface{}{"c": make(chan int)}); err == nil {
+		t.Error("saved a value JSON cannot encode")
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+
+	// Person photos may be uploaded as WebP
+	_ "golang.org/x/image/webp"
+)
+
+// maskOverlayColor tints the masked area of a mask overlay.
+var maskOverlayColor = color.RGBA{0xff, 0x30, 0x30, 0xff}
+
+// maskOverlayOpacity is the tint strength where the mask is fully on.
+const maskOverlayOpacity = 0.55
+
+// MaskOverlay tints the masked area of a person image and returns it as a
+// PNG. The mask is a greyscale image, white where the garment will be
+// replaced, and is stretched to the person image if the sizes differ.
+func MaskOverlay(personBytes, maskBytes []byte) ([]byte, error) {
+	person, _, err := image.Decode(bytes.NewReader(personBytes))
+	if err != nil {
+		return nil, err
+	}
+	mask, _, err := image.Decode(bytes.NewReader(maskBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	pb, mb := person.Bounds(), mask.Bounds()
+	overlay := image.NewRGBA(image.Rect(0, 0, pb.Dx(), pb.Dy()))
+	for y := 0; y < pb.Dy(); y++ {
+		my := mb.Min.Y + y*mb.Dy()/pb.Dy()
+		for x := 0; x < pb.Dx(); x++ {
+			mx := mb.Min.X + x*mb.Dx()/pb.Dx()
+			m := float64(color.GrayModel.Convert(mask.At(mx, my)).(color.Gray).Y) / 0xff
+			alpha := m * maskOverlayOpacity
+
+			r, g, b, _ := person.At(pb.Min.X+x, pb.Min.Y+y).RGBA()
+			overlay.SetRGBA(x, y, color.RGBA{
+				R: blendChannel(r>>8, maskOverlayColor.R, alpha),
+				G: blendChannel(g>>8, maskOverlayColor.G, alpha),
+				B: blendChannel(b>>8, maskOverlayColor.B, alpha),
+				A: 0xff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, overlay); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func blendChannel(base uint32, tint uint8, alpha float64) uint8 {
+	return uint8(float64(base)*(1-alpha) + float64(tint)*alpha)
+}
@@ -0,0 +1,162 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// solidWebP encodes a lossless WebP of one colour. Every prefix code has a
+// single symbol, so the pixels themselves take no bits.
+func solidWebP(w, h int, c color.NRGBA) []byte {
+	var data []byte
+	var acc uint64
+	var n uint
+	put := func(v uint64, bits uint) {
+		acc |= v << n
+		for n += bits; n >= 8; n -= 8 {
+			data = append(data, byte(acc))
+			acc >>= 8
+		}
+	}
+	put(0x2f, 8) // signature
+	put(uint64(w-1), 14)
+	put(uint64(h-1), 14)
+	put(1, 1) // alpha is used
+	put(0, 3) // version
+	put(0, 1) // no transform
+	put(0, 1) // no colour cache
+	put(0, 1) // no meta prefix codes
+	// green, red, blue, alpha and distance codes
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		put(1, 1) // simple code
+		put(0, 1) // one symbol
+		put(1, 1) // 8-bit symbol
+		put(uint64(symbol), 8)
+	}
+	if n > 0 {
+		data = append(data, byte(acc))
+	}
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(12+len(data)))
+	buf.WriteString("WEBPVP8L")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestMaskOverlay(t *testing.T) {
+	grey := color.NRGBA{R: 200, G: 200, B: 200, A: 255}
+	solid := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			solid.Set(x, y, grey)
+		}
+	}
+	encode := func(enc func(*bytes.Buffer) error) []byte {
+		var buf bytes.Buffer
+		if err := enc(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	// The mask is half the size of the person and white on its left half
+	maskImg := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 2; x++ {
+			maskImg.SetGray(x, y, color.Gray{Y: 0xff})
+		}
+	}
+	mask := encode(func(b *bytes.Buffer) error { return png.Encode(b, maskImg) })
+
+	for _, tc := range []struct {
+		name   string
+		person []byte
+	}{
+		{"png", encode(func(b *bytes.Buffer) error { return png.Encode(b, solid) })},
+		{"jpeg", encode(func(b *bytes.Buffer) error { return jpeg.Encode(b, solid, &jpeg.Options{Quality: 100}) })},
+		{"webp", solidWebP(8, 8, grey)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := internal.MaskOverlay(tc.person, mask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			overlay, err := png.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("overlay is not a PNG: %v", err)
+			}
+			if b := overlay.Bounds(); b.Dx() != 8 || b.Dy() != 8 {
+				t.Fatalf("overlay is %v, want the person's 8x8", b)
+			}
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					r, g, _, _ := overlay.At(x, y).RGBA()
+					tinted := r>>8 > g>>8+40
+					if tinted != (x < 4) {
+						t.Fatalf("pixel %d,%d = %v, want tinted: %v", x, y, overlay.At(x, y), x < 4)
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := internal.MaskOverlay([]byte("GIF89a"), mask); err == nil {
+		t.Error("unsupported person image decoded")
+	}
+}
+
+func TestMaskOverlayBlend(t *testing.T) {
+	white := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i := range white.Pix {
+		white.Pix[i] = 0xff
+	}
+	var person bytes.Buffer
+	if err := png.Encode(&person, white); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		mask uint8
+		want color.RGBA
+	}{
+		{"off", 0, color.RGBA{255, 255, 255, 255}},
+		{"half", 128, color.RGBA{255, 197, 197, 255}},
+		{"on", 255, color.RGBA{255, 141, 141, 255}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mask bytes.Buffer
+			if err := png.Encode(&mask, &image.Gray{Pix: []uint8{tc.mask}, Stride: 1, Rect: image.Rect(0, 0, 1, 1)}); err != nil {
+				t.Fatal(err)
+			}
+			out, err := internal.MaskOverlay(person.Bytes(), mask.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			overlay, err := png.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := color.RGBAModel.Convert(overlay.At(1, 1)); got != tc.want {
+				t.Errorf("pixel = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := internal.MaskOverlay(person.Bytes(), []byte("not a mask")); err == nil {
+		t.Error("invalid mask decoded")
+	}
+}
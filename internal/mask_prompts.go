@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// MaskPrompts maps clothing subcategories and categories to the
+// GroundingDINO prompt that masks that kind of garment on a person.
+type MaskPrompts map[string]string
+
+// DefaultMaskPrompts is used for any entry not overridden by the mapping
+// file. Subcategories are listed where their category's prompt would mask
+// the wrong area, e.g. shorts and skirts among bottoms.
+var DefaultMaskPrompts = MaskPrompts{
+	CategoryTop:       "shirt",
+	CategoryBottom:    "pants",
+	CategoryOuterwear: "jacket",
+	CategoryShoes:     "shoes",
+	CategoryAccessory: "accessory",
+
+	"tank top":     "tank top",
+	"crop top":     "crop top",
+	"sweater vest": "vest",
+	"hoodie":       "hoodie",
+	"sweater":      "sweater",
+	"cardigan":     "cardigan",
+	"shorts":       "shorts",
+	"skirt":        "skirt",
+	"leggings":     "leggings",
+	"culottes":     "pants",
+	"capris":       "pants",
+}
+
+// LoadMaskPrompts returns the default mapping overlaid with the entries of
+// the JSON object at path, e.g. {"skirt": "long skirt"}. Keys are not case
+// sensitive. An empty path yields the defaults.
+func LoadMaskPrompts(path string) (MaskPrompts, error) {
+	prompts := maps.Clone(DefaultMaskPrompts)
+	if path == "" {
+		return prompts, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mask prompts: %w", err)
+	}
+	var overrides map[string]string
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse mask prompts: %w", err)
+	}
+	// Keys are matched case-insensitively, so two spellings of one key
+	// would override it in random order
+	spellings := make(map[string]string, len(overrides))
+	for key, prompt := range overrides {
+		prompt = strings.TrimSpace(prompt)
+		if prompt == "" {
+			return nil, fmt.Errorf("mask prompt for %q is empty", key)
+		}
+		normalized := strings.ToLower(strings.TrimSpace(key))
+		if other, ok := spellings[normalized]; ok {
+			return nil, fmt.Errorf("mask prompts %q and %q name the same garment", other, key)
+		}
+		spellings[normalized] = key
+		prompts[normalized] = prompt
+	}
+	return prompts, nil
+}
+
+// For returns the mask prompt for a garment: by subcategory, then by
+// category, then DefaultMaskPrompt.
+func (m MaskPrompts) For(attrs ClothingAttributes) string {
+	if prompt, ok := m[attrs.Subcategory]; ok && attrs.Subcategory != "" {
+		return prompt
+	}
+	if prompt, ok := m[attrs.Category]; ok && attrs.Category != "" {
+		return prompt
+	}
+	return DefaultMaskPrompt
+}
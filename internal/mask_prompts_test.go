@@ -0,0 +1,83 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+func TestMaskPromptsFor(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		attrs internal.ClothingAttributes
+		want  string
+	}{
+		{"subcategory", internal.ClothingAttributes{Category: internal.CategoryBottom, Subcategory: "skirt"}, "skirt"},
+		{"category", internal.ClothingAttributes{Category: internal.CategoryBottom, Subcategory: "jeans"}, "pants"},
+		{"category only", internal.ClothingAttributes{Category: internal.CategoryOuterwear}, "jacket"},
+		{"unknown", internal.ClothingAttributes{Category: "costume", Subcategory: "cape"}, internal.DefaultMaskPrompt},
+		{"empty", internal.ClothingAttributes{}, internal.DefaultMaskPrompt},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := internal.DefaultMaskPrompts.For(tc.attrs); got != tc.want {
+				t.Errorf("For(%+v) = %q, want %q", tc.attrs, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadMaskPrompts(t *testing.T) {
+	skirt := internal.ClothingAttributes{Category: internal.CategoryBottom, Subcategory: "skirt"}
+	for _, tc := range []struct {
+		name    string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{"no file", "", "skirt", ""},
+		{"override", `{"skirt": "long skirt"}`, "long skirt", ""},
+		{"mixed case key", `{"Skirt": "long skirt"}`, "long skirt", ""},
+		{"padded key and prompt", `{" SKIRT ": "  long skirt "}`, "long skirt", ""},
+		{"other entries kept", `{"shorts": "short pants"}`, "skirt", ""},
+		{"case duplicates", `{"skirt": "a", "Skirt": "b"}`, "", "name the same garment"},
+		{"empty prompt", `{"skirt": " "}`, "", "is empty"},
+		{"not an object", `["skirt"]`, "", "failed to parse"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := ""
+			if tc.file != "" {
+				path = filepath.Join(t.TempDir(), "mask_prompts.json")
+				if err := os.WriteFile(path, []byte(tc.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			prompts, err := internal.LoadMaskPrompts(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := prompts.For(skirt); got != tc.want {
+				t.Errorf("skirt prompt = %q, want %q", got, tc.want)
+			}
+			if got := prompts.For(internal.ClothingAttributes{Category: internal.CategoryTop}); got != "shirt" {
+				t.Errorf("top prompt = %q, want the default", got)
+			}
+		})
+	}
+
+	if _, err := internal.LoadMaskPrompts(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file loaded")
+	}
+	// Overrides do not leak into the defaults
+	if internal.DefaultMaskPrompts["skirt"] != "skirt" {
+		t.Errorf("defaults changed: %q", internal.DefaultMaskPrompts["skirt"])
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+// AdminMiddleware only lets through users whose ID is in adminUserIDs. It
+// must run after AuthMiddleware, which injects the user ID.
+func AdminMiddleware(adminUserIDs []string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		userId, _ := c.Get("userId")
+		id, ok := userId.(string)
+		if !ok || id == "" || !slices.Contains(adminUserIDs, id) {
+			response.Abort(c, apperr.New(apperr.Forbidden, "admin access required"))
+			return
+		}
+		c.Next(ctx)
+	}
+}
@@ -4,19 +4,20 @@ import (
 	"context"
 	"errors"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
 )
 
 func AuthMiddleware(jwtSecret []byte) app.HandlerFunc {
 	return func(ctx context.Context, c *app.RequestContext) {
 		authHeader := string(c.GetHeader("Authorization"))
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "Authorization token required"})
+			response.Abort(c, apperr.New(apperr.Unauthorized, "authorization token required"))
 			return
 		}
 
@@ -24,7 +25,7 @@ func AuthMiddleware(jwtSecret []byte) app.HandlerFunc {
 		userId, err := validateTokenAndExtractUserID(authHeader, jwtSecret)
 		if err != nil {
 			log.Printf("Auth error: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			response.Abort(c, apperr.Wrap(apperr.Unauthorized, err))
 			return
 		}
 
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+// RequestIDMiddleware assigns every request an ID, taken from the
+// X-Request-ID header when the client sends one. It is echoed in the
+// response header and envelope.
+func RequestIDMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		response.RequestID(c)
+		c.Next(ctx)
+	}
+}
@@ -0,0 +1,466 @@
+// Package openapi builds an OpenAPI 3 document from a table of routes.
+// Request and response bodies are described by example Go values, whose
+// schemas are derived from their types and json tags, so the document
+// follows the types the handlers actually send.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/response"
+)
+
+// Version is the OpenAPI version documents are written in.
+const Version = "3.0.3"
+
+// Auth is what a route requires of the caller.
+type Auth int
+
+const (
+	// Public routes need no credentials.
+	Public Auth = iota
+	// User routes need a bearer token.
+	User
+	// Admin routes need the bearer token of an admin user.
+	Admin
+)
+
+// Param is a query parameter, multipart form field or response header.
+type Param struct {
+	Name string
+	// Type is string, integer, number, boolean or file.
+	Type        string
+	Description string
+	Required    bool
+	// Multiple allows the parameter to be repeated.
+	Multiple bool
+}
+
+// Route describes one route of the API.
+type Route struct {
+	// Method and Path are as registered with the router; path parameters
+	// are written :name.
+	Method  string
+	Path    string
+	Summary string
+	Tag     string
+	Auth    Auth
+	Query   []Param
+	// Form lists the fields of a multipart/form-data body.
+	Form []Param
+	// JSON is an example of a JSON body.
+	JSON interface{}
+	// Status is the success status, 200 by default.
+	Status int
+	// Data is an example of the data of the response envelope.
+	Data interface{}
+	// Raw is the content type of a response sent as is rather than in
+	// the envelope, such as a rendered image.
+	Raw     string
+	Headers []Param
+}
+
+// Object describes a JSON object by example values of its fields, like
+// the maps handlers respond with. Every field is required unless wrapped
+// in Optional.
+type Object map[string]interface{}
+
+type optional struct {
+	value interface{}
+}
+
+// Optional marks a field of an Object that may be left out.
+func Optional(value interface{}) interface{} {
+	return optional{value}
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower-case method.
+type PathItem map[string]*Operation
+
+// Operation is one method of a path.
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header.
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas and security schemes referenced by the
+// operations.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how callers authenticate.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is a JSON schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// bearerAuth names the security scheme of User and Admin routes.
+const bearerAuth = "bearerAuth"
+
+// pathParamPattern matches the :name path parameters of the router.
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Path converts a router path to an OpenAPI path, :name to {name}.
+func Path(routerPath string) string {
+	return pathParamPattern.ReplaceAllString(routerPath, "{$1}")
+}
+
+// Build describes the routes in a document. It fails if a route is listed
+// twice.
+func Build(info Info, routes []Route) (*Document, error) {
+	g := &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+	g.typeSchema(reflect.TypeOf(response.ErrorBody{}))
+	g.schemas["Error"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error":      {Ref: "#/components/schemas/ErrorBody"},
+			"request_id": {Type: "string"},
+		},
+		Required: []string{"error", "request_id"},
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	for _, route := range routes {
+		path := Path(route.Path)
+		method := strings.ToLower(route.Method)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		if item[method] != nil {
+			return nil, fmt.Errorf("route %s %s is listed twice", route.Method, route.Path)
+		}
+		item[method] = g.operation(route)
+	}
+	return doc, nil
+}
+
+func (g *generator) operation(route Route) *Operation {
+	op := &Operation{
+		Summary:   route.Summary,
+		Responses: make(map[string]Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, param := range route.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        param.Name,
+			In:          "query",
+			Description: param.Description,
+			Required:    param.Required,
+			Schema:      paramSchema(param),
+		})
+	}
+
+	switch {
+	case len(route.Form) > 0:
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, field := range route.Form {
+			schema := paramSchema(field)
+			schema.Description = field.Description
+			form.Properties[field.Name] = schema
+			if field.Required {
+				form.Required = append(form.Required, field.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"multipart/form-data": {Schema: form}},
+		}
+	case route.JSON != nil:
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(route.JSON)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	switch {
+	case route.Raw != "":
+		schema := &Schema{Type: "string", Format: "binary"}
+		if route.Raw == "application/json" {
+			schema = &Schema{Type: "object"}
+		}
+		success.Content = map[string]MediaType{route.Raw: {Schema: schema}}
+	case route.Data != nil:
+		success.Content = map[string]MediaType{"application/json": {Schema: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":       g.schemaOf(route.Data),
+				"request_id": {Type: "string"},
+			},
+			Required: []string{"data", "request_id"},
+		}}}
+	}
+	if len(route.Headers) > 0 {
+		success.Headers = make(map[string]Header)
+		for _, header := range route.Headers {
+			success.Headers[header.Name] = Header{Description: header.Description, Schema: paramSchema(header)}
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if route.Auth != Public {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+		op.Responses[strconv.Itoa(http.StatusUnauthorized)] = errorResponse("Missing or invalid bearer token")
+	}
+	if route.Auth == Admin {
+		op.Description = "Only users listed in ADMIN_USER_IDS may call this route."
+		op.Responses[strconv.Itoa(http.StatusForbidden)] = errorResponse("The user is not an admin")
+	}
+	op.Responses["default"] = errorResponse("Error")
+	return op
+}
+
+func errorResponse(description string) Response {
+	return Response{
+		Description: description,
+		Content: map[string]MediaType{"application/json": {
+			Schema: &Schema{Ref: "#/components/schemas/Error"},
+		}},
+	}
+}
+
+func paramSchema(param Param) *Schema {
+	schema := &Schema{Type: param.Type}
+	if param.Type == "file" {
+		schema = &Schema{Type: "string", Format: "binary"}
+	}
+	if param.Multiple {
+		schema = &Schema{Type: "array", Items: schema}
+	}
+	return schema
+}
+
+// generator derives schemas from Go types. Named struct types become
+// components, referenced wherever they are used.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *generator) schemaOf(value interface{}) *Schema {
+	switch v := value.(type) {
+	case Object:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema, len(v))}
+		for name, field := range v {
+			if opt, ok := field.(optional); ok {
+				field = opt.value
+			} else {
+				schema.Required = append(schema.Required, name)
+			}
+			schema.Properties[name] = g.schemaOf(field)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	case []Object:
+		return &Schema{Type: "array", Items: g.schemaOf(v[0])}
+	case nil:
+		return &Schema{}
+	}
+	return g.typeSchema(reflect.TypeOf(value))
+}
+
+func (g *generator) typeSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.typeSchema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	// interface{} and anything else may hold any value
+	return &Schema{}
+}
+
+// component registers the schema of a named struct type and returns its
+// component name: the type name, qualified by its package when another
+// type has the same name.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	// Reserve the name first so recursive types end in a reference
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	return schema
+}
+
+func (g *generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var fieldSchema *Schema
+		if strings.Contains(","+opts+",", ",string,") {
+			fieldSchema = &Schema{Type: "string"}
+		} else {
+			fieldSchema = g.typeSchema(field.Type)
+		}
+		schema.Properties[name] = fieldSchema
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPath(t *testing.T) {
+	for _, tc := range []struct {
+		path, want string
+	}{
+		{"/api/wardrobe", "/api/wardrobe"},
+		{"/api/wardrobe/:clothId", "/api/wardrobe/{clothId}"},
+		{"/api/tryon-batches/:batchId/contact-sheet", "/api/tryon-batches/{batchId}/contact-sheet"},
+		{"/api/:a/:b_2", "/api/{a}/{b_2}"},
+	} {
+		if got := Path(tc.path); got != tc.want {
+			t.Errorf("Path(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+// ErrorBody has the same name as response.ErrorBody, which every document
+// registers.
+type ErrorBody struct {
+	Reason string `json:"reason"`
+}
+
+type tree struct {
+	Name     string  `json:"name"`
+	Children []*tree `json:"children,omitempty"`
+}
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type item struct {
+	base
+	Hash      uint64            `json:"hash,string"`
+	Tags      []string          `json:"tags"`
+	Data      []byte            `json:"data"`
+	Meta      map[string]int    `json:"meta,omitempty"`
+	Parent    *tree             `json:"parent"`
+	Score     *float64          `json:"score"`
+	CreatedAt time.Time         `json:"created_at"`
+	Extra     interface{}       `json:"extra"`
+	Labels    map[string]string `json:"-"`
+	NoTag     bool
+	hidden    string
+}
+
+func TestSchemas(t *testing.T) {
+	doc, err := Build(Info{Title: "test", Version: "1"}, []Route{
+		{Method: http.MethodGet, Path: "/items", Data: Object{"items": []item{}, "next": Optional("")}},
+		{Method: http.MethodGet, Path: "/errors", Data: ErrorBody{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc.Components.Schemas
+
+	for _, tc := range []struct {
+		name   string
+		schema *Schema
+		want   *Schema
+	}{
+		{"embedded field", schemas["item"].Properties["id"], &Schema{Type: "string"}},
+		{"string option", schemas["item"].Properties["hash"], &Schema{Type: "string"}},
+		{"slice", schemas["item"].Properties["tags"], &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		{"bytes", schemas["item"].Properties["data"], &Schema{Type: "string", Format: "byte"}},
+		{"map", schemas["item"].Properties["meta"], &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}},
+		{"pointer to struct", schemas["item"].Properties["parent"], &Schema{Ref: "#/components/schemas/tree"}},
+		{"pointer", schemas["item"].Properties["score"], &Schema{Type: "number", Nullable: true}},
+		{"time", schemas["item"].Properties["created_at"], &Schema{Type: "string", Format: "date-time"}},
+		{"interface", schemas["item"].Properties["extra"], &Schema{}},
+		{"untagged field", schemas["item"].Properties["NoTag"], &Schema{Type: "boolean"}},
+		{"recursive type", schemas["tree"].Properties["children"], &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/tree"}}},
+		{"name taken", schemas["OpenapiErrorBody"], &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"reason": {Type: "string"}},
+			Required:   []string{"reason"},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.schema, tc.want) {
+				t.Errorf("schema = %+v, want %+v", tc.schema, tc.want)
+			}
+		})
+	}
+
+	properties := schemas["item"].Properties
+	for _, name := range []string{"Labels", "hidden", "base"} {
+		if _, ok := properties[name]; ok {
+			t.Errorf("item has property %s", name)
+		}
+	}
+	if want := []string{"id", "hash", "tags", "data", "parent", "score", "created_at", "extra", "NoTag"}; !reflect.DeepEqual(schemas["item"].Required, want) {
+		t.Errorf("required = %v, want %v", schemas["item"].Required, want)
+	}
+
+	data := doc.Paths["/items"]["get"].Responses["200"].Content["application/json"].Schema.Properties["data"]
+	if !reflect.DeepEqual(data.Required, []string{"items"}) || data.Properties["items"].Items.Ref != "#/components/schemas/item" {
+		t.Errorf("object schema = %+v", data)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		route Route
+		check func(t *testing.T, op *Operation)
+	}{
+		{"public", Route{Method: http.MethodGet, Path: "/health"}, func(t *testing.T, op *Operation) {
+			if op.Security != nil || op.Responses["401"].Description != "" {
+				t.Error("public route requires a token")
+			}
+			if _, ok := op.Responses["200"]; !ok {
+				t.Error("no 200 response")
+			}
+		}},
+		{"user", Route{Method: http.MethodGet, Path: "/wardrobe", Auth: User}, func(t *testing.T, op *Operation) {
+			if len(op.Security) != 1 || op.Responses["401"].Description == "" || op.Responses["403"].Description != "" {
+				t.Errorf("security %v, responses %v", op.Security, op.Responses)
+			}
+		}},
+		{"admin", Route{Method: http.MethodDelete, Path: "/admin/cache", Auth: Admin}, func(t *testing.T, op *Operation) {
+			if op.Responses["401"].Description == "" || op.Responses["403"].Description == "" || op.Description == "" {
+				t.Errorf("responses %v, description %q", op.Responses, op.Description)
+			}
+		}},
+		{"parameters", Route{Method: http.MethodGet, Path: "/outfits/:outfitId", Query: []Param{
+			{Name: "limit", Type: "integer", Required: true},
+			{Name: "tag", Type: "string", Multiple: true},
+		}}, func(t *testing.T, op *Operation) {
+			want := []Parameter{
+				{Name: "outfitId", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+				{Name: "limit", In: "query", Required: true, Schema: &Schema{Type: "integer"}},
+				{Name: "tag", In: "query", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+			}
+			if !reflect.DeepEqual(op.Parameters, want) {
+				t.Errorf("parameters = %+v", op.Parameters)
+			}
+		}},
+		{"form", Route{Method: http.MethodPost, Path: "/upload", Status: http.StatusCreated, Form: []Param{
+			{Name: "image", Type: "file", Required: true},
+			{Name: "note", Type: "string"},
+		}}, func(t *testing.T, op *Operation) {
+			form := op.RequestBody.Content["multipart/form-data"].Schema
+			if form.Properties["image"].Format != "binary" || !reflect.DeepEqual(form.Required, []string{"image"}) {
+				t.Errorf("form = %+v", form)
+			}
+			if _, ok := op.Responses["201"]; !ok {
+				t.Errorf("responses = %v, want 201", op.Responses)
+			}
+		}},
+		{"raw", Route{Method: http.MethodGet, Path: "/sheet", Raw: "image/jpeg", Headers: []Param{{Name: "X-Cache", Type: "string"}}}, func(t *testing.T, op *Operation) {
+			resp := op.Responses["200"]
+			if resp.Content["image/jpeg"].Schema.Format != "binary" || resp.Headers["X-Cache"].Schema.Type != "string" {
+				t.Errorf("response = %+v", resp)
+			}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Build(Info{}, []Route{tc.route})
+			if err != nil {
+				t.Fatal(err)
+			}
+			op := doc.Paths[Path(tc.route.Path)][strings.ToLower(tc.route.Method)]
+			if op == nil {
+				t.Fatal("operation missing")
+			}
+			if op.Responses["default"].Content["application/json"].Schema.Ref != "#/components/schemas/Error" {
+				t.Error("no error response")
+			}
+			tc.check(t, op)
+		})
+	}
+}
+
+func TestBuildRejectsDuplicateRoutes(t *testing.T) {
+	_, err := Build(Info{}, []Route{
+		{Method: http.MethodGet, Path: "/outfits/:id"},
+		{Method: http.MethodPost, Path: "/outfits/:id"},
+		{Method: http.MethodGet, Path: "/outfits/:id"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "GET /outfits/:id is listed twice") {
+		t.Errorf("error = %v, want the duplicate reported", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package response writes the JSON envelope every endpoint answers with:
+//
+//	{"data": {...}, "request_id": "..."}
+//	{"error": {"code": "...", "message": "...", "details": {...}}, "request_id": "..."}
+//
+// Exactly one of data and error is set. Error codes come from the kind of
+// the error, see apperr. Internal and upstream errors are logged with the
+// request ID and answered with a fixed message.
+package response
+
+import (
+	"errors"
+	"log"
+	"regexp"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the request context.
+const requestIDKey = "requestId"
+
+// internalMessage replaces the message of internal errors, which may
+// mention file paths or upstream responses.
+const internalMessage = "internal server error"
+
+// fixedMessages replace the messages of the kinds whose errors may mention
+// file paths, backend URLs or upstream responses.
+var fixedMessages = map[apperr.Kind]string{
+	apperr.Internal:            internalMessage,
+	apperr.UpstreamUnavailable: "upstream service unavailable",
+	apperr.UpstreamFailed:      "upstream service failed",
+	apperr.Timeout:             "request timed out",
+}
+
+// requestIDPattern limits the request IDs accepted from clients.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Envelope is the body of every JSON response.
+type Envelope struct {
+	Data      interface{} `json:"data,omitempty"`
+	Error     *ErrorBody  `json:"error,omitempty"`
+	RequestID string      `json:"request_id"`
+}
+
+// ErrorBody describes a failed request.
+type ErrorBody struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Success answers with data.
+func Success(c *app.RequestContext, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data, RequestID: RequestID(c)})
+}
+
+// Error answers with the status and error code of err's kind. Errors
+// without a kind are internal errors.
+func Error(c *app.RequestContext, err error) {
+	c.JSON(apperr.KindOf(err).Status(), errorEnvelope(c, err))
+}
+
+// Abort answers like Error and stops the handler chain, for middleware.
+func Abort(c *app.RequestContext, err error) {
+	c.AbortWithStatusJSON(apperr.KindOf(err).Status(), errorEnvelope(c, err))
+}
+
+func errorEnvelope(c *app.RequestContext, err error) Envelope {
+	requestID := RequestID(c)
+	kind := apperr.KindOf(err)
+	body := &ErrorBody{
+		Code:    kind.Code(),
+		Message: err.Error(),
+	}
+	if message, ok := fixedMessages[kind]; ok {
+		log.Printf("Error in request %s (%s): %v", requestID, kind, err)
+		body.Message = message
+	}
+	var e *apperr.Error
+	if errors.As(err, &e) {
+		body.Details = e.Details
+	}
+	return Envelope{Error: body, RequestID: requestID}
+}
+
+// RequestID returns the ID of the request: the client's X-Request-ID if it
+// is well-formed, or a new one. It is echoed in the X-Request-ID response
+// header.
+func RequestID(c *app.RequestContext) string {
+	if id, ok := c.Get(requestIDKey); ok {
+		if s, ok := id.(string); ok {
+			return s
+		}
+	}
+	id := string(c.GetHeader(RequestIDHeader))
+	if !requestIDPattern.MatchString(id) {
+		id = uuid.NewString()
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	return id
+}
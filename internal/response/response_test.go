@@ -0,0 +1,112 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/zulfkhar00/instafit_mvp/internal/apperr"
+)
+
+func TestError(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		details map[string]interface{}
+	}{
+		{"validation", apperr.New(apperr.Validation, "name is required"), http.StatusBadRequest, "VALIDATION_ERROR", "name is required", nil},
+		{"wrapped kind", fmt.Errorf("loading: %w", apperr.New(apperr.NotFound, "photo not found")), http.StatusNotFound, "NOT_FOUND", "loading: photo not found", nil},
+		{"details", apperr.WithDetails(apperr.New(apperr.Forbidden, "not your photo"), map[string]interface{}{"photo": "p1"}), http.StatusForbidden, "FORBIDDEN", "not your photo", map[string]interface{}{"photo": "p1"}},
+		{"upstream failed", apperr.Wrap(apperr.UpstreamFailed, errors.New("ComfyUI backend http://10.0.0.5:8188: CUDA out of memory")), http.StatusBadGateway, "UPSTREAM_FAILED", fixedMessages[apperr.UpstreamFailed], nil},
+		{"upstream unavailable", apperr.Newf(apperr.UpstreamUnavailable, "ComfyUI is unavailable: %w", errors.New("dial tcp 10.0.0.5:8188: connection refused")), http.StatusServiceUnavailable, "UPSTREAM_UNAVAILABLE", fixedMessages[apperr.UpstreamUnavailable], nil},
+		{"timeout with details", apperr.WithDetails(apperr.New(apperr.Timeout, "prompt abc timed out on http://10.0.0.5:8188"), map[string]interface{}{"after": "5m"}), http.StatusGatewayTimeout, "TIMEOUT", fixedMessages[apperr.Timeout], map[string]interface{}{"after": "5m"}},
+		{"no kind", errors.New("open /data/wardrobe.json: permission denied"), http.StatusInternalServerError, "INTERNAL_ERROR", internalMessage, nil},
+		{"internal", apperr.Newf(apperr.Internal, "failed to save: %w", errors.New("disk full")), http.StatusInternalServerError, "INTERNAL_ERROR", internalMessage, nil},
+		{"internal with details", apperr.WithDetails(errors.New("bucket unreachable"), map[string]interface{}{"purged": float64(2)}), http.StatusInternalServerError, "INTERNAL_ERROR", internalMessage, map[string]interface{}{"purged": float64(2)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&logged)
+
+			engine := route.NewEngine(config.NewOptions(nil))
+			engine.GET("/", func(ctx context.Context, c *app.RequestContext) { Error(c, tc.err) })
+			resp := ut.PerformRequest(engine, http.MethodGet, "/", nil, ut.Header{Key: RequestIDHeader, Value: "req-1"}).Result()
+
+			if resp.StatusCode() != tc.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode(), tc.status)
+			}
+			var envelope Envelope
+			if err := json.Unmarshal(resp.Body(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Error == nil || envelope.Data != nil {
+				t.Fatalf("envelope = %s, want only an error", resp.Body())
+			}
+			if envelope.RequestID != "req-1" {
+				t.Errorf("request ID = %q, want the client's", envelope.RequestID)
+			}
+			if envelope.Error.Code != tc.code || envelope.Error.Message != tc.message {
+				t.Errorf("error = %s %q, want %s %q", envelope.Error.Code, envelope.Error.Message, tc.code, tc.message)
+			}
+			if fmt.Sprint(envelope.Error.Details) != fmt.Sprint(tc.details) {
+				t.Errorf("details = %v, want %v", envelope.Error.Details, tc.details)
+			}
+
+			// Masked errors are logged with the request ID instead
+			_, masked := fixedMessages[apperr.KindOf(tc.err)]
+			if got := strings.Contains(logged.String(), "req-1") && strings.Contains(logged.String(), tc.err.Error()); got != masked {
+				t.Errorf("logged %q, want the error logged: %v", logged.String(), masked)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"client ID", "abc-123_x.y", true},
+		{"missing", "", false},
+		{"malformed", "bad id\n", false},
+		{"too long", strings.Repeat("a", 129), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := route.NewEngine(config.NewOptions(nil))
+			engine.GET("/", func(ctx context.Context, c *app.RequestContext) {
+				Success(c, http.StatusOK, map[string]string{"ok": "yes"})
+			})
+			var headers []ut.Header
+			if tc.header != "" {
+				headers = append(headers, ut.Header{Key: RequestIDHeader, Value: tc.header})
+			}
+			resp := ut.PerformRequest(engine, http.MethodGet, "/", nil, headers...).Result()
+
+			var envelope Envelope
+			if err := json.Unmarshal(resp.Body(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.RequestID == "" || envelope.RequestID != string(resp.Header.Peek(RequestIDHeader)) {
+				t.Errorf("envelope ID %q and header %q differ", envelope.RequestID, resp.Header.Peek(RequestIDHeader))
+			}
+			if (envelope.RequestID == tc.header) != tc.keep {
+				t.Errorf("request ID = %q, keep client's %q: %v", envelope.RequestID, tc.header, tc.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package segmentertest provides a fake segmentation service for tests.
+// It follows the /segment/ contract of the Python segmenter but cuts the
+// posted image into horizontal bands instead of finding garments, so its
+// answers are deterministic.
+package segmentertest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// DefaultMetadata is the metadata of the bands cut by default: a top
+// above a bottom.
+var DefaultMetadata = []map[string]interface{}{
+	{
+		"categories": []interface{}{"tops"},
+		"types":      []interface{}{"t-shirt"},
+		"seasons":    []interface{}{"spring", "summer"},
+		"patterns":   []interface{}{"solid"},
+		"styles":     []interface{}{"casual"},
+		"occasions":  []interface{}{"daily"},
+	},
+	{
+		"categories": []interface{}{"bottoms"},
+		"types":      []interface{}{"jeans"},
+		"seasons":    []interface{}{"fall", "winter"},
+		"patterns":   []interface{}{"solid"},
+		"styles":     []interface{}{"casual"},
+		"occasions":  []interface{}{"daily"},
+	},
+}
+
+// Server is a fake segmenter. Its URL is the base URL of the service.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	metadata []map[string]interface{}
+	requests int
+}
+
+// NewServer starts a fake segmenter that cuts every image into one band
+// per entry of DefaultMetadata. Close it when done.
+func NewServer() *Server {
+	s := &Server{metadata: DefaultMetadata}
+	mux := http.NewServeMux()
+	mux.HandleFunc(internal.SegmentPath, s.handleSegment)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// SetMetadata sets the bands cut from images: one per entry, top to
+// bottom, each returned with its entry as metadata.
+func (s *Server) SetMetadata(metadata ...map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata = metadata
+}
+
+// Requests returns the number of images segmented so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) handleSegment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeDetail(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	file, _, err := r.FormFile(internal.SegmentFileField)
+	if err != nil {
+		writeDetail(w, http.StatusUnprocessableEntity, fmt.Sprintf("field %q is required", internal.SegmentFileField))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, "invalid image")
+		return
+	}
+
+	s.mu.Lock()
+	metadata := s.metadata
+	s.requests++
+	s.mu.Unlock()
+
+	resp, err := Segment(img, metadata)
+	if err != nil {
+		writeDetail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Segment cuts img into one horizontal band per metadata entry, encoded
+// as PNG, the way the fake server answers.
+func Segment(img image.Image, metadata []map[string]interface{}) (internal.SegmentResponse, error) {
+	resp := internal.SegmentResponse{SegmentedImages: make([]internal.SegmentedItem, 0, len(metadata))}
+	bounds := img.Bounds()
+	for i, meta := range metadata {
+		band := image.Rect(bounds.Min.X, bounds.Min.Y+bounds.Dy()*i/len(metadata), bounds.Max.X, bounds.Min.Y+bounds.Dy()*(i+1)/len(metadata))
+		if band.Empty() {
+			continue
+		}
+		cut := image.NewRGBA(image.Rect(0, 0, band.Dx(), band.Dy()))
+		draw.Draw(cut, cut.Bounds(), img, band.Min, draw.Src)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, cut); err != nil {
+			return internal.SegmentResponse{}, err
+		}
+		resp.SegmentedImages = append(resp.SegmentedImages, internal.SegmentedItem{
+			Filename: fmt.Sprintf("segment_%d.png", i),
+			Image:    base64.StdEncoding.EncodeToString(buf.Bytes()),
+			Metadata: meta,
+		})
+	}
+	return resp, nil
+}
+
+// writeDetail writes an error the way FastAPI does.
+func writeDetail(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"detail": detail})
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Node IDs of ImageWorkflow.json that take request parameters.
+const (
+	MaskNode         = "21" // GroundingDinoSAMSegment: prompt, threshold
+	GarmentImageNode = "22" // LoadImage for the garment
+	TryOnNode        = "24" // CatVTONWrapper: seed, steps, cfg, mask_grow
+	PersonImageNode  = "27" // LoadImage for the person
+	OutputNode       = "30" // SaveImageWebsocket
+
+	// Nodes added by MaskWorkflow to send the mask back.
+	MaskImageNode  = "40" // MaskToImage
+	MaskOutputNode = "41" // SaveImageWebsocket
+)
+
+// maskNodes are the nodes of ImageWorkflow.json that produce the mask.
+var maskNodes = []string{"19", "20", MaskNode, PersonImageNode}
+
+// Allowed ranges of the try-on generation parameters.
+const (
+	MaxSeed      = 1<<53 - 1 // largest integer JSON clients round-trip exactly
+	MinSteps     = 1
+	MaxSteps     = 100
+	MinCFG       = 1.0
+	MaxCFG       = 10.0
+	MinMaskGrow  = 0
+	MaxMaskGrow  = 100
+	MinThreshold = 0.05
+	MaxThreshold = 0.95
+)
+
+// DefaultMaskPrompt is the mask prompt used when none is given and the
+// garment type is unknown.
+const DefaultMaskPrompt = "shirt"
+
+// TryOnParams are the generation parameters of a try-on render.
+type TryOnParams struct {
+	Preset    string  `json:"preset,omitempty"`
+	Prompt    string  `json:"prompt"`
+	Seed      int64   `json:"seed"`
+	Steps     int     `json:"steps"`
+	CFG       float64 `json:"cfg"`
+	MaskGrow  int     `json:"mask_grow"`
+	Threshold float64 `json:"threshold"`
+	// ExplicitPrompt is set when the request gave a prompt. Otherwise the
+	// mask prompt may follow the garment.
+	ExplicitPrompt bool `json:"-"`
+}
+
+// TryOnPresets trade render quality for speed. They only set steps and cfg.
+var TryOnPresets = map[string]struct {
+	Steps int
+	CFG   float64
+}{
+	"fast":     {Steps: 15, CFG: 2.5},
+	"balanced": {Steps: 25, CFG: 2.7},
+	"best":     {Steps: 35, CFG: 2.7},
+}
+
+// WorkflowInput returns an input value of a workflow node, or nil.
+func WorkflowInput(workflow map[string]interface{}, node, name string) interface{} {
+	if n, ok := workflow[node].(map[string]interface{}); ok {
+		if inputs, ok := n["inputs"].(map[string]interface{}); ok {
+			return inputs[name]
+		}
+	}
+	return nil
+}
+
+// SetWorkflowInput sets an input value of a workflow node if the node exists.
+func SetWorkflowInput(workflow map[string]interface{}, node, name string, value interface{}) {
+	if n, ok := workflow[node].(map[string]interface{}); ok {
+		if inputs, ok := n["inputs"].(map[string]interface{}); ok {
+			inputs[name] = value
+		}
+	}
+}
+
+// DefaultTryOnParams reads the parameters baked into a workflow template.
+func DefaultTryOnParams(workflow map[string]interface{}) TryOnParams {
+	number := func(node, name string) float64 {
+		v, _ := WorkflowInput(workflow, node, name).(float64)
+		return v
+	}
+	return TryOnParams{
+		Prompt:    DefaultMaskPrompt,
+		Seed:      int64(number(TryOnNode, "seed")),
+		Steps:     int(number(TryOnNode, "steps")),
+		CFG:       number(TryOnNode, "cfg"),
+		MaskGrow:  int(number(TryOnNode, "mask_grow")),
+		Threshold: number(MaskNode, "threshold"),
+	}
+}
+
+// ParseTryOnParams overlays request values on the defaults: first the
+// preset, then any explicit value. get returns a raw request value, or ""
+// when it is absent. A seed of "random" picks a new seed. Every invalid
+// value is reported in the returned error, not just the first.
+func ParseTryOnParams(defaults TryOnParams, get func(name string) string) (TryOnParams, error) {
+	params := defaults
+	var problems []string
+
+	if preset := strings.ToLower(get("preset")); preset != "" {
+		p, ok := TryOnPresets[preset]
+		if !ok {
+			problems = append(problems, "preset must be one of fast, balanced, best")
+		} else {
+			params.Preset = preset
+			params.Steps, params.CFG = p.Steps, p.CFG
+		}
+	}
+	if prompt := strings.TrimSpace(get("prompt")); prompt != "" {
+		params.Prompt = prompt
+		params.ExplicitPrompt = true
+	}
+
+	if raw := get("seed"); raw == "random" {
+		params.Seed = rand.Int64N(MaxSeed + 1)
+	} else if raw != "" {
+		seed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seed < 0 || seed > MaxSeed {
+			problems = append(problems, fmt.Sprintf("seed must be an integer between 0 and %d or \"random\"", int64(MaxSeed)))
+		} else {
+			params.Seed = seed
+		}
+	}
+	if raw := get("steps"); raw != "" {
+		steps, err := strconv.Atoi(raw)
+		if err != nil || steps < MinSteps || steps > MaxSteps {
+			problems = append(problems, fmt.Sprintf("steps must be an integer between %d and %d", MinSteps, MaxSteps))
+		} else {
+			params.Steps = steps
+		}
+	}
+	if raw := get("cfg"); raw != "" {
+		cfg, err := strconv.ParseFloat(raw, 64)
+		if err != nil || cfg < MinCFG || cfg > MaxCFG {
+			problems = append(problems, fmt.Sprintf("cfg must be a number between %g and %g", MinCFG, MaxCFG))
+		} else {
+			params.CFG = cfg
+		}
+	}
+	if raw := get("mask_grow"); raw != "" {
+		grow, err := strconv.Atoi(raw)
+		if err != nil || grow < MinMaskGrow || grow > MaxMaskGrow {
+			problems = append(problems, fmt.Sprintf("mask_grow must be an integer between %d and %d", MinMaskGrow, MaxMaskGrow))
+		} else {
+			params.MaskGrow = grow
+		}
+	}
+	if raw := get("threshold"); raw != "" {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold < MinThreshold || threshold > MaxThreshold {
+			problems = append(problems, fmt.Sprintf("threshold must be a number between %g and %g", MinThreshold, MaxThreshold))
+		} else {
+			params.Threshold = threshold
+		}
+	}
+
+	if len(problems) > 0 {
+		return TryOnParams{}, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return params, nil
+}
+
+// MaskWorkflow returns the masking portion of a try-on workflow: the SAM
+// and GroundingDINO loaders, the segmenter and the person image, with the
+// mask sent back as an image from MaskOutputNode. The nodes are shared
+// with workflow, not copied.
+func MaskWorkflow(workflow map[string]interface{}) map[string]interface{} {
+	masking := make(map[string]interface{}, len(maskNodes)+2)
+	for _, node := range maskNodes {
+		if n, ok := workflow[node]; ok {
+			masking[node] = n
+		}
+	}
+	masking[MaskImageNode] = map[string]interface{}{
+		"inputs":     map[string]interface{}{"mask": []interface{}{MaskNode, 1}},
+		"class_type": "MaskToImage",
+	}
+	masking[MaskOutputNode] = map[string]interface{}{
+		"inputs":     map[string]interface{}{"images": []interface{}{MaskImageNode, 0}},
+		"class_type": "SaveImageWebsocket",
+	}
+	return masking
+}
+
+// Apply writes the parameters to the matching workflow nodes.
+func (p TryOnParams) Apply(workflow map[string]interface{}) {
+	SetWorkflowInput(workflow, MaskNode, "prompt", p.Prompt)
+	SetWorkflowInput(workflow, MaskNode, "threshold", p.Threshold)
+	SetWorkflowInput(workflow, TryOnNode, "seed", p.Seed)
+	SetWorkflowInput(workflow, TryOnNode, "steps", p.Steps)
+	SetWorkflowInput(workflow, TryOnNode, "cfg", p.CFG)
+	SetWorkflowInput(workflow, TryOnNode, "mask_grow", p.MaskGrow)
+}
+
+// CacheParams returns the parameters as a map for hashing and storage.
+// The preset is left out since it only names the values it set.
+func (p TryOnParams) CacheParams() map[string]interface{} {
+	return map[string]interface{}{
+		"prompt":    p.Prompt,
+		"seed":      p.Seed,
+		"steps":     p.Steps,
+		"cfg":       p.CFG,
+		"mask_grow": p.MaskGrow,
+		"threshold": p.Threshold,
+	}
+}
@@ -0,0 +1,145 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// form returns a get function for ParseTryOnParams over fixed values.
+func form(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestParseTryOnParamsExplicitPrompt(t *testing.T) {
+	defaults := internal.TryOnParams{Prompt: internal.DefaultMaskPrompt}
+	for _, tc := range []struct {
+		name     string
+		prompt   string
+		want     string
+		explicit bool
+	}{
+		{"absent", "", internal.DefaultMaskPrompt, false},
+		{"blank", "  \t", internal.DefaultMaskPrompt, false},
+		{"given", "dress", "dress", true},
+		{"padded", "  long skirt ", "long skirt", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := internal.ParseTryOnParams(defaults, form(map[string]string{"prompt": tc.prompt}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params.Prompt != tc.want || params.ExplicitPrompt != tc.explicit {
+				t.Errorf("prompt = %q explicit %v, want %q explicit %v", params.Prompt, params.ExplicitPrompt, tc.want, tc.explicit)
+			}
+		})
+	}
+}
+
+// testWorkflow has the parameter inputs of ImageWorkflow.json, decoded as
+// encoding/json would.
+func testWorkflow() map[string]interface{} {
+	return map[string]interface{}{
+		internal.MaskNode: map[string]interface{}{"inputs": map[string]interface{}{"prompt": "shirt", "threshold": 0.3}},
+		internal.TryOnNode: map[string]interface{}{"inputs": map[string]interface{}{
+			"seed": float64(42), "steps": float64(50), "cfg": 2.5, "mask_grow": float64(25),
+		}},
+	}
+}
+
+func TestDefaultTryOnParams(t *testing.T) {
+	want := internal.TryOnParams{Prompt: internal.DefaultMaskPrompt, Seed: 42, Steps: 50, CFG: 2.5, MaskGrow: 25, Threshold: 0.3}
+	if got := internal.DefaultTryOnParams(testWorkflow()); got != want {
+		t.Errorf("defaults = %+v, want %+v", got, want)
+	}
+	// Missing nodes leave zero values rather than panicking
+	if got := internal.DefaultTryOnParams(map[string]interface{}{}); got != (internal.TryOnParams{Prompt: internal.DefaultMaskPrompt}) {
+		t.Errorf("defaults of an empty workflow = %+v", got)
+	}
+}
+
+func TestParseTryOnParams(t *testing.T) {
+	defaults := internal.DefaultTryOnParams(testWorkflow())
+	with := func(change func(p *internal.TryOnParams)) internal.TryOnParams {
+		p := defaults
+		change(&p)
+		return p
+	}
+	for _, tc := range []struct {
+		name   string
+		values map[string]string
+		want   internal.TryOnParams
+		// problems are the fields the error must mention, in order
+		problems []string
+	}{
+		{"defaults", nil, defaults, nil},
+		{"preset", map[string]string{"preset": "fast"}, with(func(p *internal.TryOnParams) {
+			p.Preset, p.Steps, p.CFG = "fast", 15, 2.5
+		}), nil},
+		{"preset is case insensitive", map[string]string{"preset": "Best"}, with(func(p *internal.TryOnParams) {
+			p.Preset, p.Steps, p.CFG = "best", 35, 2.7
+		}), nil},
+		{"explicit values override the preset", map[string]string{"preset": "balanced", "steps": "10"}, with(func(p *internal.TryOnParams) {
+			p.Preset, p.Steps, p.CFG = "balanced", 10, 2.7
+		}), nil},
+		{"every value", map[string]string{"seed": "7", "steps": "100", "cfg": "1", "mask_grow": "0", "threshold": "0.95"}, with(func(p *internal.TryOnParams) {
+			p.Seed, p.Steps, p.CFG, p.MaskGrow, p.Threshold = 7, 100, 1, 0, 0.95
+		}), nil},
+		{"largest seed", map[string]string{"seed": "9007199254740991"}, with(func(p *internal.TryOnParams) {
+			p.Seed = internal.MaxSeed
+		}), nil},
+		{"unknown preset", map[string]string{"preset": "ultra"}, internal.TryOnParams{}, []string{"preset"}},
+		{"seed too large", map[string]string{"seed": "9007199254740992"}, internal.TryOnParams{}, []string{"seed"}},
+		{"negative seed", map[string]string{"seed": "-1"}, internal.TryOnParams{}, []string{"seed"}},
+		{"steps out of range", map[string]string{"steps": "0"}, internal.TryOnParams{}, []string{"steps"}},
+		{"cfg not a number", map[string]string{"cfg": "high"}, internal.TryOnParams{}, []string{"cfg"}},
+		{"mask_grow out of range", map[string]string{"mask_grow": "101"}, internal.TryOnParams{}, []string{"mask_grow"}},
+		{"threshold out of range", map[string]string{"threshold": "0.01"}, internal.TryOnParams{}, []string{"threshold"}},
+		{"every problem", map[string]string{
+			"preset": "ultra", "seed": "x", "steps": "1.5", "cfg": "11", "mask_grow": "-1", "threshold": "1",
+		}, internal.TryOnParams{}, []string{"preset", "seed", "steps", "cfg", "mask_grow", "threshold"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := internal.ParseTryOnParams(defaults, form(tc.values))
+			if len(tc.problems) > 0 {
+				if err == nil {
+					t.Fatalf("params = %+v, want an error", params)
+				}
+				problems := strings.Split(err.Error(), "; ")
+				if len(problems) != len(tc.problems) {
+					t.Fatalf("error %q, want problems with %v", err, tc.problems)
+				}
+				for i, field := range tc.problems {
+					if !strings.HasPrefix(problems[i], field+" must be") {
+						t.Errorf("problem %d = %q, want one about %s", i, problems[i], field)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params != tc.want {
+				t.Errorf("params = %+v, want %+v", params, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTryOnParamsRandomSeed(t *testing.T) {
+	seeds := make(map[int64]bool)
+	for range 5 {
+		params, err := internal.ParseTryOnParams(internal.TryOnParams{Seed: -1}, form(map[string]string{"seed": "random"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Seed < 0 || params.Seed > internal.MaxSeed {
+			t.Fatalf("random seed %d is out of range", params.Seed)
+		}
+		seeds[params.Seed] = true
+	}
+	if len(seeds) == 1 {
+		t.Error("random seeds repeat")
+	}
+}
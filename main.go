@@ -4,75 +4,182 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
-	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/zulfkhar00/instafit_mvp/handlers"
-	"github.com/zulfkhar00/instafit_mvp/internal/middleware"
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/config"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/recommendation"
 	"github.com/zulfkhar00/instafit_mvp/services/storage"
-
-	"github.com/joho/godotenv"
-)
-
-const (
-	ServerPort = "8080"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/tryoncache"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
 )
 
 func main() {
-	// Default environment
-	env := os.Getenv("APP_ENV")
-	if env == "" {
-		env = "dev"
-	}
-	envFile := fmt.Sprintf(".env.%s", env)
-	// Load specific environment file, fallback to default .env
-	if _, err := os.Stat(envFile); err == nil {
-		log.Printf("Loading environment from %s", envFile)
-	} else {
-		log.Printf("%s not found, loading default .env file", envFile)
-		envFile = ".env"
-	}
-	if err := godotenv.Load(envFile); err != nil {
-		log.Fatalf("Error loading %s file: %v", envFile, err)
-	}
-
-	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
-	if len(jwtSecret) == 0 {
-		log.Fatal("JWT_SECRET environment variable not set")
+	// Settings come from .env files, an optional CONFIG_FILE and the
+	// environment; every problem is reported at once
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
+	jwtSecret := []byte(cfg.Auth.JWTSecret)
 
 	// Initialize services
-	storageSvc, err := storage.NewR2Service()
+	storageSvc, err := storage.NewR2Service(cfg.R2)
 	if err != nil {
 		log.Fatalf("failed to initialize storage service: %v", err)
 	}
+	wardrobeStore, err := wardrobe.NewFileStore(cfg.Data.Wardrobe)
+	if err != nil {
+		log.Fatalf("failed to initialize wardrobe store: %v", err)
+	}
+	outfitStore, err := outfits.NewFileStore(cfg.Data.Outfits)
+	if err != nil {
+		log.Fatalf("failed to initialize outfit store: %v", err)
+	}
+	wearLogStore, err := wearlog.NewFileStore(cfg.Data.WearLog)
+	if err != nil {
+		log.Fatalf("failed to initialize wear log store: %v", err)
+	}
+	photoStore, err := photos.NewFileStore(cfg.Data.PersonPhotos)
+	if err != nil {
+		log.Fatalf("failed to initialize person photo store: %v", err)
+	}
+	tryOnStore, err := tryons.NewFileStore(cfg.Data.TryOns)
+	if err != nil {
+		log.Fatalf("failed to initialize try-on store: %v", err)
+	}
+	tryOnCache, err := tryoncache.NewCache(storageSvc, cfg.Data.TryOnCache)
+	if err != nil {
+		log.Fatalf("failed to initialize try-on cache: %v", err)
+	}
+	maskPrompts, err := internal.LoadMaskPrompts(cfg.MaskPromptsPath)
+	if err != nil {
+		log.Fatalf("failed to load mask prompts: %v", err)
+	}
+	segmenter := internal.NewSegmenter(cfg.Segmenter.URL)
+
+	// ComfyUI is supervised when a checkout is configured; otherwise it is
+	// expected to be running already
+	var comfySupervisor *internal.Supervisor
+	if cfg.ComfyUI.Path != "" {
+		comfySupervisor = internal.NewSupervisor(internal.SupervisorConfig{
+			Dir:     cfg.ComfyUI.Path,
+			Command: cfg.ComfyUI.Command,
+			Args:    cfg.ComfyUI.Args,
+			URL:     cfg.ComfyUI.URL,
+		})
+		if err := comfySupervisor.Start(); err != nil {
+			log.Fatalf("failed to start ComfyUI: %v", err)
+		}
+	}
+
+	comfyPool, err := internal.NewPool(cfg.ComfyUI.URLs, internal.DefaultHealthCheckInterval)
+	if err != nil {
+		log.Fatalf("failed to initialize ComfyUI pool: %v", err)
+	}
+	comfyPool.SetTimeout(cfg.ComfyUI.PromptTimeout)
+	comfyPool.Start()
+
+	// Debug artifacts of try-ons are only recorded when a directory is
+	// configured, and then only for requests that ask for them
+	var debugRecorder *internal.DebugRecorder
+	if cfg.Debug.Dir != "" {
+		debugRecorder, err = internal.NewDebugRecorder(cfg.Debug.Dir, cfg.Debug.MaxJobs, cfg.Debug.MaxAge)
+		if err != nil {
+			log.Fatalf("failed to initialize debug recorder: %v", err)
+		}
+	}
+
+	// Weather is optional; without it recommendations by location are rejected
+	var weatherProvider weather.Provider
+	if cfg.Weather.APIURL != "" {
+		weatherProvider = weather.NewHTTPProvider(cfg.Weather.APIURL)
+	}
 
 	// initialize handlers
 	clothesHandler := &handlers.ClothesHandler{
+		Storage:   storageSvc,
+		Wardrobe:  wardrobeStore,
+		Outfits:   outfitStore,
+		WearLog:   wearLogStore,
+		Segmenter: segmenter,
+	}
+	outfitHandler := &handlers.OutfitHandler{
+		Outfits:  outfitStore,
+		Wardrobe: wardrobeStore,
+	}
+	wearLogHandler := &handlers.WearLogHandler{
+		WearLog:  wearLogStore,
+		Outfits:  outfitStore,
+		Wardrobe: wardrobeStore,
+	}
+	recommendationHandler := &handlers.RecommendationHandler{
+		Recommender: recommendation.NewService(wardrobeStore, weatherProvider),
+	}
+	personPhotoHandler := &handlers.PersonPhotoHandler{
 		Storage: storageSvc,
+		Photos:  photoStore,
 	}
-	userHandler := &handlers.UserHandler{}
+	tryOnHandler := &handlers.TryOnHandler{
+		Storage:      storageSvc,
+		Photos:       photoStore,
+		Wardrobe:     wardrobeStore,
+		TryOns:       tryOnStore,
+		Cache:        tryOnCache,
+		Batches:      tryonbatch.NewTracker(tryonbatch.DefaultMaxFinished, tryonbatch.DefaultRetention),
+		ComfyUI:      comfyPool,
+		Supervisor:   comfySupervisor,
+		Debug:        debugRecorder,
+		MaskPrompts:  maskPrompts,
+		Segmenter:    segmenter,
+		WorkflowPath: cfg.ComfyUI.WorkflowPath,
+	}
+	userHandler := &handlers.UserHandler{JWTSecret: jwtSecret}
+	healthHandler := &handlers.HealthHandler{ComfyUI: comfyPool}
+	openAPISpec, err := openAPIDocument()
+	if err != nil {
+		log.Fatalf("failed to build OpenAPI document: %v", err)
+	}
+	docsHandler := &handlers.DocsHandler{OpenAPI: openAPISpec}
 
 	// create a new Hertz server
-	h := server.New(server.WithHostPorts(":" + ServerPort))
-
-	// Set up routes
-	h.GET("/api/health", func(ctx context.Context, c *app.RequestContext) {
-		handlers.HealthCheckHandler(ctx, c)
+	h := server.New(
+		server.WithHostPorts(fmt.Sprintf(":%d", cfg.Server.Port)),
+		// cancel request contexts on disconnect so abandoned renders stop
+		server.WithSenseClientDisconnection(true),
+		// leave ComfyUI time to stop cleanly
+		server.WithExitWaitTime(internal.DefaultStopTimeout+5*time.Second),
+	)
+	h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) {
+		comfyPool.Stop()
 	})
-	// WARNING: This is a TESTING-ONLY route. Disable or remove in production!
-	h.POST("/api/test-auth", userHandler.TestAuthHandler)
+	if comfySupervisor != nil {
+		h.OnShutdown = append(h.OnShutdown, func(ctx context.Context) {
+			comfySupervisor.Stop(ctx)
+		})
+	}
 
-	authGroup := h.Group("/api")
-	authGroup.Use(middleware.AuthMiddleware(jwtSecret))
-	authGroup.POST("/wardrobe/add", clothesHandler.AddClothesToWardrobeHandler)
-	authGroup.DELETE("/wardrobe/:clothId", clothesHandler.RemoveClothingFromWardrobeHandler)
-	authGroup.POST("/virtual-tryon", func(ctx context.Context, c *app.RequestContext) {
-		handlers.VirtualTryOnHandler(ctx, c)
-	})
+	registerRoutes(h.Engine, routeHandlers{
+		clothes:        clothesHandler,
+		outfit:         outfitHandler,
+		wearLog:        wearLogHandler,
+		recommendation: recommendationHandler,
+		personPhoto:    personPhotoHandler,
+		tryOn:          tryOnHandler,
+		user:           userHandler,
+		health:         healthHandler,
+		docs:           docsHandler,
+	}, jwtSecret, cfg.Auth.AdminUserIDs)
 
 	// Start server
-	log.Printf("Server starting on port %s...", ServerPort)
+	log.Printf("Server starting on port %d...", cfg.Server.Port)
 	h.Spin()
 }
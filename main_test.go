@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/zulfkhar00/instafit_mvp/handlers"
+	"github.com/zulfkhar00/instafit_mvp/internal/openapi"
+)
+
+// newTestEngine registers the API routes with handlers that have no
+// dependencies; only routing and middleware can be exercised.
+func newTestEngine(t *testing.T) *route.Engine {
+	t.Helper()
+	spec, err := openAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := route.NewEngine(config.NewOptions(nil))
+	registerRoutes(engine, routeHandlers{docs: &handlers.DocsHandler{OpenAPI: spec}}, []byte("test-secret"), nil)
+	return engine
+}
+
+// loadDocument decodes the served document.
+func loadDocument(t *testing.T) openapi.Document {
+	t.Helper()
+	spec, err := openAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, r := range newTestEngine(t).Routes() {
+		registered[r.Method+" "+openapi.Path(r.Path)] = true
+	}
+	documented := make(map[string]bool)
+	for path, item := range loadDocument(t).Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for route := range registered {
+		if !documented[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from the OpenAPI document, add them to apiRoutes:\n%s", strings.Join(missing, "\n"))
+	}
+	if len(stale) > 0 {
+		t.Errorf("documented routes that are not registered:\n%s", strings.Join(stale, "\n"))
+	}
+}
+
+func TestOpenAPIDocumentAuthMatchesRoutes(t *testing.T) {
+	engine := newTestEngine(t)
+	for path, item := range loadDocument(t).Paths {
+		for method, op := range item {
+			if len(op.Security) == 0 {
+				continue
+			}
+			// Without a token the auth middleware answers before any handler
+			url := strings.NewReplacer("{", "", "}", "").Replace(path)
+			resp := ut.PerformRequest(engine, strings.ToUpper(method), url, nil).Result()
+			if resp.StatusCode() != http.StatusUnauthorized {
+				t.Errorf("%s %s is documented as requiring a token but answered %d without one", strings.ToUpper(method), path, resp.StatusCode())
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocumentIsValid(t *testing.T) {
+	doc := loadDocument(t)
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", doc.OpenAPI)
+	}
+	if doc.Info.Title == "" || doc.Info.Version == "" {
+		t.Error("info needs a title and version")
+	}
+
+	// checkRefs reports references to components that do not exist
+	var checkRefs func(where string, s *openapi.Schema)
+	checkRefs = func(where string, s *openapi.Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			if _, ok := doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]; !ok {
+				t.Errorf("%s: unresolved reference %s", where, s.Ref)
+			}
+		}
+		checkRefs(where, s.Items)
+		checkRefs(where, s.AdditionalProperties)
+		for _, p := range s.Properties {
+			checkRefs(where, p)
+		}
+	}
+	for name, s := range doc.Components.Schemas {
+		checkRefs("component "+name, s)
+	}
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			where := strings.ToUpper(method) + " " + path
+			declared := make(map[string]bool)
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					declared[p.Name] = true
+				}
+			}
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, "{") && !declared[strings.Trim(segment, "{}")] {
+					t.Errorf("%s: path parameter %s is not declared", where, segment)
+				}
+			}
+
+			success := false
+			for status, resp := range op.Responses {
+				success = success || strings.HasPrefix(status, "2")
+				for _, media := range resp.Content {
+					checkRefs(where, media.Schema)
+				}
+			}
+			if !success {
+				t.Errorf("%s: no success response", where)
+			}
+			if _, ok := op.Responses["default"]; !ok {
+				t.Errorf("%s: no error response", where)
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					checkRefs(where, media.Schema)
+				}
+			}
+			for _, requirement := range op.Security {
+				for scheme := range requirement {
+					if _, ok := doc.Components.SecuritySchemes[scheme]; !ok {
+						t.Errorf("%s: unknown security scheme %s", where, scheme)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPIPropertiesAreSnakeCase(t *testing.T) {
+	doc := loadDocument(t)
+	snakeCase := regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	var check func(where string, s *openapi.Schema)
+	check = func(where string, s *openapi.Schema) {
+		if s == nil {
+			return
+		}
+		for name, p := range s.Properties {
+			if !snakeCase.MatchString(name) {
+				t.Errorf("%s: property %q is not snake_case", where, name)
+			}
+			check(where, p)
+		}
+		check(where, s.Items)
+		check(where, s.AdditionalProperties)
+	}
+	for name, s := range doc.Components.Schemas {
+		check("component "+name, s)
+	}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			where := strings.ToUpper(method) + " " + path
+			for _, resp := range op.Responses {
+				for _, media := range resp.Content {
+					check(where, media.Schema)
+				}
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					check(where, media.Schema)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPIRouteServesDocument(t *testing.T) {
+	resp := ut.PerformRequest(newTestEngine(t), http.MethodGet, "/api/openapi.json", nil).Result()
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode())
+	}
+	if ct := string(resp.Header.ContentType()); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("content type = %q", ct)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(resp.Body(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths["/api/virtual-tryon"]["post"] == nil {
+		t.Error("served document does not describe POST /api/virtual-tryon")
+	}
+}
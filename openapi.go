@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/openapi"
+	"github.com/zulfkhar00/instafit_mvp/services/outfits"
+	"github.com/zulfkhar00/instafit_mvp/services/photos"
+	"github.com/zulfkhar00/instafit_mvp/services/recommendation"
+	"github.com/zulfkhar00/instafit_mvp/services/tryonbatch"
+	"github.com/zulfkhar00/instafit_mvp/services/tryons"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/wearlog"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+// apiInfo describes the API in the OpenAPI document.
+var apiInfo = openapi.Info{
+	Title:   "InstaFit API",
+	Version: "1.0.0",
+	Description: "Wardrobe management, outfit recommendations and virtual try-on. " +
+		"JSON responses are wrapped in an envelope holding either data or error, and the request ID.",
+}
+
+// personImageFields select the person of a try-on: an upload, a saved
+// photo, or by default the user's default photo.
+var personImageFields = []openapi.Param{
+	{Name: "person_image", Type: "file", Description: "Photo of the person; overrides person_photo_id"},
+	{Name: "person_photo_id", Type: "string", Description: "Saved person photo; the default photo when neither is given"},
+}
+
+// tryOnParamFields are the generation parameters of try-on renders.
+var tryOnParamFields = []openapi.Param{
+	{Name: "preset", Type: "string", Description: "fast, balanced or best; sets steps and cfg"},
+	{Name: "prompt", Type: "string", Description: "Mask prompt; derived from the garment type by default"},
+	{Name: "seed", Type: "string", Description: "Integer seed or \"random\""},
+	{Name: "steps", Type: "integer"},
+	{Name: "cfg", Type: "number"},
+	{Name: "mask_grow", Type: "integer"},
+	{Name: "threshold", Type: "number"},
+}
+
+// debugField asks for the debug artifacts of a render to be recorded.
+var debugField = openapi.Param{Name: "debug", Type: "boolean", Description: "Record debug artifacts when the server keeps them"}
+
+// fields concatenates lists of form fields.
+func fields(lists ...[]openapi.Param) []openapi.Param {
+	var all []openapi.Param
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// apiRoutes describes every route registered by registerRoutes.
+var apiRoutes = []openapi.Route{
+	{
+		Method: http.MethodGet, Path: "/api/health", Tag: "health",
+		Summary: "Report the health of the server and its ComfyUI backends",
+		Data: openapi.Object{
+			"status":           "",
+			"comfyui_status":   "",
+			"comfyui_backends": []internal.BackendStatus{},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/openapi.json", Tag: "docs",
+		Summary: "Get this OpenAPI document",
+		Raw:     "application/json",
+	},
+	{
+		Method: http.MethodPost, Path: "/api/test-auth", Tag: "auth",
+		Summary: "Issue a token for a user ID; for testing only",
+		JSON:    openapi.Object{"user_id": ""},
+		Data:    openapi.Object{"token": ""},
+	},
+
+	// Wardrobe
+	{
+		Method: http.MethodGet, Path: "/api/wardrobe", Tag: "wardrobe", Auth: openapi.User,
+		Summary: "List wardrobe items",
+		Query: []openapi.Param{
+			{Name: "color_family", Type: "string", Description: "Comma-separated color families"},
+			{Name: "category", Type: "string", Description: "Comma-separated categories"},
+			{Name: "season", Type: "string", Description: "Comma-separated seasons"},
+			{Name: "formality", Type: "string", Description: "Comma-separated formalities"},
+			{Name: "not_worn_days", Type: "integer", Description: "Only items not worn in this many days"},
+			{Name: "sort", Type: "string", Description: "least_worn"},
+		},
+		Data: openapi.Object{
+			"clothes":    []wardrobe.ClothingItem{},
+			"wear_stats": map[string]wearlog.ItemStats{},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/wardrobe/add", Tag: "wardrobe", Auth: openapi.User,
+		Summary: "Segment photos of clothes and add the garments to the wardrobe",
+		Form: []openapi.Param{
+			{Name: "clothes", Type: "file", Required: true, Multiple: true},
+			{Name: "on_duplicate", Type: "string", Description: "warn (default) or skip"},
+		},
+		Data: openapi.Object{
+			"clothes": []openapi.Object{{
+				"image_url":    "",
+				"clothing_id":  "",
+				"attributes":   internal.ClothingAttributes{},
+				"duplicate_of": openapi.Optional(duplicateInfo),
+			}},
+			"skipped": []openapi.Object{{
+				"reason":       "",
+				"error":        openapi.Optional(""),
+				"attributes":   internal.ClothingAttributes{},
+				"duplicate_of": openapi.Optional(duplicateInfo),
+			}},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/wardrobe/duplicates", Tag: "wardrobe", Auth: openapi.User,
+		Summary: "Group wardrobe items that look like the same garment",
+		Query: []openapi.Param{
+			{Name: "max_distance", Type: "integer", Description: "Perceptual hash distance, 0 to 64"},
+		},
+		Data: openapi.Object{
+			"max_distance": 0,
+			"duplicates":   []wardrobe.DuplicateGroup{},
+		},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/wardrobe/:clothId", Tag: "wardrobe", Auth: openapi.User,
+		Summary: "Remove a wardrobe item and take it out of outfits",
+		Data: openapi.Object{
+			"cloth_id":        "",
+			"updated_outfits": []string{},
+			"deleted_outfits": []string{},
+		},
+	},
+
+	// Outfits
+	{
+		Method: http.MethodGet, Path: "/api/outfits", Tag: "outfits", Auth: openapi.User,
+		Summary: "List outfits",
+		Data:    openapi.Object{"outfits": []outfits.Outfit{}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/outfits", Tag: "outfits", Auth: openapi.User,
+		Summary: "Create an outfit from wardrobe items",
+		JSON: openapi.Object{
+			"name":              "",
+			"items":             []outfits.OutfitItem{},
+			"cover_clothing_id": openapi.Optional(""),
+		},
+		Data: openapi.Object{"outfit": outfits.Outfit{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/outfits/:outfitId", Tag: "outfits", Auth: openapi.User,
+		Summary: "Get an outfit",
+		Data:    openapi.Object{"outfit": outfits.Outfit{}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/outfits/:outfitId", Tag: "outfits", Auth: openapi.User,
+		Summary: "Delete an outfit",
+		Data:    openapi.Object{"outfit_id": ""},
+	},
+
+	// Wear log
+	{
+		Method: http.MethodGet, Path: "/api/wear-log", Tag: "wear-log", Auth: openapi.User,
+		Summary: "Get the wear log of a month",
+		Query: []openapi.Param{
+			{Name: "month", Type: "string", Description: "YYYY-MM, the current month by default"},
+		},
+		Data: openapi.Object{
+			"month": "",
+			"days": []openapi.Object{{
+				"date":    "",
+				"entries": []wearlog.Entry{},
+			}},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/wear-log", Tag: "wear-log", Auth: openapi.User,
+		Summary: "Log the clothes or outfit worn on a day",
+		JSON: openapi.Object{
+			"date":         "",
+			"outfit_id":    openapi.Optional(""),
+			"clothing_ids": openapi.Optional([]string{}),
+		},
+		Data: openapi.Object{
+			"entry":     wearlog.Entry{},
+			"repeat_of": openapi.Optional(wearlog.Entry{}),
+			"warning":   openapi.Optional(""),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/wear-log/stats", Tag: "wear-log", Auth: openapi.User,
+		Summary: "Get how often each wardrobe item was worn, least worn first",
+		Data:    openapi.Object{"items": []wearlog.ItemStats{}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/wear-log/:entryId", Tag: "wear-log", Auth: openapi.User,
+		Summary: "Delete a wear log entry",
+		Data:    openapi.Object{"entry_id": ""},
+	},
+
+	// Recommendations
+	{
+		Method: http.MethodGet, Path: "/api/recommendations", Tag: "recommendations", Auth: openapi.User,
+		Summary: "Suggest outfits from the wardrobe",
+		Query: []openapi.Param{
+			{Name: "season", Type: "string"},
+			{Name: "formality", Type: "string"},
+			{Name: "occasion", Type: "string"},
+			{Name: "location", Type: "string", Description: "Dress for the current weather here"},
+			{Name: "limit", Type: "integer"},
+		},
+		Data: openapi.Object{
+			"season":          "",
+			"formality":       "",
+			"occasion":        "",
+			"weather":         &weather.Conditions{},
+			"recommendations": []recommendation.Recommendation{},
+		},
+	},
+
+	// Person photos
+	{
+		Method: http.MethodGet, Path: "/api/person-photos", Tag: "person-photos", Auth: openapi.User,
+		Summary: "List saved person photos",
+		Data:    openapi.Object{"photos": []photos.PersonPhoto{}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/person-photos", Tag: "person-photos", Auth: openapi.User,
+		Summary: "Save a person photo for try-ons",
+		Form: []openapi.Param{
+			{Name: "photo", Type: "file", Required: true},
+			{Name: "set_default", Type: "boolean"},
+		},
+		Data: openapi.Object{"photo": photos.PersonPhoto{}},
+	},
+	{
+		Method: http.MethodPut, Path: "/api/person-photos/:photoId/default", Tag: "person-photos", Auth: openapi.User,
+		Summary: "Make a person photo the default",
+		Data:    openapi.Object{"photo": photos.PersonPhoto{}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/person-photos/:photoId", Tag: "person-photos", Auth: openapi.User,
+		Summary: "Delete a person photo",
+		Data:    openapi.Object{"photo_id": ""},
+	},
+
+	// Virtual try-on
+	{
+		Method: http.MethodPost, Path: "/api/virtual-tryon", Tag: "virtual-tryon", Auth: openapi.User,
+		Summary: "Render a garment on a person",
+		Form: fields(personImageFields, []openapi.Param{
+			{Name: "garment_image", Type: "file", Description: "Photo of the garment; overrides clothing_id"},
+			{Name: "clothing_id", Type: "string", Description: "Wardrobe item to try on"},
+		}, tryOnParamFields, []openapi.Param{debugField}),
+		Raw: "image/jpeg",
+		Headers: []openapi.Param{
+			{Name: "X-TryOn-Id", Type: "string", Description: "Gallery ID of the try-on"},
+			{Name: "X-TryOn-Params", Type: "string", Description: "JSON of the effective generation parameters"},
+			{Name: "X-Cache", Type: "string", Description: "HIT or MISS"},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/virtual-tryon/mask-preview", Tag: "virtual-tryon", Auth: openapi.User,
+		Summary: "Preview the garment mask a try-on would use",
+		Form: fields(personImageFields, []openapi.Param{
+			{Name: "clothing_id", Type: "string", Description: "Derive the prompt from this wardrobe item"},
+		}, tryOnParamFields, []openapi.Param{debugField}),
+		Data: openapi.Object{
+			"prompt":    "",
+			"threshold": 0.0,
+			"mask":      []byte{},
+			"overlay":   []byte{},
+		},
+	},
+
+	// Try-on batches
+	{
+		Method: http.MethodPost, Path: "/api/tryon-batches", Tag: "tryon-batches", Auth: openapi.User,
+		Summary: "Start rendering every garment on every person photo",
+		Form: fields([]openapi.Param{
+			{Name: "person_photo_ids", Type: "string", Required: true, Description: "Comma-separated person photo IDs"},
+			{Name: "clothing_ids", Type: "string", Required: true, Description: "Comma-separated wardrobe item IDs"},
+		}, tryOnParamFields, []openapi.Param{debugField}),
+		Status: http.StatusAccepted,
+		Data:   openapi.Object{"batch": tryonbatch.Batch{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/tryon-batches/:batchId", Tag: "tryon-batches", Auth: openapi.User,
+		Summary: "Get the progress of a batch",
+		Data:    openapi.Object{"batch": tryonbatch.Batch{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/tryon-batches/:batchId/contact-sheet", Tag: "tryon-batches", Auth: openapi.User,
+		Summary: "Get the rendered cells of a batch as one image",
+		Raw:     "image/jpeg",
+	},
+	{
+		Method: http.MethodPost, Path: "/api/tryon-batches/:batchId/cancel", Tag: "tryon-batches", Auth: openapi.User,
+		Summary: "Cancel the cells of a batch that have not rendered yet",
+		Data:    openapi.Object{"batch": tryonbatch.Batch{}},
+	},
+
+	// Try-on gallery
+	{
+		Method: http.MethodGet, Path: "/api/tryons", Tag: "tryons", Auth: openapi.User,
+		Summary: "List saved try-ons, newest first",
+		Query: []openapi.Param{
+			{Name: "favorite", Type: "boolean", Description: "Only favorites"},
+		},
+		Data: openapi.Object{"tryons": []tryons.TryOn{}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/tryons/:tryOnId", Tag: "tryons", Auth: openapi.User,
+		Summary: "Get a saved try-on",
+		Data:    openapi.Object{"tryon": tryons.TryOn{}},
+	},
+	{
+		Method: http.MethodPut, Path: "/api/tryons/:tryOnId/favorite", Tag: "tryons", Auth: openapi.User,
+		Summary: "Mark or unmark a try-on as favorite",
+		JSON:    openapi.Object{"favorite": false},
+		Data:    openapi.Object{"tryon": tryons.TryOn{}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/tryons/:tryOnId", Tag: "tryons", Auth: openapi.User,
+		Summary: "Delete a saved try-on",
+		Data:    openapi.Object{"tryon_id": ""},
+	},
+
+	// Admin
+	{
+		Method: http.MethodDelete, Path: "/api/admin/tryon-cache", Tag: "admin", Auth: openapi.Admin,
+		Summary: "Purge the try-on cache",
+		Query: []openapi.Param{
+			{Name: "older_than", Type: "string", Description: "Only entries older than this duration, such as 72h"},
+		},
+		Data: openapi.Object{"purged": 0},
+	},
+}
+
+// duplicateInfo describes the wardrobe item an added garment duplicates.
+var duplicateInfo = openapi.Object{
+	"clothing_id": "",
+	"image_url":   "",
+	"distance":    0,
+}
+
+// openAPIDocument returns the encoded OpenAPI document of apiRoutes.
+func openAPIDocument() ([]byte, error) {
+	doc, err := openapi.Build(apiInfo, apiRoutes)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/zulfkhar00/instafit_mvp/handlers"
+	"github.com/zulfkhar00/instafit_mvp/internal/middleware"
+)
+
+// routeHandlers are the handlers the API routes are served by.
+type routeHandlers struct {
+	clothes        *handlers.ClothesHandler
+	outfit         *handlers.OutfitHandler
+	wearLog        *handlers.WearLogHandler
+	recommendation *handlers.RecommendationHandler
+	personPhoto    *handlers.PersonPhotoHandler
+	tryOn          *handlers.TryOnHandler
+	user           *handlers.UserHandler
+	health         *handlers.HealthHandler
+	docs           *handlers.DocsHandler
+}
+
+// registerRoutes sets up the API routes. Every route must be described in
+// apiRoutes, which the OpenAPI document is built from.
+func registerRoutes(e *route.Engine, hs routeHandlers, jwtSecret []byte, adminUserIDs []string) {
+	e.Use(middleware.RequestIDMiddleware())
+
+	e.GET("/api/health", hs.health.HealthCheckHandler)
+	e.GET("/api/openapi.json", hs.docs.OpenAPIHandler)
+	// WARNING: This is a TESTING-ONLY route. Disable or remove in production!
+	e.POST("/api/test-auth", hs.user.TestAuthHandler)
+
+	authGroup := e.Group("/api")
+	authGroup.Use(middleware.AuthMiddleware(jwtSecret))
+	authGroup.GET("/wardrobe", hs.clothes.ListWardrobeHandler)
+	authGroup.POST("/wardrobe/add", hs.clothes.AddClothesToWardrobeHandler)
+	authGroup.GET("/wardrobe/duplicates", hs.clothes.WardrobeDuplicatesHandler)
+	authGroup.DELETE("/wardrobe/:clothId", hs.clothes.RemoveClothingFromWardrobeHandler)
+	authGroup.GET("/outfits", hs.outfit.ListOutfitsHandler)
+	authGroup.POST("/outfits", hs.outfit.CreateOutfitHandler)
+	authGroup.GET("/outfits/:outfitId", hs.outfit.GetOutfitHandler)
+	authGroup.DELETE("/outfits/:outfitId", hs.outfit.DeleteOutfitHandler)
+	authGroup.GET("/wear-log", hs.wearLog.WearCalendarHandler)
+	authGroup.POST("/wear-log", hs.wearLog.LogWearHandler)
+	authGroup.GET("/wear-log/stats", hs.wearLog.WearStatsHandler)
+	authGroup.DELETE("/wear-log/:entryId", hs.wearLog.DeleteWearEntryHandler)
+	authGroup.GET("/recommendations", hs.recommendation.RecommendOutfitsHandler)
+	authGroup.GET("/person-photos", hs.personPhoto.ListPersonPhotosHandler)
+	authGroup.POST("/person-photos", hs.personPhoto.UploadPersonPhotoHandler)
+	authGroup.PUT("/person-photos/:photoId/default", hs.personPhoto.SetDefaultPersonPhotoHandler)
+	authGroup.DELETE("/person-photos/:photoId", hs.personPhoto.DeletePersonPhotoHandler)
+	authGroup.POST("/virtual-tryon", hs.tryOn.VirtualTryOnHandler)
+	authGroup.POST("/virtual-tryon/mask-preview", hs.tryOn.MaskPreviewHandler)
+	authGroup.POST("/tryon-batches", hs.tryOn.StartTryOnBatchHandler)
+	authGroup.GET("/tryon-batches/:batchId", hs.tryOn.GetTryOnBatchHandler)
+	authGroup.GET("/tryon-batches/:batchId/contact-sheet", hs.tryOn.TryOnBatchContactSheetHandler)
+	authGroup.POST("/tryon-batches/:batchId/cancel", hs.tryOn.CancelTryOnBatchHandler)
+	authGroup.GET("/tryons", hs.tryOn.ListTryOnsHandler)
+	authGroup.GET("/tryons/:tryOnId", hs.tryOn.GetTryOnHandler)
+	authGroup.PUT("/tryons/:tryOnId/favorite", hs.tryOn.FavoriteTryOnHandler)
+	authGroup.DELETE("/tryons/:tryOnId", hs.tryOn.DeleteTryOnHandler)
+
+	// Admin routes, restricted to adminUserIDs
+	adminGroup := e.Group("/api/admin")
+	adminGroup.Use(middleware.AuthMiddleware(jwtSecret), middleware.AdminMiddleware(adminUserIDs))
+	adminGroup.DELETE("/tryon-cache", hs.tryOn.PurgeTryOnCacheHandler)
+}
@@ -0,0 +1,154 @@
+// file_store.go
+package outfits
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+)
+
+// FileStore keeps outfits in memory and persists them as a JSON file.
+type FileStore struct {
+	mu      sync.RWMutex
+	path    string
+	outfits map[string]Outfit
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+// Constructor for FileStore. An empty path keeps outfits in memory only.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		outfits: make(map[string]Outfit),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var outfits []Outfit
+	if err := jsonfile.Load(path, &outfits); err != nil {
+		return nil, fmt.Errorf("failed to load outfits: %w", err)
+	}
+	for _, outfit := range outfits {
+		s.outfits[outfit.ID] = outfit
+	}
+	return s, nil
+}
+
+func (s *FileStore) SaveOutfit(ctx context.Context, outfit Outfit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outfits[outfit.ID] = outfit
+	return s.persist()
+}
+
+func (s *FileStore) GetOutfit(ctx context.Context, userID, outfitID string) (Outfit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	outfit, ok := s.outfits[outfitID]
+	if !ok || outfit.UserID != userID {
+		return Outfit{}, ErrOutfitNotFound
+	}
+	return outfit, nil
+}
+
+func (s *FileStore) ListOutfits(ctx context.Context, userID string) ([]Outfit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	outfits := make([]Outfit, 0)
+	for _, outfit := range s.outfits {
+		if outfit.UserID == userID {
+			outfits = append(outfits, outfit)
+		}
+	}
+	sortOutfits(outfits)
+	return outfits, nil
+}
+
+func (s *FileStore) DeleteOutfit(ctx context.Context, userID, outfitID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	outfit, ok := s.outfits[outfitID]
+	if !ok || outfit.UserID != userID {
+		return ErrOutfitNotFound
+	}
+	delete(s.outfits, outfitID)
+	return s.persist()
+}
+
+func (s *FileStore) RemoveClothing(ctx context.Context, userID, clothingID string) ([]string, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := make([]string, 0)
+	deleted := make([]string, 0)
+	for id, outfit := range s.outfits {
+		if outfit.UserID != userID {
+			continue
+		}
+		items := make([]OutfitItem, 0, len(outfit.Items))
+		for _, item := range outfit.Items {
+			if item.ClothingID != clothingID {
+				items = append(items, item)
+			}
+		}
+		if len(items) == len(outfit.Items) {
+			continue
+		}
+		if len(items) == 0 {
+			delete(s.outfits, id)
+			deleted = append(deleted, id)
+			continue
+		}
+
+		outfit.Items = items
+		if outfit.CoverClothingID == clothingID {
+			// The handler fills in the new cover's image URL, since it
+			// lives in the wardrobe.
+			outfit.CoverClothingID = items[0].ClothingID
+			outfit.CoverImageURL = ""
+		}
+		outfit.UpdatedAt = time.Now().UTC()
+		s.outfits[id] = outfit
+		updated = append(updated, id)
+	}
+	if len(updated) == 0 && len(deleted) == 0 {
+		return updated, deleted, nil
+	}
+	sort.Strings(updated)
+	sort.Strings(deleted)
+	return updated, deleted, s.persist()
+}
+
+// persist writes all outfits to disk. Callers must hold the write lock.
+func (s *FileStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	outfits := make([]Outfit, 0, len(s.outfits))
+	for _, outfit := range s.outfits {
+		outfits = append(outfits, outfit)
+	}
+	sortOutfits(outfits)
+	return jsonfile.Save(s.path, outfits)
+}
+
+func sortOutfits(outfits []Outfit) {
+	sort.Slice(outfits, func(i, j int) bool {
+		if outfits[i].CreatedAt.Equal(outfits[j].CreatedAt) {
+			return outfits[i].ID < outfits[j].ID
+		}
+		return outfits[i].CreatedAt.Before(outfits[j].CreatedAt)
+	})
+}
@@ -0,0 +1,60 @@
+// outfits.go
+package outfits
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// ErrOutfitNotFound is returned when an outfit does not exist for the user.
+var ErrOutfitNotFound = errors.New("outfit not found")
+
+// Body slots an outfit item can fill, in display order. They share their
+// names with the garment categories.
+var Slots = internal.Categories
+
+// OutfitItem is a wardrobe item placed in a body slot of an outfit.
+type OutfitItem struct {
+	ClothingID string `json:"clothing_id"`
+	Slot       string `json:"slot"`
+}
+
+// Outfit is a named combination of wardrobe items.
+type Outfit struct {
+	ID              string       `json:"id"`
+	UserID          string       `json:"user_id"`
+	Name            string       `json:"name"`
+	Items           []OutfitItem `json:"items"`
+	CoverClothingID string       `json:"cover_clothing_id"`
+	CoverImageURL   string       `json:"cover_image_url"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// SortItems orders outfit items by body slot, keeping the relative order of
+// items sharing a slot.
+func SortItems(items []OutfitItem) {
+	slices.SortStableFunc(items, func(a, b OutfitItem) int {
+		return slices.Index(Slots, a.Slot) - slices.Index(Slots, b.Slot)
+	})
+}
+
+// Store defines the methods for persisting outfits.
+type Store interface {
+	// SaveOutfit inserts or replaces an outfit.
+	SaveOutfit(ctx context.Context, outfit Outfit) error
+	// GetOutfit returns a user's outfit by ID.
+	GetOutfit(ctx context.Context, userID, outfitID string) (Outfit, error)
+	// ListOutfits returns all outfits of a user, oldest first.
+	ListOutfits(ctx context.Context, userID string) ([]Outfit, error)
+	// DeleteOutfit removes a user's outfit by ID.
+	DeleteOutfit(ctx context.Context, userID, outfitID string) error
+	// RemoveClothing drops a wardrobe item from every outfit of the user.
+	// Outfits left without items are deleted. It returns the IDs of the
+	// updated and deleted outfits.
+	RemoveClothing(ctx context.Context, userID, clothingID string) (updated, deleted []string, err error)
+}
@@ -0,0 +1,153 @@
+package outfits
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+func TestSortItems(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		items []OutfitItem
+		want  []string
+	}{
+		{"empty", nil, nil},
+		{"slot order", []OutfitItem{
+			{"boots", internal.CategoryShoes}, {"coat", internal.CategoryOuterwear},
+			{"jeans", internal.CategoryBottom}, {"tee", internal.CategoryTop},
+		}, []string{"tee", "jeans", "boots", "coat"}},
+		{"shared slots keep their order", []OutfitItem{
+			{"scarf", internal.CategoryAccessory}, {"tee", internal.CategoryTop},
+			{"belt", internal.CategoryAccessory}, {"shirt", internal.CategoryTop},
+		}, []string{"tee", "shirt", "scarf", "belt"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			SortItems(tc.items)
+			var got []string
+			for _, item := range tc.items {
+				got = append(got, item.ClothingID)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("order = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func outfit(id, userID string, created int, clothingIDs ...string) Outfit {
+	o := Outfit{ID: id, UserID: userID, Name: id, CreatedAt: time.Unix(int64(created), 0).UTC()}
+	for _, clothingID := range clothingIDs {
+		o.Items = append(o.Items, OutfitItem{ClothingID: clothingID, Slot: internal.CategoryTop})
+	}
+	if len(clothingIDs) > 0 {
+		o.CoverClothingID = clothingIDs[0]
+		o.CoverImageURL = "https://cdn.example.com/" + clothingIDs[0]
+	}
+	return o
+}
+
+func TestFileStoreRemoveClothing(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		outfits  []Outfit
+		clothing string
+		updated  []string
+		deleted  []string
+		// covers are the cover clothing IDs of the outfits left
+		covers map[string]string
+	}{
+		{"unused item", []Outfit{outfit("o1", "u", 1, "a", "b")}, "z", []string{}, []string{}, map[string]string{"o1": "a"}},
+		{"dropped from outfits", []Outfit{outfit("o1", "u", 1, "b", "a"), outfit("o2", "u", 2, "c", "a")}, "a", []string{"o1", "o2"}, []string{}, map[string]string{"o1": "b", "o2": "c"}},
+		{"cover replaced", []Outfit{outfit("o1", "u", 1, "a", "b")}, "a", []string{"o1"}, []string{}, map[string]string{"o1": "b"}},
+		{"emptied outfit deleted", []Outfit{outfit("o1", "u", 1, "a"), outfit("o2", "u", 2, "a", "b")}, "a", []string{"o2"}, []string{"o1"}, map[string]string{"o2": "b"}},
+		{"other users untouched", []Outfit{outfit("o1", "other", 1, "a")}, "a", []string{}, []string{}, map[string]string{"o1": "a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "outfits.json")
+			store, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range tc.outfits {
+				if err := store.SaveOutfit(context.Background(), o); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			updated, deleted, err := store.RemoveClothing(context.Background(), "u", tc.clothing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(updated, tc.updated) || !slices.Equal(deleted, tc.deleted) {
+				t.Errorf("updated %v deleted %v, want %v and %v", updated, deleted, tc.updated, tc.deleted)
+			}
+
+			// The change is persisted
+			reloaded, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range tc.outfits {
+				got, err := reloaded.GetOutfit(context.Background(), o.UserID, o.ID)
+				cover, kept := tc.covers[o.ID]
+				if !kept {
+					if !errors.Is(err, ErrOutfitNotFound) {
+						t.Errorf("outfit %s kept, want it deleted", o.ID)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("outfit %s: %v", o.ID, err)
+				}
+				if got.CoverClothingID != cover {
+					t.Errorf("outfit %s cover = %s, want %s", o.ID, got.CoverClothingID, cover)
+				}
+				if cover != o.CoverClothingID && got.CoverImageURL != "" {
+					t.Errorf("outfit %s kept the old cover image", o.ID)
+				}
+				if slices.ContainsFunc(got.Items, func(item OutfitItem) bool { return item.ClothingID == tc.clothing && o.UserID == "u" }) {
+					t.Errorf("outfit %s still has %s", o.ID, tc.clothing)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreOwnership(t *testing.T) {
+	store, err := NewFileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, o := range []Outfit{outfit("late", "u", 3, "a"), outfit("early", "u", 1, "b"), outfit("theirs", "other", 2, "c")} {
+		if err := store.SaveOutfit(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := store.ListOutfits(ctx, "u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "early" || list[1].ID != "late" {
+		t.Errorf("list = %v, want early then late", list)
+	}
+	if _, err := store.GetOutfit(ctx, "u", "theirs"); !errors.Is(err, ErrOutfitNotFound) {
+		t.Errorf("got another user's outfit: %v", err)
+	}
+	if err := store.DeleteOutfit(ctx, "u", "theirs"); !errors.Is(err, ErrOutfitNotFound) {
+		t.Errorf("deleted another user's outfit: %v", err)
+	}
+	if err := store.DeleteOutfit(ctx, "u", "early"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetOutfit(ctx, "u", "early"); !errors.Is(err, ErrOutfitNotFound) {
+		t.Errorf("deleted outfit still found: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// file_store.go
+package photos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+)
+
+// FileStore keeps person photo records in memory and persists them as a
+// JSON file. The images themselves live in StorageService.
+type FileStore struct {
+	mu     sync.RWMutex
+	path   string
+	photos map[string]PersonPhoto
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+// Constructor for FileStore. An empty path keeps photos in memory only.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		photos: make(map[string]PersonPhoto),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var photos []PersonPhoto
+	if err := jsonfile.Load(path, &photos); err != nil {
+		return nil, fmt.Errorf("failed to load person photos: %w", err)
+	}
+	for _, photo := range photos {
+		s.photos[photo.ID] = photo
+	}
+	return s, nil
+}
+
+func (s *FileStore) SavePhoto(ctx context.Context, photo PersonPhoto) (PersonPhoto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.userPhotos(photo.UserID)) == 0 {
+		photo.IsDefault = true
+	}
+	if photo.IsDefault {
+		s.clearDefault(photo.UserID)
+	}
+	s.photos[photo.ID] = photo
+	return photo, s.persist()
+}
+
+func (s *FileStore) GetPhoto(ctx context.Context, userID, photoID string) (PersonPhoto, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	photo, ok := s.photos[photoID]
+	if !ok || photo.UserID != userID {
+		return PersonPhoto{}, ErrPhotoNotFound
+	}
+	return photo, nil
+}
+
+func (s *FileStore) GetDefaultPhoto(ctx context.Context, userID string) (PersonPhoto, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, photo := range s.photos {
+		if photo.UserID == userID && photo.IsDefault {
+			return photo, nil
+		}
+	}
+	return PersonPhoto{}, ErrPhotoNotFound
+}
+
+func (s *FileStore) ListPhotos(ctx context.Context, userID string) ([]PersonPhoto, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userPhotos(userID), nil
+}
+
+func (s *FileStore) SetDefaultPhoto(ctx context.Context, userID, photoID string) (PersonPhoto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	photo, ok := s.photos[photoID]
+	if !ok || photo.UserID != userID {
+		return PersonPhoto{}, ErrPhotoNotFound
+	}
+	s.clearDefault(userID)
+	photo.IsDefault = true
+	s.photos[photoID] = photo
+	return photo, s.persist()
+}
+
+func (s *FileStore) DeletePhoto(ctx context.Context, userID, photoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	photo, ok := s.photos[photoID]
+	if !ok || photo.UserID != userID {
+		return ErrPhotoNotFound
+	}
+	delete(s.photos, photoID)
+
+	if photo.IsDefault {
+		if remaining := s.userPhotos(userID); len(remaining) > 0 {
+			newest := remaining[len(remaining)-1]
+			newest.IsDefault = true
+			s.photos[newest.ID] = newest
+		}
+	}
+	return s.persist()
+}
+
+// userPhotos returns a user's photos, oldest first. Callers must hold the lock.
+func (s *FileStore) userPhotos(userID string) []PersonPhoto {
+	photos := make([]PersonPhoto, 0)
+	for _, photo := range s.photos {
+		if photo.UserID == userID {
+			photos = append(photos, photo)
+		}
+	}
+	sortPhotos(photos)
+	return photos
+}
+
+// clearDefault unsets the user's default photo. Callers must hold the write lock.
+func (s *FileStore) clearDefault(userID string) {
+	for id, photo := range s.photos {
+		if photo.UserID == userID && photo.IsDefault {
+			photo.IsDefault = false
+			s.photos[id] = photo
+		}
+	}
+}
+
+// persist writes all photos to disk. Callers must hold the write lock.
+func (s *FileStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	photos := make([]PersonPhoto, 0, len(s.photos))
+	for _, photo := range s.photos {
+		photos = append(photos, photo)
+	}
+	sortPhotos(photos)
+	return jsonfile.Save(s.path, photos)
+}
+
+func sortPhotos(photos []PersonPhoto) {
+	sort.Slice(photos, func(i, j int) bool {
+		if photos[i].CreatedAt.Equal(photos[j].CreatedAt) {
+			return photos[i].ID < photos[j].ID
+		}
+		return photos[i].CreatedAt.Before(photos[j].CreatedAt)
+	})
+}
@@ -0,0 +1,41 @@
+// photos.go
+package photos
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPhotoNotFound is returned when a person photo does not exist for the user.
+var ErrPhotoNotFound = errors.New("person photo not found")
+
+// PersonPhoto is a saved photo of the user used as the body in try-ons.
+type PersonPhoto struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	ImageURL    string    `json:"image_url"`
+	ObjectKey   string    `json:"object_key"`
+	ContentType string    `json:"content_type"`
+	IsDefault   bool      `json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store defines the methods for persisting person photos. A user with any
+// photos always has exactly one default photo.
+type Store interface {
+	// SavePhoto inserts a photo. The user's first photo becomes the default,
+	// as does any photo saved with IsDefault set.
+	SavePhoto(ctx context.Context, photo PersonPhoto) (PersonPhoto, error)
+	// GetPhoto returns a user's photo by ID.
+	GetPhoto(ctx context.Context, userID, photoID string) (PersonPhoto, error)
+	// GetDefaultPhoto returns the user's default photo.
+	GetDefaultPhoto(ctx context.Context, userID string) (PersonPhoto, error)
+	// ListPhotos returns all photos of a user, oldest first.
+	ListPhotos(ctx context.Context, userID string) ([]PersonPhoto, error)
+	// SetDefaultPhoto makes a photo the user's default.
+	SetDefaultPhoto(ctx context.Context, userID, photoID string) (PersonPhoto, error)
+	// DeletePhoto removes a photo. If it was the default, the newest
+	// remaining photo becomes the default.
+	DeletePhoto(ctx context.Context, userID, photoID string) error
+}
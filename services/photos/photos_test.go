@@ -0,0 +1,143 @@
+package photos
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// op is a step that changes the store.
+type op func(ctx context.Context, s *FileStore) error
+
+func save(id, userID string, day int, isDefault bool) op {
+	return func(ctx context.Context, s *FileStore) error {
+		_, err := s.SavePhoto(ctx, PersonPhoto{
+			ID:        id,
+			UserID:    userID,
+			IsDefault: isDefault,
+			CreatedAt: time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC),
+		})
+		return err
+	}
+}
+
+func setDefault(userID, id string) op {
+	return func(ctx context.Context, s *FileStore) error {
+		_, err := s.SetDefaultPhoto(ctx, userID, id)
+		return err
+	}
+}
+
+func remove(userID, id string) op {
+	return func(ctx context.Context, s *FileStore) error {
+		return s.DeletePhoto(ctx, userID, id)
+	}
+}
+
+// checkDefault checks that the user has exactly the wanted default photo,
+// or none when want is empty.
+func checkDefault(t *testing.T, s *FileStore, userID, want string) {
+	t.Helper()
+	ctx := context.Background()
+	list, err := s.ListPhotos(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var defaults []string
+	for _, photo := range list {
+		if photo.IsDefault {
+			defaults = append(defaults, photo.ID)
+		}
+	}
+	if want == "" {
+		if len(defaults) != 0 {
+			t.Errorf("%s has defaults %v, want none", userID, defaults)
+		}
+		if _, err := s.GetDefaultPhoto(ctx, userID); !errors.Is(err, ErrPhotoNotFound) {
+			t.Errorf("GetDefaultPhoto error = %v, want ErrPhotoNotFound", err)
+		}
+		return
+	}
+	if len(defaults) != 1 || defaults[0] != want {
+		t.Errorf("%s has defaults %v, want only %s", userID, defaults, want)
+	}
+	if got, err := s.GetDefaultPhoto(ctx, userID); err != nil || got.ID != want {
+		t.Errorf("GetDefaultPhoto = %s, %v, want %s", got.ID, err, want)
+	}
+}
+
+func TestFileStoreDefaultPhoto(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ops  []op
+		// want is alice's default photo after the ops
+		want string
+	}{
+		{"no photos", nil, ""},
+		{"first photo", []op{save("a1", "alice", 1, false)}, "a1"},
+		{"later photos keep the default", []op{save("a1", "alice", 1, false), save("a2", "alice", 2, false)}, "a1"},
+		{"saved as default", []op{save("a1", "alice", 1, false), save("a2", "alice", 2, true)}, "a2"},
+		{"set default", []op{save("a1", "alice", 1, false), save("a2", "alice", 2, false), setDefault("alice", "a2")}, "a2"},
+		{"deleting another photo", []op{save("a1", "alice", 1, false), save("a2", "alice", 2, false), remove("alice", "a2")}, "a1"},
+		{"deleting the default picks the newest", []op{
+			save("a1", "alice", 1, false), save("a3", "alice", 3, false), save("a2", "alice", 2, false), remove("alice", "a1"),
+		}, "a3"},
+		{"deleting the last photo", []op{save("a1", "alice", 1, false), remove("alice", "a1")}, ""},
+		// Another user's photos never change alice's default
+		{"other users", []op{
+			save("a1", "alice", 1, false), save("b1", "bob", 2, true), save("b2", "bob", 3, true), remove("bob", "b2"),
+		}, "a1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "photos.json")
+			s, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, op := range tc.ops {
+				if err := op(ctx, s); err != nil {
+					t.Fatalf("op %d: %v", i, err)
+				}
+			}
+			checkDefault(t, s, "alice", tc.want)
+
+			reloaded, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDefault(t, reloaded, "alice", tc.want)
+		})
+	}
+}
+
+func TestFileStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := save("a1", "alice", 1, false)(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		op   op
+	}{
+		{"get", func(ctx context.Context, s *FileStore) error { _, err := s.GetPhoto(ctx, "bob", "a1"); return err }},
+		{"set default", setDefault("bob", "a1")},
+		{"delete", remove("bob", "a1")},
+		{"missing", setDefault("alice", "a2")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.op(ctx, s); !errors.Is(err, ErrPhotoNotFound) {
+				t.Errorf("error = %v, want ErrPhotoNotFound", err)
+			}
+		})
+	}
+	checkDefault(t, s, "alice", "a1")
+	checkDefault(t, s, "bob", "")
+}
@@ -0,0 +1,254 @@
+// recommendation.go
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+var (
+	// ErrInvalidRequest is returned when the request fails validation.
+	ErrInvalidRequest = errors.New("invalid recommendation request")
+	// ErrWeatherUnavailable is returned when a location is given but no
+	// weather provider is configured.
+	ErrWeatherUnavailable = errors.New("weather provider not configured")
+	// ErrWeatherFailed is returned when the weather provider fails. It
+	// wraps the provider's error.
+	ErrWeatherFailed = errors.New("failed to get weather")
+)
+
+const (
+	// DefaultLimit is the number of outfits returned when none is requested.
+	DefaultLimit = 5
+	// MaxLimit caps the number of outfits returned.
+	MaxLimit = 20
+
+	// pairBeamWidth is how many of the best top/bottom pairs are extended
+	// with shoes and outerwear, which keeps large wardrobes cheap to score.
+	pairBeamWidth = 50
+	// maxItemReuse limits how often one top or bottom appears across the
+	// returned outfits so the suggestions stay varied. Shoes and outerwear
+	// are exempt since most wardrobes only have a few.
+	maxItemReuse = 2
+)
+
+// Request describes the outfits the user wants suggested. Occasion implies
+// a formality when none is given, and Weather implies a season.
+type Request struct {
+	Season    string
+	Formality string
+	Occasion  string
+	Location  string
+	Limit     int
+	// Weather is looked up from Location by Service.Recommend.
+	Weather *weather.Conditions
+}
+
+// RecommendedItem is a wardrobe item placed in a body slot.
+type RecommendedItem struct {
+	Slot string                `json:"slot"`
+	Item wardrobe.ClothingItem `json:"item"`
+}
+
+// Recommendation is a complete suggested outfit.
+type Recommendation struct {
+	Items   []RecommendedItem `json:"items"`
+	Score   float64           `json:"score"`
+	Reasons []string          `json:"reasons"`
+}
+
+// Result is a set of recommendations together with the effective request
+// values they were computed for.
+type Result struct {
+	Season          string              `json:"season,omitempty"`
+	Formality       string              `json:"formality,omitempty"`
+	Occasion        string              `json:"occasion,omitempty"`
+	Weather         *weather.Conditions `json:"weather,omitempty"`
+	Recommendations []Recommendation    `json:"recommendations"`
+}
+
+// Service composes outfits from a user's stored wardrobe.
+type Service struct {
+	wardrobe wardrobe.Store
+	weather  weather.Provider
+}
+
+// Constructor for Service. The weather provider may be nil, in which case
+// requests with a location are rejected.
+func NewService(store wardrobe.Store, provider weather.Provider) *Service {
+	return &Service{wardrobe: store, weather: provider}
+}
+
+// Validate checks the request values and fills in defaults.
+func (r *Request) Validate() error {
+	if r.Season != "" && !slices.Contains(internal.Seasons, r.Season) {
+		return fmt.Errorf("unknown season %q", r.Season)
+	}
+	if r.Formality != "" && !slices.Contains(internal.Formalities, r.Formality) {
+		return fmt.Errorf("unknown formality %q", r.Formality)
+	}
+	if r.Occasion != "" {
+		formality, ok := internal.OccasionFormality(r.Occasion)
+		if !ok {
+			return fmt.Errorf("unknown occasion %q", r.Occasion)
+		}
+		if r.Formality == "" {
+			r.Formality = formality
+		}
+	}
+	if r.Season == "" && r.Weather != nil {
+		r.Season = SeasonForTemperature(r.Weather.TemperatureC)
+	}
+	if r.Limit == 0 {
+		r.Limit = DefaultLimit
+	}
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	return nil
+}
+
+// Recommend validates the request and returns the best-ranked outfits for
+// the user, best first, looking up the weather when the request has a
+// location.
+func (s *Service) Recommend(ctx context.Context, userID string, req Request) (Result, error) {
+	if err := req.Validate(); err != nil {
+		return Result{}, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if req.Location != "" && req.Weather == nil {
+		if s.weather == nil {
+			return Result{}, ErrWeatherUnavailable
+		}
+		conditions, err := s.weather.Current(ctx, req.Location)
+		if err != nil {
+			return Result{}, fmt.Errorf("%w: %w", ErrWeatherFailed, err)
+		}
+		req.Weather = &conditions
+		if req.Season == "" {
+			req.Season = SeasonForTemperature(conditions.TemperatureC)
+		}
+	}
+	items, err := s.wardrobe.ListItems(ctx, userID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to load wardrobe: %w", err)
+	}
+	return Result{
+		Season:          req.Season,
+		Formality:       req.Formality,
+		Occasion:        req.Occasion,
+		Weather:         req.Weather,
+		Recommendations: Recommend(items, req),
+	}, nil
+}
+
+// Recommend builds outfits from the given items, which must come from a
+// validated request. Every outfit has a top and a bottom, adds shoes when
+// they help, and adds outerwear when the weather or season calls for it.
+func Recommend(items []wardrobe.ClothingItem, req Request) []Recommendation {
+	layers := layeringFor(req.Weather, req.Season)
+	bySlot := make(map[string][]wardrobe.ClothingItem)
+	for _, item := range items {
+		if !layers.allows(item) {
+			continue
+		}
+		if req.Season != "" && !item.Attributes.WornInSeason(req.Season) {
+			continue
+		}
+		if !matchesFormality(item, req.Formality) {
+			continue
+		}
+		bySlot[item.Attributes.Category] = append(bySlot[item.Attributes.Category], item)
+	}
+
+	// Score the core pairs first, then extend only the best of them.
+	var pairs []Recommendation
+	for _, top := range bySlot[internal.CategoryTop] {
+		for _, bottom := range bySlot[internal.CategoryBottom] {
+			pair := []RecommendedItem{
+				{Slot: internal.CategoryTop, Item: top},
+				{Slot: internal.CategoryBottom, Item: bottom},
+			}
+			score, reasons := scoreOutfit(pair, req)
+			pairs = append(pairs, Recommendation{Items: pair, Score: score, Reasons: reasons})
+		}
+	}
+	sortRecommendations(pairs)
+	if len(pairs) > pairBeamWidth {
+		pairs = pairs[:pairBeamWidth]
+	}
+
+	candidates := make([]Recommendation, 0, len(pairs))
+	for _, pair := range pairs {
+		outfit := pair
+		outfit = extend(outfit, internal.CategoryShoes, bySlot[internal.CategoryShoes], req, false)
+		outfit = extend(outfit, internal.CategoryOuterwear, bySlot[internal.CategoryOuterwear], req, layers.requireOuterwear)
+		layered := slices.ContainsFunc(outfit.Items, func(item RecommendedItem) bool { return item.Slot == internal.CategoryOuterwear })
+		if layers.requireOuterwear && !layered {
+			outfit.Reasons = append(outfit.Reasons, "no outerwear in the wardrobe for this weather")
+		} else {
+			outfit.Reasons = append(outfit.Reasons, layers.reasons...)
+		}
+		candidates = append(candidates, outfit)
+	}
+	sortRecommendations(candidates)
+
+	// Pick the best outfits while limiting how often the core garments
+	// repeat. The first two items are always the top and bottom.
+	uses := make(map[string]int)
+	results := make([]Recommendation, 0, req.Limit)
+	for _, candidate := range candidates {
+		if len(results) == req.Limit {
+			break
+		}
+		core := candidate.Items[:2]
+		if slices.ContainsFunc(core, func(item RecommendedItem) bool { return uses[item.Item.ID] >= maxItemReuse }) {
+			continue
+		}
+		for _, item := range core {
+			uses[item.Item.ID]++
+		}
+		results = append(results, candidate)
+	}
+	return results
+}
+
+// extend adds the best-scoring item of a slot to the outfit. Unless the slot
+// is required, the item is only added when it improves the score.
+func extend(outfit Recommendation, slot string, options []wardrobe.ClothingItem, req Request, required bool) Recommendation {
+	best := outfit
+	found := false
+	for _, option := range options {
+		items := append(slices.Clone(outfit.Items), RecommendedItem{Slot: slot, Item: option})
+		score, reasons := scoreOutfit(items, req)
+		if (required && !found) || score > best.Score {
+			best = Recommendation{Items: items, Score: score, Reasons: reasons}
+			found = true
+		}
+	}
+	return best
+}
+
+func sortRecommendations(recs []Recommendation) {
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].Score == recs[j].Score {
+			return outfitKey(recs[i]) < outfitKey(recs[j])
+		}
+		return recs[i].Score > recs[j].Score
+	})
+}
+
+func outfitKey(rec Recommendation) string {
+	ids := make([]string, len(rec.Items))
+	for i, item := range rec.Items {
+		ids[i] = item.Item.ID
+	}
+	return strings.Join(ids, ",")
+}
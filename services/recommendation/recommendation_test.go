@@ -0,0 +1,219 @@
+package recommendation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+func TestRequestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		req       Request
+		formality string
+		limit     int
+		wantErr   string
+	}{
+		{"defaults", Request{}, "", DefaultLimit, ""},
+		{"occasion implies formality", Request{Occasion: "work"}, internal.FormalityBusiness, DefaultLimit, ""},
+		{"explicit formality kept", Request{Occasion: "work", Formality: internal.FormalityCasual}, internal.FormalityCasual, DefaultLimit, ""},
+		{"limit kept", Request{Limit: MaxLimit}, "", MaxLimit, ""},
+		{"unknown season", Request{Season: "monsoon"}, "", 0, `unknown season "monsoon"`},
+		{"unknown formality", Request{Formality: "black tie"}, "", 0, `unknown formality "black tie"`},
+		{"unknown occasion", Request{Occasion: "heist"}, "", 0, `unknown occasion "heist"`},
+		{"limit too high", Request{Limit: MaxLimit + 1}, "", 0, "limit must be between"},
+		{"negative limit", Request{Limit: -1}, "", 0, "limit must be between"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			err := req.Validate()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Formality != tc.formality || req.Limit != tc.limit {
+				t.Errorf("formality %q limit %d, want %q and %d", req.Formality, req.Limit, tc.formality, tc.limit)
+			}
+		})
+	}
+}
+
+func TestColorHarmony(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"", internal.ColorRed, 0},
+		{internal.ColorBlack, internal.ColorBlack, 0.6},
+		{internal.ColorBlack, internal.ColorWhite, 0.8},
+		{internal.ColorNavy, internal.ColorRed, 1},
+		{internal.ColorRed, internal.ColorBeige, 1},
+		{internal.ColorGreen, internal.ColorGreen, 0.7},
+		{internal.ColorRed, internal.ColorOrange, 0.8},
+		{internal.ColorPink, internal.ColorRed, 0.8},
+		{internal.ColorYellow, internal.ColorBlue, 0.9},
+		{internal.ColorRed, internal.ColorGreen, -0.8},
+	} {
+		t.Run(tc.a+"+"+tc.b, func(t *testing.T) {
+			got, reason := colorHarmony(tc.a, tc.b)
+			if got != tc.want {
+				t.Errorf("score = %g, want %g", got, tc.want)
+			}
+			if (reason == "") != (tc.want == 0) {
+				t.Errorf("reason = %q", reason)
+			}
+			if swapped, _ := colorHarmony(tc.b, tc.a); swapped != got {
+				t.Errorf("score depends on order: %g and %g", got, swapped)
+			}
+		})
+	}
+}
+
+func TestMatchesFormality(t *testing.T) {
+	item := func(formality string) wardrobe.ClothingItem {
+		return wardrobe.ClothingItem{Attributes: internal.ClothingAttributes{Formality: formality}}
+	}
+	for _, tc := range []struct {
+		item, request string
+		want          bool
+	}{
+		{internal.FormalityFormal, "", true},
+		{internal.FormalityCasual, internal.FormalityCasual, true},
+		{internal.FormalitySmartCasual, internal.FormalityCasual, true},
+		{internal.FormalityAthletic, internal.FormalityCasual, true},
+		{internal.FormalityBusiness, internal.FormalityCasual, false},
+		{internal.FormalityAthletic, internal.FormalityFormal, false},
+		// Items without a formality count as casual
+		{"", internal.FormalitySmartCasual, true},
+		{"", internal.FormalityBusiness, false},
+	} {
+		if got := matchesFormality(item(tc.item), tc.request); got != tc.want {
+			t.Errorf("%q item for %q request = %v, want %v", tc.item, tc.request, got, tc.want)
+		}
+	}
+}
+
+func TestScoreOutfit(t *testing.T) {
+	casual := func(id, category, color string) RecommendedItem {
+		return RecommendedItem{Slot: category, Item: garment(id, category, "", color)}
+	}
+	formal := func(id, category, color string) RecommendedItem {
+		item := garment(id, category, "", color)
+		item.Attributes.Formality = internal.FormalityFormal
+		return RecommendedItem{Slot: category, Item: item}
+	}
+	for _, tc := range []struct {
+		name   string
+		items  []RecommendedItem
+		req    Request
+		want   float64
+		reason string
+	}{
+		// neutral pair 0.8 + consistent formality 1 + confidence 0.5
+		{"neutral base", []RecommendedItem{casual("t", internal.CategoryTop, internal.ColorWhite), casual("b", internal.CategoryBottom, internal.ColorNavy)}, Request{}, 2.3, "white and navy are a neutral base"},
+		// clash -0.8 + 1 + 0.5
+		{"clash", []RecommendedItem{casual("t", internal.CategoryTop, internal.ColorRed), casual("b", internal.CategoryBottom, internal.ColorGreen)}, Request{}, 0.7, "red and green clash"},
+		// six pairs, of which the navy ones ground an accent, - 0.5 for the
+		// third accent + 1 + 0.5
+		{"busy", []RecommendedItem{
+			casual("t", internal.CategoryTop, internal.ColorRed), casual("b", internal.CategoryBottom, internal.ColorNavy),
+			casual("s", internal.CategoryShoes, internal.ColorOrange), casual("c", internal.CategoryOuterwear, internal.ColorYellow),
+		}, Request{}, 1 + 0.8 + 0.8 + 1 + 1 + 0.8 - 0.5 + 1 + 0.5, "3 accent colors is busy"},
+		// 0.8 - 0.5*3 for the spread + 0.5
+		{"mixed formality", []RecommendedItem{formal("t", internal.CategoryTop, internal.ColorWhite), casual("b", internal.CategoryBottom, internal.ColorNavy)}, Request{}, -0.2, "mixes very different formality levels"},
+		// 0.8 + 1 + 1 for matching the request + 0.5
+		{"requested formality", []RecommendedItem{formal("t", internal.CategoryTop, internal.ColorWhite), formal("b", internal.CategoryBottom, internal.ColorNavy)}, Request{Formality: internal.FormalityFormal}, 3.3, "every piece is formal"},
+		{"season", []RecommendedItem{casual("t", internal.CategoryTop, internal.ColorWhite), casual("b", internal.CategoryBottom, internal.ColorNavy)}, Request{Season: internal.SeasonFall}, 2.3, "all items suit fall"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			score, reasons := scoreOutfit(tc.items, tc.req)
+			if fmt.Sprintf("%.2f", score) != fmt.Sprintf("%.2f", tc.want) {
+				t.Errorf("score = %g, want %g (reasons %v)", score, tc.want, reasons)
+			}
+			if !strings.Contains(strings.Join(reasons, "; "), tc.reason) {
+				t.Errorf("reasons %v do not mention %q", reasons, tc.reason)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	// One top goes with everything; without the reuse limit it would be in
+	// every outfit.
+	tops := []wardrobe.ClothingItem{
+		garment("white tee", internal.CategoryTop, "t-shirt", internal.ColorWhite),
+		garment("red tee", internal.CategoryTop, "t-shirt", internal.ColorRed),
+		garment("green tee", internal.CategoryTop, "t-shirt", internal.ColorGreen),
+	}
+	rest := []wardrobe.ClothingItem{
+		garment("navy jeans", internal.CategoryBottom, "jeans", internal.ColorNavy),
+		garment("black jeans", internal.CategoryBottom, "jeans", internal.ColorBlack),
+		garment("beige chinos", internal.CategoryBottom, "chinos", internal.ColorBeige),
+		garment("sneakers", internal.CategoryShoes, "", internal.ColorWhite),
+	}
+
+	for _, tc := range []struct {
+		name  string
+		items []wardrobe.ClothingItem
+		req   Request
+		count int
+	}{
+		{"limited", tops, Request{Limit: 2}, 2},
+		{"one top", tops[:1], Request{Limit: MaxLimit}, maxItemReuse},
+		// Each top and bottom may appear twice; the best pairs use up the
+		// black jeans and beige chinos, leaving the white tee one partner
+		{"reuse capped", tops, Request{Limit: MaxLimit}, 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			if err := req.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			recs := Recommend(slices.Concat(tc.items, rest), req)
+			if len(recs) != tc.count {
+				t.Fatalf("got %d outfits, want %d", len(recs), tc.count)
+			}
+			uses := make(map[string]int)
+			for i, rec := range recs {
+				if rec.Items[0].Slot != internal.CategoryTop || rec.Items[1].Slot != internal.CategoryBottom {
+					t.Errorf("outfit %d does not start with a top and a bottom", i)
+				}
+				if i > 0 && rec.Score > recs[i-1].Score {
+					t.Errorf("outfit %d scores above outfit %d", i, i-1)
+				}
+				for _, item := range rec.Items[:2] {
+					uses[item.Item.ID]++
+				}
+			}
+			for id, n := range uses {
+				if n > maxItemReuse {
+					t.Errorf("%s is in %d outfits, want at most %d", id, n, maxItemReuse)
+				}
+			}
+		})
+	}
+
+	// Scores only depend on the items, so the order is stable
+	items := slices.Concat(tops, rest)
+	req := Request{Limit: MaxLimit}
+	req.Validate()
+	first, second := Recommend(items, req), Recommend(items, req)
+	for i := range first {
+		if outfitKey(first[i]) != outfitKey(second[i]) {
+			t.Fatalf("outfit %d differs between runs", i)
+		}
+	}
+
+	if recs := Recommend(tops, req); len(recs) != 0 {
+		t.Errorf("got %d outfits without bottoms", len(recs))
+	}
+}
@@ -0,0 +1,174 @@
+// rules.go
+package recommendation
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+)
+
+// neutralColors pair with anything and keep an outfit from clashing.
+var neutralColors = []string{
+	internal.ColorBlack, internal.ColorWhite, internal.ColorGray,
+	internal.ColorBeige, internal.ColorBrown, internal.ColorNavy,
+}
+
+// colorHues places the chromatic families on the color wheel, in degrees.
+var colorHues = map[string]float64{
+	internal.ColorRed:    0,
+	internal.ColorOrange: 30,
+	internal.ColorYellow: 60,
+	internal.ColorGreen:  120,
+	internal.ColorBlue:   220,
+	internal.ColorPurple: 275,
+	internal.ColorPink:   330,
+}
+
+// colorHarmony scores how well two color families go together and explains
+// the verdict. Positive scores are harmonious, negative ones clash.
+func colorHarmony(a, b string) (float64, string) {
+	aNeutral, bNeutral := slices.Contains(neutralColors, a), slices.Contains(neutralColors, b)
+	switch {
+	case a == "" || b == "":
+		return 0, ""
+	case aNeutral && bNeutral:
+		if a == b {
+			return 0.6, fmt.Sprintf("tonal %s neutrals", a)
+		}
+		return 0.8, fmt.Sprintf("%s and %s are a neutral base", a, b)
+	case aNeutral || bNeutral:
+		return 1, fmt.Sprintf("%s grounds the %s", neutralOf(a, b), accentOf(a, b))
+	case a == b:
+		return 0.7, fmt.Sprintf("monochrome %s", a)
+	}
+
+	d := math.Abs(colorHues[a] - colorHues[b])
+	if d > 180 {
+		d = 360 - d
+	}
+	switch {
+	case d <= 60:
+		return 0.8, fmt.Sprintf("%s and %s are analogous", a, b)
+	case d >= 150:
+		return 0.9, fmt.Sprintf("%s and %s are complementary", a, b)
+	default:
+		return -0.8, fmt.Sprintf("%s and %s clash", a, b)
+	}
+}
+
+func neutralOf(a, b string) string {
+	if slices.Contains(neutralColors, a) {
+		return a
+	}
+	return b
+}
+
+func accentOf(a, b string) string {
+	if slices.Contains(neutralColors, a) {
+		return b
+	}
+	return a
+}
+
+// formalityLevel returns the position of an item's formality on the scale
+// from athletic to formal.
+func formalityLevel(item wardrobe.ClothingItem) int {
+	level := slices.Index(internal.Formalities, item.Attributes.Formality)
+	if level < 0 {
+		return slices.Index(internal.Formalities, internal.FormalityCasual)
+	}
+	return level
+}
+
+// matchesFormality reports whether an item is at most one step away from the
+// requested formality. An empty request matches everything.
+func matchesFormality(item wardrobe.ClothingItem, formality string) bool {
+	if formality == "" {
+		return true
+	}
+	target := slices.Index(internal.Formalities, formality)
+	d := formalityLevel(item) - target
+	return d >= -1 && d <= 1
+}
+
+// primaryColor returns the most dominant color family of an item.
+func primaryColor(item wardrobe.ClothingItem) string {
+	families := item.ColorFamilies()
+	if len(families) == 0 {
+		return ""
+	}
+	return families[0]
+}
+
+// scoreOutfit rates a full combination of items and lists the reasons for
+// the score.
+func scoreOutfit(items []RecommendedItem, req Request) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	// Color harmony between every pair of garments.
+	accents := make(map[string]bool)
+	for i := range items {
+		color := primaryColor(items[i].Item)
+		if color != "" && !slices.Contains(neutralColors, color) {
+			accents[color] = true
+		}
+		for j := i + 1; j < len(items); j++ {
+			s, reason := colorHarmony(color, primaryColor(items[j].Item))
+			score += s
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	if len(accents) > 2 {
+		score -= 0.5 * float64(len(accents)-2)
+		reasons = append(reasons, fmt.Sprintf("%d accent colors is busy", len(accents)))
+	}
+
+	// Formality should be consistent across the outfit and close to the
+	// requested level.
+	minLevel, maxLevel := math.MaxInt, math.MinInt
+	for _, item := range items {
+		level := formalityLevel(item.Item)
+		minLevel, maxLevel = min(minLevel, level), max(maxLevel, level)
+	}
+	switch spread := maxLevel - minLevel; {
+	case spread == 0:
+		score += 1
+		reasons = append(reasons, fmt.Sprintf("consistently %s", internal.Formalities[minLevel]))
+	case spread == 1:
+		score += 0.5
+	default:
+		score -= 0.5 * float64(spread)
+		reasons = append(reasons, "mixes very different formality levels")
+	}
+	if req.Formality != "" {
+		exact := 0
+		for _, item := range items {
+			if item.Item.Attributes.Formality == req.Formality {
+				exact++
+			}
+		}
+		score += float64(exact) / float64(len(items))
+		if exact == len(items) {
+			reasons = append(reasons, fmt.Sprintf("every piece is %s", req.Formality))
+		}
+	}
+
+	if req.Season != "" {
+		reasons = append(reasons, fmt.Sprintf("all items suit %s", req.Season))
+	}
+
+	// Trust well-classified garments a little more.
+	var confidence float64
+	for _, item := range items {
+		confidence += item.Item.Attributes.Confidence
+	}
+	score += 0.5 * confidence / float64(len(items))
+
+	return math.Round(score*100) / 100, reasons
+}
@@ -0,0 +1,90 @@
+// weather.go
+package recommendation
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+// Temperature thresholds in degrees Celsius used for layering.
+const (
+	hotTemperature  = 25
+	coolTemperature = 16
+	coldTemperature = 8
+)
+
+// warmWeatherPieces are too heavy to wear in hot weather.
+var warmWeatherPieces = []string{"sweater", "sweater vest", "hoodie", "sweatshirt"}
+
+// coldWeatherPieces leave too much skin exposed for cold weather.
+var coldWeatherPieces = []string{"shorts", "tank top", "crop top", "capris"}
+
+// layering holds the dressing rules derived from the weather.
+type layering struct {
+	requireOuterwear bool
+	excludeOuterwear bool
+	excluded         []string
+	reasons          []string
+}
+
+// SeasonForTemperature maps a temperature to the season whose clothes suit it.
+func SeasonForTemperature(temperatureC float64) string {
+	switch {
+	case temperatureC >= hotTemperature:
+		return internal.SeasonSummer
+	case temperatureC >= coolTemperature:
+		return internal.SeasonSpring
+	case temperatureC >= coldTemperature:
+		return internal.SeasonFall
+	default:
+		return internal.SeasonWinter
+	}
+}
+
+// layeringFor derives layering rules from weather conditions. Without
+// weather, cold seasons still call for outerwear.
+func layeringFor(conditions *weather.Conditions, season string) layering {
+	if conditions == nil {
+		if season == internal.SeasonFall || season == internal.SeasonWinter {
+			return layering{
+				requireOuterwear: true,
+				reasons:          []string{fmt.Sprintf("layered for %s", season)},
+			}
+		}
+		return layering{}
+	}
+
+	var l layering
+	t := conditions.TemperatureC
+	switch {
+	case t >= hotTemperature:
+		l.excludeOuterwear = true
+		l.excluded = warmWeatherPieces
+		l.reasons = append(l.reasons, fmt.Sprintf("light pieces for %.0f°C", t))
+	case t < coldTemperature:
+		l.requireOuterwear = true
+		l.excluded = coldWeatherPieces
+		l.reasons = append(l.reasons, fmt.Sprintf("warm layers for %.0f°C", t))
+	case t < coolTemperature:
+		l.requireOuterwear = true
+		l.reasons = append(l.reasons, fmt.Sprintf("a layer for %.0f°C", t))
+	}
+	if conditions.Rainy() {
+		l.requireOuterwear = true
+		l.excludeOuterwear = false
+		l.reasons = append(l.reasons, "outerwear for likely rain")
+	}
+	return l
+}
+
+// allows reports whether an item may be worn under the layering rules.
+func (l layering) allows(item wardrobe.ClothingItem) bool {
+	if l.excludeOuterwear && item.Attributes.Category == internal.CategoryOuterwear {
+		return false
+	}
+	return !slices.Contains(l.excluded, item.Attributes.Subcategory)
+}
@@ -0,0 +1,146 @@
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/services/wardrobe"
+	"github.com/zulfkhar00/instafit_mvp/services/weather"
+)
+
+// garment builds a wardrobe item of one color family.
+func garment(id, category, subcategory, color string, seasons ...string) wardrobe.ClothingItem {
+	return wardrobe.ClothingItem{
+		ID:     id,
+		UserID: "user",
+		Attributes: internal.ClothingAttributes{
+			Category:    category,
+			Subcategory: subcategory,
+			Colors:      []internal.DominantColor{{Family: color, Share: 1}},
+			Seasons:     seasons,
+			Formality:   internal.FormalityCasual,
+			Confidence:  1,
+		},
+	}
+}
+
+// weatherWardrobe has pieces for every kind of weather.
+var weatherWardrobe = []wardrobe.ClothingItem{
+	garment("tee", internal.CategoryTop, "t-shirt", internal.ColorWhite),
+	garment("tank", internal.CategoryTop, "tank top", internal.ColorBlue),
+	garment("sweater", internal.CategoryTop, "sweater", internal.ColorGray),
+	garment("jeans", internal.CategoryBottom, "jeans", internal.ColorNavy),
+	garment("shorts", internal.CategoryBottom, "shorts", internal.ColorBeige),
+	garment("coat", internal.CategoryOuterwear, "coat", internal.ColorBlack),
+}
+
+func newWeatherService(t *testing.T) *Service {
+	t.Helper()
+	store, err := wardrobe.NewFileStore(filepath.Join(t.TempDir(), "wardrobe.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range weatherWardrobe {
+		if err := store.SaveItem(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewService(store, &weather.StaticProvider{Locations: map[string]weather.Conditions{
+		"dubai":     {TemperatureC: 34},
+		"lisbon":    {TemperatureC: 20},
+		"london":    {TemperatureC: 12, PrecipitationChance: 0.8},
+		"oslo":      {TemperatureC: -3},
+		"hot edge":  {TemperatureC: hotTemperature},
+		"warm edge": {TemperatureC: hotTemperature - 0.5},
+	}})
+}
+
+func TestRecommendForWeather(t *testing.T) {
+	s := newWeatherService(t)
+	for _, tc := range []struct {
+		location string
+		season   string
+		// outerwear is whether every outfit has the coat
+		outerwear bool
+		// excluded items must not appear in any outfit
+		excluded []string
+	}{
+		{"Dubai", internal.SeasonSummer, false, []string{"sweater", "coat"}},
+		{"hot edge", internal.SeasonSummer, false, []string{"sweater", "coat"}},
+		{"warm edge", internal.SeasonSpring, false, nil},
+		{"Lisbon", internal.SeasonSpring, false, nil},
+		{"London", internal.SeasonFall, true, nil},
+		{"Oslo", internal.SeasonWinter, true, []string{"tank", "shorts"}},
+	} {
+		t.Run(tc.location, func(t *testing.T) {
+			result, err := s.Recommend(context.Background(), "user", Request{Location: tc.location})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Weather == nil || result.Weather.Location != tc.location {
+				t.Errorf("weather = %+v, want the conditions of %s", result.Weather, tc.location)
+			}
+			if result.Season != tc.season {
+				t.Errorf("season = %q, want %q", result.Season, tc.season)
+			}
+			if len(result.Recommendations) == 0 {
+				t.Fatal("no outfits recommended")
+			}
+			for _, rec := range result.Recommendations {
+				var ids []string
+				for _, item := range rec.Items {
+					ids = append(ids, item.Item.ID)
+				}
+				if tc.outerwear && !slices.Contains(ids, "coat") {
+					t.Errorf("outfit %v has no outerwear", ids)
+				}
+				for _, id := range tc.excluded {
+					if slices.Contains(ids, id) {
+						t.Errorf("outfit %v includes %s", ids, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRecommendWeatherErrors(t *testing.T) {
+	s := newWeatherService(t)
+	if _, err := s.Recommend(context.Background(), "user", Request{Location: "Atlantis"}); !errors.Is(err, weather.ErrUnknownLocation) {
+		t.Errorf("error = %v, want ErrUnknownLocation", err)
+	}
+	// Invalid requests are rejected before the weather is looked up
+	if _, err := s.Recommend(context.Background(), "user", Request{Location: "Atlantis", Season: "monsoon"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("error = %v, want ErrInvalidRequest", err)
+	}
+	s.weather = nil
+	if _, err := s.Recommend(context.Background(), "user", Request{Location: "Lisbon"}); !errors.Is(err, ErrWeatherUnavailable) {
+		t.Errorf("error = %v, want ErrWeatherUnavailable", err)
+	}
+	// Without a location the weather is not needed
+	if _, err := s.Recommend(context.Background(), "user", Request{}); err != nil {
+		t.Errorf("recommending without a location: %v", err)
+	}
+}
+
+// The season chosen for a temperature and the layering rules for it agree
+// at every threshold.
+func TestSeasonAndLayeringAgree(t *testing.T) {
+	for _, temperature := range []float64{
+		coldTemperature - 0.5, coldTemperature, coolTemperature - 0.5, coolTemperature,
+		hotTemperature - 0.5, hotTemperature,
+	} {
+		season := SeasonForTemperature(temperature)
+		l := layeringFor(&weather.Conditions{TemperatureC: temperature}, season)
+		if hot := season == internal.SeasonSummer; hot != l.excludeOuterwear {
+			t.Errorf("%g°C: season %s but outerwear excluded %v", temperature, season, l.excludeOuterwear)
+		}
+		if cold := season == internal.SeasonFall || season == internal.SeasonWinter; cold != l.requireOuterwear {
+			t.Errorf("%g°C: season %s but outerwear required %v", temperature, season, l.requireOuterwear)
+		}
+	}
+}
@@ -5,54 +5,52 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
-
-	"log"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	appconfig "github.com/zulfkhar00/instafit_mvp/internal/config"
 )
 
 type R2Service struct {
 	client     *s3.Client
 	bucketName string
+	// publicURL is the base URL uploaded blobs are served from.
+	publicURL string
 }
 
 // Ensure R2Service implements StorageService
 var _ StorageService = (*R2Service)(nil)
 
 // Constructor for R2Service
-func NewR2Service() (*R2Service, error) {
-	accountId := os.Getenv("R2_ACCOUNT_ID")
-	accessKeyId := os.Getenv("R2_ACCESS_KEY_ID")
-	accessKeySecret := os.Getenv("R2_SECRET_ACCESS_KEY")
-	bucket := os.Getenv("R2_BUCKET_NAME")
+func NewR2Service(r2 appconfig.R2) (*R2Service, error) {
 
 	customTransport := http.DefaultTransport.(*http.Transport).Clone()
 	customTransport.Proxy = nil
 
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKeyId, accessKeySecret, "")),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(r2.AccessKeyID, r2.SecretAccessKey, "")),
 		config.WithRegion("auto"),
 		config.WithHTTPClient(&http.Client{Transport: customTransport}),
 		config.WithRequestChecksumCalculation(0),
 		config.WithResponseChecksumValidation(0),
 	)
 	if err != nil {
-		log.Fatal(err)
-		return nil, err
+		return nil, fmt.Errorf("failed to load R2 client config: %w", err)
 	}
 
 	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", accountId))
+		o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", r2.AccountID))
 	})
 
 	return &R2Service{
 		client:     client,
-		bucketName: bucket,
+		bucketName: r2.Bucket,
+		publicURL:  strings.TrimRight(r2.PublicURL, "/"),
 	}, nil
 }
 
@@ -69,11 +67,28 @@ func (r *R2Service) UploadBlob(ctx context.Context, data []byte, filename, conte
 		return "", err
 	}
 
-	publicBaseURL := os.Getenv("R2_PUBLIC_URL")
-	url := fmt.Sprintf("%s/%s", publicBaseURL, filename)
+	url := fmt.Sprintf("%s/%s", r.publicURL, filename)
 	return url, nil
 }
 
+// GetBlob downloads a blob with a key from Cloudflare R2.
+func (s *R2Service) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
 // DeleteBlob deletes a blob with a key from Cloudflare R2.
 func (s *R2Service) DeleteBlob(ctx context.Context, key string) error {
 	input := &s3.DeleteObjectInput{
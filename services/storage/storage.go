@@ -7,6 +7,8 @@ import "context"
 type StorageService interface {
 	// UploadBlob uploads binary data and returns a URL or identifier.
 	UploadBlob(ctx context.Context, data []byte, filename, contentType string) (string, error)
+	// GetBlob downloads the blob stored under a key.
+	GetBlob(ctx context.Context, key string) ([]byte, error)
 	// DeleteBlob deletes a blob with a ket from the storage.
 	DeleteBlob(ctx context.Context, key string) error
 }
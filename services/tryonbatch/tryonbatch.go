@@ -0,0 +1,255 @@
+package tryonbatch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Retention defaults for finished batches.
+const (
+	DefaultMaxFinished = 100
+	DefaultRetention   = time.Hour
+)
+
+// ErrBatchNotFound is returned when a batch does not exist for the user.
+var ErrBatchNotFound = errors.New("try-on batch not found")
+
+// Batch and cell statuses.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	// StatusCancelled marks a cancelled batch and the cells that had not
+	// finished when it was cancelled.
+	StatusCancelled = "cancelled"
+)
+
+// Cell is one person × garment combination of a batch.
+type Cell struct {
+	Row           int    `json:"row"`
+	Col           int    `json:"col"`
+	PersonPhotoID string `json:"person_photo_id"`
+	ClothingID    string `json:"clothing_id"`
+	// Prompt is the mask prompt the cell was rendered with.
+	Prompt string `json:"prompt,omitempty"`
+	Status string `json:"status"`
+	// TryOnID, ImageURL and ObjectKey are set once the cell is saved to
+	// the try-on gallery.
+	TryOnID   string `json:"tryon_id,omitempty"`
+	ImageURL  string `json:"image_url,omitempty"`
+	ObjectKey string `json:"object_key,omitempty"`
+	CacheHit  bool   `json:"cache_hit"`
+	// Error is the error code of a failed cell, see apperr; the full
+	// error is only logged.
+	Error string `json:"error,omitempty"`
+}
+
+// Batch is a grid of try-ons: one row per person photo and one column per
+// garment.
+type Batch struct {
+	ID             string                 `json:"id"`
+	UserID         string                 `json:"user_id"`
+	PersonPhotoIDs []string               `json:"person_photo_ids"`
+	ClothingIDs    []string               `json:"clothing_ids"`
+	Parameters     map[string]interface{} `json:"parameters"`
+	// Debug records the debug artifacts of every cell.
+	Debug       bool       `json:"debug,omitempty"`
+	Status      string     `json:"status"`
+	Cells       []Cell     `json:"cells"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Cell returns a pointer to the cell at row, col.
+func (b *Batch) Cell(row, col int) *Cell {
+	return &b.Cells[row*len(b.ClothingIDs)+col]
+}
+
+// Tracker keeps the status of batches in memory. The rendered cells are
+// saved to the try-on gallery, so only the grid is lost on restart.
+// Finished batches are forgotten once they are older than the retention,
+// or beyond the newest maxFinished of them.
+type Tracker struct {
+	mu          sync.Mutex
+	batches     map[string]*Batch
+	cancels     map[string]context.CancelFunc
+	maxFinished int
+	retention   time.Duration
+	now         func() time.Time
+}
+
+// NewTracker creates an empty Tracker. Non-positive limits take the
+// defaults.
+func NewTracker(maxFinished int, retention time.Duration) *Tracker {
+	if maxFinished <= 0 {
+		maxFinished = DefaultMaxFinished
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Tracker{
+		batches:     make(map[string]*Batch),
+		cancels:     make(map[string]context.CancelFunc),
+		maxFinished: maxFinished,
+		retention:   retention,
+		now:         time.Now,
+	}
+}
+
+// NewBatch builds a queued batch with one cell per combination.
+func NewBatch(id, userID string, personPhotoIDs, clothingIDs []string, params map[string]interface{}) Batch {
+	batch := Batch{
+		ID:             id,
+		UserID:         userID,
+		PersonPhotoIDs: personPhotoIDs,
+		ClothingIDs:    clothingIDs,
+		Parameters:     params,
+		Status:         StatusQueued,
+		CreatedAt:      time.Now().UTC(),
+	}
+	for row, personPhotoID := range personPhotoIDs {
+		for col, clothingID := range clothingIDs {
+			batch.Cells = append(batch.Cells, Cell{
+				Row:           row,
+				Col:           col,
+				PersonPhotoID: personPhotoID,
+				ClothingID:    clothingID,
+				Status:        StatusQueued,
+			})
+		}
+	}
+	return batch
+}
+
+// Add starts tracking a batch. cancel stops the batch's renders.
+func (t *Tracker) Add(batch Batch, cancel context.CancelFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked()
+	t.batches[batch.ID] = &batch
+	t.cancels[batch.ID] = cancel
+}
+
+// pruneLocked forgets finished batches past the retention, then the
+// oldest finished batches beyond maxFinished. t.mu must be held.
+func (t *Tracker) pruneLocked() {
+	cutoff := t.now().UTC().Add(-t.retention)
+	var finished []*Batch
+	for id, batch := range t.batches {
+		if batch.CompletedAt == nil {
+			continue
+		}
+		if batch.CompletedAt.Before(cutoff) {
+			t.removeLocked(id)
+			continue
+		}
+		finished = append(finished, batch)
+	}
+	if len(finished) <= t.maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CompletedAt.Before(*finished[j].CompletedAt)
+	})
+	for _, batch := range finished[:len(finished)-t.maxFinished] {
+		t.removeLocked(batch.ID)
+	}
+}
+
+func (t *Tracker) removeLocked(batchID string) {
+	delete(t.batches, batchID)
+	delete(t.cancels, batchID)
+}
+
+// Cancel stops a user's batch. Queued cells are marked cancelled at once;
+// running cells are marked by the renderer once ComfyUI has stopped them.
+// Cancelling a finished batch changes nothing.
+func (t *Tracker) Cancel(userID, batchID string) (Batch, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	batch, ok := t.batches[batchID]
+	if !ok || batch.UserID != userID {
+		return Batch{}, ErrBatchNotFound
+	}
+	if batch.CompletedAt == nil {
+		t.cancels[batchID]()
+		batch.Status = StatusCancelled
+		for i := range batch.Cells {
+			if batch.Cells[i].Status == StatusQueued {
+				batch.Cells[i].Status = StatusCancelled
+			}
+		}
+		t.updateCompletionLocked(batch)
+	}
+	return copyBatch(batch), nil
+}
+
+// Get returns a copy of a user's batch.
+func (t *Tracker) Get(userID, batchID string) (Batch, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked()
+
+	batch, ok := t.batches[batchID]
+	if !ok || batch.UserID != userID {
+		return Batch{}, ErrBatchNotFound
+	}
+	return copyBatch(batch), nil
+}
+
+func copyBatch(batch *Batch) Batch {
+	copied := *batch
+	copied.Cells = append([]Cell(nil), batch.Cells...)
+	return copied
+}
+
+// UpdateCell applies update to a cell and recomputes the batch status: it
+// is running while any cell is, done once every cell has finished, and
+// failed if every cell failed. A cancelled batch stays cancelled.
+func (t *Tracker) UpdateCell(batchID string, row, col int, update func(*Cell)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	batch, ok := t.batches[batchID]
+	if !ok {
+		return
+	}
+	update(batch.Cell(row, col))
+	t.updateCompletionLocked(batch)
+}
+
+// updateCompletionLocked recomputes a batch's status. t.mu must be held.
+func (t *Tracker) updateCompletionLocked(batch *Batch) {
+	finished, failed := 0, 0
+	for _, cell := range batch.Cells {
+		switch cell.Status {
+		case StatusDone, StatusCancelled:
+			finished++
+		case StatusFailed:
+			finished++
+			failed++
+		}
+	}
+	if finished == len(batch.Cells) && batch.CompletedAt == nil {
+		now := t.now().UTC()
+		batch.CompletedAt = &now
+		// Release the batch's context
+		t.cancels[batch.ID]()
+	}
+	if batch.Status == StatusCancelled {
+		return
+	}
+	switch {
+	case finished < len(batch.Cells):
+		batch.Status = StatusRunning
+	case failed == len(batch.Cells):
+		batch.Status = StatusFailed
+	default:
+		batch.Status = StatusDone
+	}
+}
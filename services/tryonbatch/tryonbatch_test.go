@@ -0,0 +1,172 @@
+package tryonbatch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// finish adds a one-cell batch and marks its cell done at the given time.
+func finish(t *Tracker, id string, at time.Time) {
+	t.now = func() time.Time { return at }
+	t.Add(NewBatch(id, "user", []string{"p"}, []string{"c"}, nil), func() {})
+	t.UpdateCell(id, 0, 0, func(c *Cell) { c.Status = StatusDone })
+}
+
+func TestTrackerEvictsFinishedBatches(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name        string
+		maxFinished int
+		retention   time.Duration
+		// finished batches complete one minute apart, starting at start
+		finished int
+		// now is when the tracker is read
+		now  time.Duration
+		kept []string
+	}{
+		{"within limits", 5, time.Hour, 3, 3 * time.Minute, []string{"b0", "b1", "b2"}},
+		{"over the count", 2, time.Hour, 4, 4 * time.Minute, []string{"b2", "b3"}},
+		{"past the retention", 5, 10 * time.Minute, 3, 11*time.Minute + 30*time.Second, []string{"b2"}},
+		{"all expired", 5, time.Minute, 2, time.Hour, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewTracker(tc.maxFinished, tc.retention)
+			for i := 0; i < tc.finished; i++ {
+				finish(tracker, fmt.Sprintf("b%d", i), start.Add(time.Duration(i)*time.Minute))
+			}
+			tracker.now = func() time.Time { return start.Add(tc.now) }
+			// A running batch is never evicted
+			tracker.Add(NewBatch("running", "user", []string{"p"}, []string{"c"}, nil), func() {})
+
+			kept := make(map[string]bool)
+			for _, id := range tc.kept {
+				kept[id] = true
+			}
+			for i := 0; i < tc.finished; i++ {
+				id := fmt.Sprintf("b%d", i)
+				_, err := tracker.Get("user", id)
+				if kept[id] && err != nil {
+					t.Errorf("%s evicted: %v", id, err)
+				}
+				if !kept[id] && err != ErrBatchNotFound {
+					t.Errorf("%s kept, want it evicted", id)
+				}
+			}
+			if _, err := tracker.Get("user", "running"); err != nil {
+				t.Errorf("running batch evicted: %v", err)
+			}
+			if len(tracker.batches) != len(tracker.cancels) {
+				t.Errorf("%d batches but %d cancel funcs", len(tracker.batches), len(tracker.cancels))
+			}
+		})
+	}
+}
+
+func TestTrackerEvictsCancelledBatches(t *testing.T) {
+	tracker := NewTracker(1, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker.Add(NewBatch("cancelled", "user", []string{"p"}, []string{"c"}, nil), cancel)
+	if _, err := tracker.Cancel("user", "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("cancelling did not stop the batch's context")
+	}
+	finish(tracker, "newer", time.Now().Add(time.Minute))
+	if _, err := tracker.Get("user", "cancelled"); err != ErrBatchNotFound {
+		t.Errorf("cancelled batch kept beyond the count, err = %v", err)
+	}
+}
+
+func TestTrackerStatus(t *testing.T) {
+	// cellUpdate sets the status of the cell in a row of a 2×1 batch, or
+	// cancels the batch when row is -1.
+	type cellUpdate struct {
+		row    int
+		status string
+	}
+	cancelBatch := cellUpdate{row: -1}
+	for _, tc := range []struct {
+		name     string
+		updates  []cellUpdate
+		status   string
+		cells    []string
+		complete bool
+	}{
+		{"queued", nil, StatusQueued, []string{StatusQueued, StatusQueued}, false},
+		{"running", []cellUpdate{{0, StatusRunning}}, StatusRunning, []string{StatusRunning, StatusQueued}, false},
+		{"one done", []cellUpdate{{0, StatusDone}}, StatusRunning, []string{StatusDone, StatusQueued}, false},
+		{"done", []cellUpdate{{0, StatusDone}, {1, StatusDone}}, StatusDone, []string{StatusDone, StatusDone}, true},
+		{"partly failed", []cellUpdate{{0, StatusFailed}, {1, StatusDone}}, StatusDone, []string{StatusFailed, StatusDone}, true},
+		{"failed", []cellUpdate{{0, StatusFailed}, {1, StatusFailed}}, StatusFailed, []string{StatusFailed, StatusFailed}, true},
+		{"cancelled while queued", []cellUpdate{cancelBatch}, StatusCancelled, []string{StatusCancelled, StatusCancelled}, true},
+		// The running cell is left to the renderer, which marks it once
+		// ComfyUI has stopped
+		{"cancelled while running", []cellUpdate{{0, StatusRunning}, cancelBatch}, StatusCancelled, []string{StatusRunning, StatusCancelled}, false},
+		{"running cell stopped", []cellUpdate{{0, StatusRunning}, cancelBatch, {0, StatusCancelled}}, StatusCancelled, []string{StatusCancelled, StatusCancelled}, true},
+		{"running cell finished", []cellUpdate{{0, StatusRunning}, cancelBatch, {0, StatusDone}}, StatusCancelled, []string{StatusDone, StatusCancelled}, true},
+		{"cancelled when finished", []cellUpdate{{0, StatusDone}, {1, StatusDone}, cancelBatch}, StatusDone, []string{StatusDone, StatusDone}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewTracker(0, 0)
+			ctx, cancel := context.WithCancel(context.Background())
+			tracker.Add(NewBatch("b", "user", []string{"p0", "p1"}, []string{"c"}, nil), cancel)
+			for _, u := range tc.updates {
+				if u.row < 0 {
+					if _, err := tracker.Cancel("user", "b"); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				tracker.UpdateCell("b", u.row, 0, func(c *Cell) { c.Status = u.status })
+			}
+
+			batch, err := tracker.Get("user", "b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if batch.Status != tc.status {
+				t.Errorf("status = %s, want %s", batch.Status, tc.status)
+			}
+			for i, want := range tc.cells {
+				if batch.Cells[i].Status != want {
+					t.Errorf("cell %d is %s, want %s", i, batch.Cells[i].Status, want)
+				}
+			}
+			if (batch.CompletedAt != nil) != tc.complete {
+				t.Errorf("completed at %v, want complete %v", batch.CompletedAt, tc.complete)
+			}
+			// A finished batch releases its context
+			if tc.complete && ctx.Err() == nil {
+				t.Error("finished batch did not release its context")
+			}
+		})
+	}
+}
+
+func TestTrackerOwnership(t *testing.T) {
+	tracker := NewTracker(0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker.Add(NewBatch("b", "alice", []string{"p"}, []string{"c"}, nil), cancel)
+
+	if _, err := tracker.Get("bob", "b"); err != ErrBatchNotFound {
+		t.Errorf("Get error = %v, want ErrBatchNotFound", err)
+	}
+	if _, err := tracker.Cancel("bob", "b"); err != ErrBatchNotFound {
+		t.Errorf("Cancel error = %v, want ErrBatchNotFound", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("another user cancelled the batch")
+	}
+	// Updates to unknown batches are ignored
+	tracker.UpdateCell("missing", 0, 0, func(c *Cell) { t.Error("update ran for a missing batch") })
+
+	// Get returns a copy
+	batch, _ := tracker.Get("alice", "b")
+	batch.Cells[0].Status = StatusDone
+	if batch, _ := tracker.Get("alice", "b"); batch.Cells[0].Status != StatusQueued {
+		t.Error("changing a returned batch changed the tracker")
+	}
+}
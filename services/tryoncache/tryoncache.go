@@ -0,0 +1,179 @@
+// tryoncache.go
+package tryoncache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+	"github.com/zulfkhar00/instafit_mvp/services/storage"
+)
+
+// CachePrefix is the storage prefix for cached try-on renders.
+const CachePrefix = "tryon-cache"
+
+// Entry is a cached render indexed by the hash of its inputs.
+type Entry struct {
+	Key       string    `json:"key"`
+	ObjectKey string    `json:"object_key"`
+	Hits      int       `json:"hits"`
+	CreatedAt time.Time `json:"created_at"`
+	LastHitAt time.Time `json:"last_hit_at,omitempty"`
+}
+
+// Cache stores rendered try-on images in StorageService under a content
+// hash of everything that determines the render. The index of entries is
+// kept in a JSON file so the cache can be listed and purged.
+type Cache struct {
+	mu      sync.Mutex
+	storage storage.StorageService
+	path    string
+	entries map[string]Entry
+}
+
+// Constructor for Cache. An empty path keeps the index in memory only.
+func NewCache(storageSvc storage.StorageService, path string) (*Cache, error) {
+	c := &Cache{
+		storage: storageSvc,
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	var entries []Entry
+	if err := jsonfile.Load(path, &entries); err != nil {
+		return nil, fmt.Errorf("failed to load try-on cache index: %w", err)
+	}
+	for _, entry := range entries {
+		c.entries[entry.Key] = entry
+	}
+	return c, nil
+}
+
+// Key hashes the inputs of a render: both images, the workflow template
+// and the generation parameters. Each part is length-prefixed so that
+// moving bytes between parts changes the key.
+func Key(personImage, garmentImage, workflow []byte, params map[string]interface{}) (string, error) {
+	// encoding/json sorts map keys, so equal parameters encode equally.
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode parameters: %w", err)
+	}
+
+	h := sha256.New()
+	for _, part := range [][]byte{personImage, garmentImage, workflow, encodedParams} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(part)))
+		h.Write(size[:])
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get returns the cached render for a key, if any. A cached entry whose
+// blob can no longer be read is dropped and reported as a miss.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := c.storage.GetBlob(ctx, entry.ObjectKey)
+	if err != nil {
+		log.Printf("Error reading cached try-on %s: %v", key, err)
+		c.mu.Lock()
+		delete(c.entries, key)
+		if err := c.persist(); err != nil {
+			log.Printf("Error saving try-on cache index: %v", err)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		entry.Hits++
+		entry.LastHitAt = time.Now().UTC()
+		c.entries[key] = entry
+		if err := c.persist(); err != nil {
+			log.Printf("Error saving try-on cache index: %v", err)
+		}
+	}
+	return data, true
+}
+
+// Put stores a render under a key.
+func (c *Cache) Put(ctx context.Context, key string, data []byte) error {
+	objectKey := fmt.Sprintf("%s/%s%s", CachePrefix, key, internal.ImageExtension(data))
+	if _, err := c.storage.UploadBlob(ctx, data, objectKey, http.DetectContentType(data)); err != nil {
+		return fmt.Errorf("failed to upload cached try-on: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = Entry{
+		Key:       key,
+		ObjectKey: objectKey,
+		CreatedAt: time.Now().UTC(),
+	}
+	return c.persist()
+}
+
+// Purge deletes cached renders created before the cutoff, or all of them
+// when the cutoff is zero. It returns the number of entries removed. The
+// entries are dropped from the index before their blobs are deleted, so a
+// blob that fails to delete is only orphaned in storage.
+func (c *Cache) Purge(ctx context.Context, before time.Time) (int, error) {
+	c.mu.Lock()
+	var purged []Entry
+	for key, entry := range c.entries {
+		if !before.IsZero() && !entry.CreatedAt.Before(before) {
+			continue
+		}
+		delete(c.entries, key)
+		purged = append(purged, entry)
+	}
+	firstErr := c.persist()
+	c.mu.Unlock()
+
+	// Get treats an entry whose blob is gone as a miss, so the blobs can
+	// go even if the index could not be saved
+	for _, entry := range purged {
+		if err := c.storage.DeleteBlob(ctx, entry.ObjectKey); err != nil {
+			log.Printf("Error deleting cached try-on %s: %v", entry.Key, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return len(purged), firstErr
+}
+
+// persist writes the index to disk. Callers must hold the lock.
+func (c *Cache) persist() error {
+	if c.path == "" {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return jsonfile.Save(c.path, entries)
+}
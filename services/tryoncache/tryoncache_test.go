@@ -0,0 +1,162 @@
+package tryoncache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStorage is an in-memory storage.StorageService. onDelete, if set,
+// runs inside DeleteBlob.
+type memStorage struct {
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	failing  map[string]bool
+	onDelete func()
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{blobs: make(map[string][]byte), failing: make(map[string]bool)}
+}
+
+func (s *memStorage) UploadBlob(ctx context.Context, data []byte, key, contentType string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = data
+	return "https://cdn.example.com/" + key, nil
+}
+
+func (s *memStorage) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, errors.New("blob not found")
+	}
+	return data, nil
+}
+
+func (s *memStorage) DeleteBlob(ctx context.Context, key string) error {
+	if s.onDelete != nil {
+		s.onDelete()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing[key] {
+		return errors.New("delete failed")
+	}
+	delete(s.blobs, key)
+	return nil
+}
+
+func TestCachePurge(t *testing.T) {
+	now := time.Now().UTC()
+	for _, tc := range []struct {
+		name    string
+		before  time.Time
+		failing []string
+		purged  int
+		kept    []string
+		wantErr bool
+	}{
+		{"everything", time.Time{}, nil, 3, nil, false},
+		{"older than the cutoff", now.Add(-90 * time.Minute), nil, 1, []string{"new", "mid"}, false},
+		{"failed deletes", time.Time{}, []string{"old"}, 3, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemStorage()
+			path := filepath.Join(t.TempDir(), "cache.json")
+			cache, err := NewCache(store, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, age := range map[string]time.Duration{"new": 0, "mid": time.Hour, "old": 2 * time.Hour} {
+				if err := cache.Put(context.Background(), key, []byte(key)); err != nil {
+					t.Fatal(err)
+				}
+				entry := cache.entries[key]
+				entry.CreatedAt = now.Add(-age)
+				cache.entries[key] = entry
+			}
+			for _, key := range tc.failing {
+				store.failing[cache.entries[key].ObjectKey] = true
+			}
+			// The index lock must not be held across the storage calls
+			store.onDelete = func() {
+				if !cache.mu.TryLock() {
+					t.Error("DeleteBlob called with the cache locked")
+					return
+				}
+				cache.mu.Unlock()
+			}
+
+			purged, err := cache.Purge(context.Background(), tc.before)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if purged != tc.purged {
+				t.Errorf("purged = %d, want %d", purged, tc.purged)
+			}
+
+			reloaded, err := NewCache(store, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reloaded.entries) != len(tc.kept) {
+				t.Errorf("index has %d entries after reload, want %v", len(reloaded.entries), tc.kept)
+			}
+			for _, key := range tc.kept {
+				if _, ok := cache.Get(context.Background(), key); !ok {
+					t.Errorf("%s purged, want it kept", key)
+				}
+			}
+			if len(store.blobs) != len(tc.kept)+len(tc.failing) {
+				t.Errorf("%d blobs left, want %d", len(store.blobs), len(tc.kept)+len(tc.failing))
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	base, err := Key([]byte("person"), []byte("garment"), []byte("workflow"), map[string]interface{}{"seed": 1, "steps": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(base) != 64 {
+		t.Errorf("key %q is not a hex SHA-256", base)
+	}
+
+	for _, tc := range []struct {
+		name                      string
+		person, garment, workflow string
+		params                    map[string]interface{}
+		same                      bool
+	}{
+		{"same inputs", "person", "garment", "workflow", map[string]interface{}{"seed": 1, "steps": 20}, true},
+		{"parameter order", "person", "garment", "workflow", map[string]interface{}{"steps": 20, "seed": 1}, true},
+		{"person image", "person2", "garment", "workflow", map[string]interface{}{"seed": 1, "steps": 20}, false},
+		{"garment image", "person", "garment2", "workflow", map[string]interface{}{"seed": 1, "steps": 20}, false},
+		{"workflow", "person", "garment", "workflow2", map[string]interface{}{"seed": 1, "steps": 20}, false},
+		{"parameter value", "person", "garment", "workflow", map[string]interface{}{"seed": 2, "steps": 20}, false},
+		{"extra parameter", "person", "garment", "workflow", map[string]interface{}{"seed": 1, "steps": 20, "cfg": 7}, false},
+		// Without length prefixes these would hash the same bytes
+		{"bytes moved between images", "personga", "rment", "workflow", map[string]interface{}{"seed": 1, "steps": 20}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := Key([]byte(tc.person), []byte(tc.garment), []byte(tc.workflow), tc.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (key == base) != tc.same {
+				t.Errorf("key equal to the base key = %v, want %v", key == base, tc.same)
+			}
+		})
+	}
+
+	if _, err := Key(nil, nil, nil, map[string]interface{}{"bad": func() {}}); err == nil {
+		t.Error("parameters that cannot be encoded were hashed")
+	}
+}
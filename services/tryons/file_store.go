@@ -0,0 +1,125 @@
+// file_store.go
+package tryons
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+)
+
+// FileStore keeps try-on records in memory and persists them as a JSON
+// file. The rendered images themselves live in StorageService.
+type FileStore struct {
+	mu     sync.RWMutex
+	path   string
+	tryOns map[string]TryOn
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+// Constructor for FileStore. An empty path keeps try-ons in memory only.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		tryOns: make(map[string]TryOn),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var tryOns []TryOn
+	if err := jsonfile.Load(path, &tryOns); err != nil {
+		return nil, fmt.Errorf("failed to load try-ons: %w", err)
+	}
+	for _, tryOn := range tryOns {
+		s.tryOns[tryOn.ID] = tryOn
+	}
+	return s, nil
+}
+
+func (s *FileStore) SaveTryOn(ctx context.Context, tryOn TryOn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tryOns[tryOn.ID] = tryOn
+	return s.persist()
+}
+
+func (s *FileStore) GetTryOn(ctx context.Context, userID, tryOnID string) (TryOn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tryOn, ok := s.tryOns[tryOnID]
+	if !ok || tryOn.UserID != userID {
+		return TryOn{}, ErrTryOnNotFound
+	}
+	return tryOn, nil
+}
+
+func (s *FileStore) ListTryOns(ctx context.Context, userID string, favoritesOnly bool) ([]TryOn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tryOns := make([]TryOn, 0)
+	for _, tryOn := range s.tryOns {
+		if tryOn.UserID != userID || (favoritesOnly && !tryOn.Favorite) {
+			continue
+		}
+		tryOns = append(tryOns, tryOn)
+	}
+	sortTryOns(tryOns)
+	return tryOns, nil
+}
+
+func (s *FileStore) SetFavorite(ctx context.Context, userID, tryOnID string, favorite bool) (TryOn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tryOn, ok := s.tryOns[tryOnID]
+	if !ok || tryOn.UserID != userID {
+		return TryOn{}, ErrTryOnNotFound
+	}
+	tryOn.Favorite = favorite
+	s.tryOns[tryOnID] = tryOn
+	return tryOn, s.persist()
+}
+
+func (s *FileStore) DeleteTryOn(ctx context.Context, userID, tryOnID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tryOn, ok := s.tryOns[tryOnID]
+	if !ok || tryOn.UserID != userID {
+		return ErrTryOnNotFound
+	}
+	delete(s.tryOns, tryOnID)
+	return s.persist()
+}
+
+// persist writes all try-ons to disk. Callers must hold the write lock.
+func (s *FileStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	tryOns := make([]TryOn, 0, len(s.tryOns))
+	for _, tryOn := range s.tryOns {
+		tryOns = append(tryOns, tryOn)
+	}
+	sortTryOns(tryOns)
+	return jsonfile.Save(s.path, tryOns)
+}
+
+// sortTryOns orders try-ons newest first, as the gallery shows them.
+func sortTryOns(tryOns []TryOn) {
+	sort.Slice(tryOns, func(i, j int) bool {
+		if tryOns[i].CreatedAt.Equal(tryOns[j].CreatedAt) {
+			return tryOns[i].ID < tryOns[j].ID
+		}
+		return tryOns[i].CreatedAt.After(tryOns[j].CreatedAt)
+	})
+}
@@ -0,0 +1,45 @@
+// tryons.go
+package tryons
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTryOnNotFound is returned when a try-on does not exist for the user.
+var ErrTryOnNotFound = errors.New("try-on not found")
+
+// TryOn is a rendered try-on result kept in the user's gallery, together
+// with the inputs it was made from.
+type TryOn struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	ImageURL  string `json:"image_url"`
+	ObjectKey string `json:"object_key"`
+	// PersonPhotoID is empty when the person image was uploaded ad hoc.
+	PersonPhotoID string `json:"person_photo_id,omitempty"`
+	// ClothingID is empty when the garment image was uploaded ad hoc.
+	ClothingID string                 `json:"clothing_id,omitempty"`
+	Workflow   string                 `json:"workflow"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// CacheHit is set when the render was served from the try-on cache.
+	CacheHit  bool      `json:"cache_hit"`
+	Favorite  bool      `json:"favorite"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store defines the methods for persisting try-on results.
+type Store interface {
+	// SaveTryOn inserts or replaces a try-on.
+	SaveTryOn(ctx context.Context, tryOn TryOn) error
+	// GetTryOn returns a user's try-on by ID.
+	GetTryOn(ctx context.Context, userID, tryOnID string) (TryOn, error)
+	// ListTryOns returns a user's try-ons, newest first, optionally only
+	// the favorites.
+	ListTryOns(ctx context.Context, userID string, favoritesOnly bool) ([]TryOn, error)
+	// SetFavorite marks or unmarks a try-on as favorite.
+	SetFavorite(ctx context.Context, userID, tryOnID string, favorite bool) (TryOn, error)
+	// DeleteTryOn removes a user's try-on by ID.
+	DeleteTryOn(ctx context.Context, userID, tryOnID string) error
+}
@@ -0,0 +1,119 @@
+package tryons
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// gallery saves try-ons for alice and bob into a store persisted in a temp
+// directory. The names say who owns a try-on and the day it was made.
+func gallery(t *testing.T) (*FileStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tryons.json")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tryOn := range []TryOn{
+		{ID: "alice-1", UserID: "alice", CreatedAt: day(1)},
+		{ID: "alice-3", UserID: "alice", CreatedAt: day(3), Favorite: true},
+		{ID: "alice-2", UserID: "alice", CreatedAt: day(2)},
+		{ID: "alice-2b", UserID: "alice", CreatedAt: day(2)},
+		{ID: "bob-4", UserID: "bob", CreatedAt: day(4), Favorite: true},
+	} {
+		if err := s.SaveTryOn(context.Background(), tryOn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, path
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)
+}
+
+func ids(tryOns []TryOn) []string {
+	var ids []string
+	for _, tryOn := range tryOns {
+		ids = append(ids, tryOn.ID)
+	}
+	return ids
+}
+
+func TestFileStoreList(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		change        func(ctx context.Context, s *FileStore) error
+		userID        string
+		favoritesOnly bool
+		want          []string
+	}{
+		{"newest first", nil, "alice", false, []string{"alice-3", "alice-2", "alice-2b", "alice-1"}},
+		{"favorites", nil, "alice", true, []string{"alice-3"}},
+		{"other user", nil, "bob", false, []string{"bob-4"}},
+		{"unknown user", nil, "carol", false, nil},
+		{"favorited", func(ctx context.Context, s *FileStore) error {
+			_, err := s.SetFavorite(ctx, "alice", "alice-1", true)
+			return err
+		}, "alice", true, []string{"alice-3", "alice-1"}},
+		{"unfavorited", func(ctx context.Context, s *FileStore) error {
+			_, err := s.SetFavorite(ctx, "alice", "alice-3", false)
+			return err
+		}, "alice", true, nil},
+		{"deleted", func(ctx context.Context, s *FileStore) error {
+			return s.DeleteTryOn(ctx, "alice", "alice-2")
+		}, "alice", false, []string{"alice-3", "alice-2b", "alice-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, path := gallery(t)
+			if tc.change != nil {
+				if err := tc.change(ctx, s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reloaded, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, store := range []*FileStore{s, reloaded} {
+				got, err := store.ListTryOns(ctx, tc.userID, tc.favoritesOnly)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(ids(got), tc.want) {
+					t.Errorf("try-ons = %v, want %v", ids(got), tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+	s, _ := gallery(t)
+	for _, tc := range []struct {
+		name string
+		op   func() error
+	}{
+		{"get", func() error { _, err := s.GetTryOn(ctx, "bob", "alice-1"); return err }},
+		{"favorite", func() error { _, err := s.SetFavorite(ctx, "bob", "alice-1", true); return err }},
+		{"delete", func() error { return s.DeleteTryOn(ctx, "bob", "alice-1") }},
+		{"missing", func() error { _, err := s.GetTryOn(ctx, "alice", "alice-9"); return err }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.op(); !errors.Is(err, ErrTryOnNotFound) {
+				t.Errorf("error = %v, want ErrTryOnNotFound", err)
+			}
+		})
+	}
+
+	tryOn, err := s.GetTryOn(ctx, "alice", "alice-1")
+	if err != nil || tryOn.Favorite {
+		t.Errorf("alice-1 = %+v, %v, want it unchanged", tryOn, err)
+	}
+}
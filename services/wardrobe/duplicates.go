@@ -0,0 +1,75 @@
+// duplicates.go
+package wardrobe
+
+import "github.com/zulfkhar00/instafit_mvp/internal"
+
+// DuplicateGroup is a set of wardrobe items that look like the same garment.
+type DuplicateGroup struct {
+	Items       []ClothingItem `json:"items"`
+	MaxDistance int            `json:"max_distance"`
+}
+
+// FindDuplicate returns the closest item whose perceptual hash is within
+// maxDistance of hash, and whether one was found.
+func FindDuplicate(items []ClothingItem, hash uint64, maxDistance int) (ClothingItem, int, bool) {
+	var best ClothingItem
+	bestDistance := -1
+	for _, item := range items {
+		d := internal.HammingDistance(item.PerceptualHash, hash)
+		if d <= maxDistance && (bestDistance < 0 || d < bestDistance) {
+			best, bestDistance = item, d
+		}
+	}
+	return best, bestDistance, bestDistance >= 0
+}
+
+// GroupDuplicates clusters items whose hashes are transitively within
+// maxDistance of each other. Items without a duplicate are left out.
+func GroupDuplicates(items []ClothingItem, maxDistance int) []DuplicateGroup {
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			if internal.HammingDistance(items[i].PerceptualHash, items[j].PerceptualHash) <= maxDistance {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range items {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	for _, root := range roots {
+		idx := members[root]
+		if len(idx) < 2 {
+			continue
+		}
+		group := DuplicateGroup{Items: make([]ClothingItem, 0, len(idx))}
+		for a, i := range idx {
+			group.Items = append(group.Items, items[i])
+			for _, j := range idx[a+1:] {
+				group.MaxDistance = max(group.MaxDistance, internal.HammingDistance(items[i].PerceptualHash, items[j].PerceptualHash))
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
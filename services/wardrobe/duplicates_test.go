@@ -0,0 +1,77 @@
+package wardrobe
+
+import (
+	"slices"
+	"testing"
+)
+
+func hashed(id string, hash uint64) ClothingItem {
+	return ClothingItem{ID: id, PerceptualHash: hash}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	items := []ClothingItem{hashed("far", 0xff), hashed("near", 0b1), hashed("exact", 0)}
+	for _, tc := range []struct {
+		name        string
+		items       []ClothingItem
+		hash        uint64
+		maxDistance int
+		want        string
+		distance    int
+	}{
+		{"closest wins", items, 0, 10, "exact", 0},
+		{"within distance", items[:2], 0b11, 1, "near", 1},
+		{"none close enough", items[:1], 0, 7, "", -1},
+		{"no items", nil, 0, 10, "", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			item, d, ok := FindDuplicate(tc.items, tc.hash, tc.maxDistance)
+			if ok != (tc.want != "") || item.ID != tc.want || d != tc.distance {
+				t.Errorf("FindDuplicate = %q, %d, %v, want %q, %d", item.ID, d, ok, tc.want, tc.distance)
+			}
+		})
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		items       []ClothingItem
+		maxDistance int
+		want        [][]string
+		distances   []int
+	}{
+		{"no items", nil, 10, nil, nil},
+		{"all distinct", []ClothingItem{hashed("a", 0), hashed("b", 0xffff)}, 4, nil, nil},
+		{"one pair", []ClothingItem{hashed("a", 0), hashed("lone", 0xffff00), hashed("b", 0b11)}, 4, [][]string{{"a", "b"}}, []int{2}},
+		{
+			"transitive chain",
+			[]ClothingItem{hashed("a", 0), hashed("b", 0b111), hashed("c", 0b111111)},
+			3, [][]string{{"a", "b", "c"}}, []int{6},
+		},
+		{
+			"separate groups",
+			[]ClothingItem{hashed("a1", 0), hashed("b1", 0xff00), hashed("a2", 0b1), hashed("b2", 0xff01)},
+			1, [][]string{{"a1", "a2"}, {"b1", "b2"}}, []int{1, 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := GroupDuplicates(tc.items, tc.maxDistance)
+			if groups == nil {
+				t.Fatal("GroupDuplicates returned nil, want an empty list")
+			}
+			if len(groups) != len(tc.want) {
+				t.Fatalf("got %d groups, want %v", len(groups), tc.want)
+			}
+			for i, group := range groups {
+				var ids []string
+				for _, item := range group.Items {
+					ids = append(ids, item.ID)
+				}
+				if !slices.Equal(ids, tc.want[i]) || group.MaxDistance != tc.distances[i] {
+					t.Errorf("group %d = %v at %d, want %v at %d", i, ids, group.MaxDistance, tc.want[i], tc.distances[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// file_store.go
+package wardrobe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+)
+
+// FileStore keeps wardrobe items in memory and persists them as a JSON file.
+type FileStore struct {
+	mu    sync.RWMutex
+	path  string
+	items map[string]ClothingItem
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+// Constructor for FileStore. An empty path keeps items in memory only.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:  path,
+		items: make(map[string]ClothingItem),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var items []ClothingItem
+	if err := jsonfile.Load(path, &items); err != nil {
+		return nil, fmt.Errorf("failed to load wardrobe: %w", err)
+	}
+	for _, item := range items {
+		s.items[item.ID] = item
+	}
+	return s, nil
+}
+
+func (s *FileStore) SaveItem(ctx context.Context, item ClothingItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[item.ID] = item
+	return s.persist()
+}
+
+func (s *FileStore) GetItem(ctx context.Context, userID, itemID string) (ClothingItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[itemID]
+	if !ok || item.UserID != userID {
+		return ClothingItem{}, ErrItemNotFound
+	}
+	return item, nil
+}
+
+func (s *FileStore) ListItems(ctx context.Context, userID string) ([]ClothingItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]ClothingItem, 0)
+	for _, item := range s.items {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	sortItems(items)
+	return items, nil
+}
+
+func (s *FileStore) DeleteItem(ctx context.Context, userID, itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[itemID]
+	if !ok || item.UserID != userID {
+		return ErrItemNotFound
+	}
+	delete(s.items, itemID)
+	return s.persist()
+}
+
+// persist writes all items to disk. Callers must hold the write lock.
+func (s *FileStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	items := make([]ClothingItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sortItems(items)
+
+	return jsonfile.Save(s.path, items)
+}
+
+func sortItems(items []ClothingItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+}
@@ -0,0 +1,61 @@
+// wardrobe.go
+package wardrobe
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zulfkhar00/instafit_mvp/internal"
+)
+
+// ErrItemNotFound is returned when a clothing item does not exist for the user.
+var ErrItemNotFound = errors.New("clothing item not found")
+
+// ClothingItem is a single segmented garment stored in a user's wardrobe.
+type ClothingItem struct {
+	ID             string                      `json:"id"`
+	UserID         string                      `json:"user_id"`
+	ImageURL       string                      `json:"image_url"`
+	ObjectKey      string                      `json:"object_key"`
+	Attributes     internal.ClothingAttributes `json:"attributes"`
+	PerceptualHash uint64                      `json:"perceptual_hash,string"`
+	CreatedAt      time.Time                   `json:"created_at"`
+}
+
+// ColorFamilies returns the distinct color families of the item, most
+// dominant first.
+func (item ClothingItem) ColorFamilies() []string {
+	families := make([]string, 0, len(item.Attributes.Colors))
+	seen := make(map[string]bool)
+	for _, color := range item.Attributes.Colors {
+		if !seen[color.Family] {
+			seen[color.Family] = true
+			families = append(families, color.Family)
+		}
+	}
+	return families
+}
+
+// HasColorFamily reports whether any of the item's dominant colors belongs
+// to the given family.
+func (item ClothingItem) HasColorFamily(family string) bool {
+	for _, color := range item.Attributes.Colors {
+		if color.Family == family {
+			return true
+		}
+	}
+	return false
+}
+
+// Store defines the methods for persisting wardrobe items.
+type Store interface {
+	// SaveItem inserts or replaces a clothing item.
+	SaveItem(ctx context.Context, item ClothingItem) error
+	// GetItem returns a user's clothing item by ID.
+	GetItem(ctx context.Context, userID, itemID string) (ClothingItem, error)
+	// ListItems returns all clothing items of a user, oldest first.
+	ListItems(ctx context.Context, userID string) ([]ClothingItem, error)
+	// DeleteItem removes a user's clothing item by ID.
+	DeleteItem(ctx context.Context, userID, itemID string) error
+}
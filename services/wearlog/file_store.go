@@ -0,0 +1,106 @@
+// file_store.go
+package wearlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zulfkhar00/instafit_mvp/internal/jsonfile"
+)
+
+// FileStore keeps the wear log in memory and persists it as a JSON file.
+type FileStore struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]Entry
+}
+
+// Ensure FileStore implements Store
+var _ Store = (*FileStore)(nil)
+
+// Constructor for FileStore. An empty path keeps entries in memory only.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	var entries []Entry
+	if err := jsonfile.Load(path, &entries); err != nil {
+		return nil, fmt.Errorf("failed to load wear log: %w", err)
+	}
+	for _, entry := range entries {
+		s.entries[entry.ID] = entry
+	}
+	return s, nil
+}
+
+func (s *FileStore) AddEntry(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.ID] = entry
+	return s.persist()
+}
+
+func (s *FileStore) ListEntries(ctx context.Context, userID, from, to string) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]Entry, 0)
+	for _, entry := range s.entries {
+		if entry.UserID != userID {
+			continue
+		}
+		// Dates use a fixed-width layout, so they compare as strings.
+		if (from != "" && entry.Date < from) || (to != "" && entry.Date > to) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func (s *FileStore) DeleteEntry(ctx context.Context, userID, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[entryID]
+	if !ok || entry.UserID != userID {
+		return ErrEntryNotFound
+	}
+	delete(s.entries, entryID)
+	return s.persist()
+}
+
+// persist writes all entries to disk. Callers must hold the write lock.
+func (s *FileStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return jsonfile.Save(s.path, entries)
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
@@ -0,0 +1,73 @@
+// wearlog.go
+package wearlog
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of entry dates.
+const DateLayout = "2006-01-02"
+
+// ErrEntryNotFound is returned when a wear log entry does not exist for the user.
+var ErrEntryNotFound = errors.New("wear log entry not found")
+
+// Entry records the clothes a user wore on a day. The clothing IDs are
+// copied from the outfit when one is given, so history survives the
+// outfit being edited or deleted.
+type Entry struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Date        string    `json:"date"`
+	OutfitID    string    `json:"outfit_id,omitempty"`
+	ClothingIDs []string  `json:"clothing_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ItemStats summarises how often a wardrobe item was worn.
+type ItemStats struct {
+	ClothingID string `json:"clothing_id"`
+	WearCount  int    `json:"wear_count"`
+	LastWorn   string `json:"last_worn,omitempty"`
+}
+
+// Store defines the methods for persisting the wear log.
+type Store interface {
+	// AddEntry inserts a wear log entry.
+	AddEntry(ctx context.Context, entry Entry) error
+	// ListEntries returns a user's entries dated between from and to,
+	// inclusive, ordered by date. Empty bounds are open.
+	ListEntries(ctx context.Context, userID, from, to string) ([]Entry, error)
+	// DeleteEntry removes a user's entry by ID.
+	DeleteEntry(ctx context.Context, userID, entryID string) error
+}
+
+// Stats computes per-item wear counts and last-worn dates from entries.
+func Stats(entries []Entry) map[string]ItemStats {
+	stats := make(map[string]ItemStats)
+	for _, entry := range entries {
+		for _, id := range entry.ClothingIDs {
+			s := stats[id]
+			s.ClothingID = id
+			s.WearCount++
+			if entry.Date > s.LastWorn {
+				s.LastWorn = entry.Date
+			}
+			stats[id] = s
+		}
+	}
+	return stats
+}
+
+// CompareLeastWorn orders stats by wear count, then by how long ago the
+// item was last worn, for slices.SortStableFunc. Items never worn come
+// first.
+func CompareLeastWorn(a, b ItemStats) int {
+	if c := cmp.Compare(a.WearCount, b.WearCount); c != 0 {
+		return c
+	}
+	return strings.Compare(a.LastWorn, b.LastWorn)
+}
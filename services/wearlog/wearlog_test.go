@@ -0,0 +1,76 @@
+package wearlog
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entries []Entry
+		want    map[string]ItemStats
+	}{
+		{"no entries", nil, map[string]ItemStats{}},
+		{"counts every wear", []Entry{
+			{Date: "2026-03-01", ClothingIDs: []string{"shirt", "jeans"}},
+			{Date: "2026-03-02", ClothingIDs: []string{"shirt"}},
+		}, map[string]ItemStats{
+			"shirt": {ClothingID: "shirt", WearCount: 2, LastWorn: "2026-03-02"},
+			"jeans": {ClothingID: "jeans", WearCount: 1, LastWorn: "2026-03-01"},
+		}},
+		// Entries are usually sorted, but the latest date wins regardless
+		{"unsorted entries", []Entry{
+			{Date: "2026-03-05", ClothingIDs: []string{"shirt"}},
+			{Date: "2025-12-31", ClothingIDs: []string{"shirt"}},
+		}, map[string]ItemStats{
+			"shirt": {ClothingID: "shirt", WearCount: 2, LastWorn: "2026-03-05"},
+		}},
+		{"twice on one day", []Entry{
+			{Date: "2026-03-01", ClothingIDs: []string{"shirt"}},
+			{Date: "2026-03-01", ClothingIDs: []string{"shirt"}},
+		}, map[string]ItemStats{
+			"shirt": {ClothingID: "shirt", WearCount: 2, LastWorn: "2026-03-01"},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Stats(tc.entries); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("stats = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompareLeastWorn(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		stats []ItemStats
+		want  []string
+	}{
+		{"by wear count", []ItemStats{
+			{ClothingID: "often", WearCount: 5, LastWorn: "2026-01-01"},
+			{ClothingID: "once", WearCount: 1, LastWorn: "2026-03-01"},
+			{ClothingID: "never"},
+		}, []string{"never", "once", "often"}},
+		{"then by last worn", []ItemStats{
+			{ClothingID: "recent", WearCount: 2, LastWorn: "2026-03-01"},
+			{ClothingID: "old", WearCount: 2, LastWorn: "2025-12-24"},
+		}, []string{"old", "recent"}},
+		{"ties keep their order", []ItemStats{
+			{ClothingID: "b", WearCount: 1, LastWorn: "2026-01-01"},
+			{ClothingID: "a", WearCount: 1, LastWorn: "2026-01-01"},
+		}, []string{"b", "a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			slices.SortStableFunc(tc.stats, CompareLeastWorn)
+			var got []string
+			for _, s := range tc.stats {
+				got = append(got, s.ClothingID)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("order = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// http.go
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPProvider fetches conditions from a weather service that answers
+// GET {baseURL}/weather?location=... with a JSON Conditions document and
+// 404 for unknown locations. A local stand-in can serve the same contract.
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Ensure HTTPProvider implements Provider
+var _ Provider = (*HTTPProvider)(nil)
+
+// Constructor for HTTPProvider
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (p *HTTPProvider) Current(ctx context.Context, location string) (Conditions, error) {
+	endpoint := fmt.Sprintf("%s/weather?location=%s", p.baseURL, url.QueryEscape(location))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Conditions{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Conditions{}, fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Conditions{}, ErrUnknownLocation
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Conditions{}, fmt.Errorf("bad response from weather service: %s", string(body))
+	}
+
+	var conditions Conditions
+	if err := json.NewDecoder(resp.Body).Decode(&conditions); err != nil {
+		return Conditions{}, fmt.Errorf("failed to parse weather response: %w", err)
+	}
+	if conditions.Location == "" {
+		conditions.Location = location
+	}
+	return conditions, nil
+}
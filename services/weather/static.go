@@ -0,0 +1,32 @@
+// static.go
+package weather
+
+import (
+	"context"
+	"strings"
+)
+
+// StaticProvider serves fixed conditions, for tests and offline development.
+type StaticProvider struct {
+	// Locations maps lower-cased location names to their conditions.
+	Locations map[string]Conditions
+	// Default is returned for unlisted locations when set.
+	Default *Conditions
+}
+
+// Ensure StaticProvider implements Provider
+var _ Provider = (*StaticProvider)(nil)
+
+func (p *StaticProvider) Current(ctx context.Context, location string) (Conditions, error) {
+	key := strings.ToLower(strings.TrimSpace(location))
+	if conditions, ok := p.Locations[key]; ok {
+		conditions.Location = location
+		return conditions, nil
+	}
+	if p.Default != nil {
+		conditions := *p.Default
+		conditions.Location = location
+		return conditions, nil
+	}
+	return Conditions{}, ErrUnknownLocation
+}
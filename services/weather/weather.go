@@ -0,0 +1,36 @@
+// weather.go
+package weather
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrUnknownLocation is returned when a provider has no data for a
+	// location.
+	ErrUnknownLocation = errors.New("unknown location")
+	// ErrServiceUnavailable is returned when a provider cannot reach its
+	// weather service.
+	ErrServiceUnavailable = errors.New("weather service unavailable")
+)
+
+// Conditions describes the current weather at a location.
+type Conditions struct {
+	Location     string  `json:"location"`
+	TemperatureC float64 `json:"temperature_c"`
+	// PrecipitationChance is the probability of rain or snow, in [0, 1].
+	PrecipitationChance float64 `json:"precipitation_chance"`
+	Description         string  `json:"description,omitempty"`
+}
+
+// Rainy reports whether precipitation is likely enough to dress for it.
+func (c Conditions) Rainy() bool {
+	return c.PrecipitationChance >= 0.5
+}
+
+// Provider defines the methods for looking up weather conditions.
+type Provider interface {
+	// Current returns the current conditions at a location.
+	Current(ctx context.Context, location string) (Conditions, error)
+}
@@ -0,0 +1,115 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStaticProvider(t *testing.T) {
+	london := Conditions{TemperatureC: 12, PrecipitationChance: 0.7}
+	for _, tc := range []struct {
+		name     string
+		provider *StaticProvider
+		location string
+		want     Conditions
+		wantErr  error
+	}{
+		{"listed", &StaticProvider{Locations: map[string]Conditions{"london": london}}, "London",
+			Conditions{Location: "London", TemperatureC: 12, PrecipitationChance: 0.7}, nil},
+		{"padded", &StaticProvider{Locations: map[string]Conditions{"london": london}}, " LONDON ",
+			Conditions{Location: " LONDON ", TemperatureC: 12, PrecipitationChance: 0.7}, nil},
+		{"default", &StaticProvider{Default: &Conditions{TemperatureC: 20}}, "Paris",
+			Conditions{Location: "Paris", TemperatureC: 20}, nil},
+		{"unknown", &StaticProvider{Locations: map[string]Conditions{"london": london}}, "Paris",
+			Conditions{}, ErrUnknownLocation},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.provider.Current(context.Background(), tc.location)
+			if !errors.Is(err, tc.wantErr) || got != tc.want {
+				t.Errorf("Current = %+v, %v, want %+v, %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/weather" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("location") {
+		case "São Paulo":
+			w.Write([]byte(`{"location": "Sao Paulo, BR", "temperature_c": 28.5, "precipitation_chance": 0.4}`))
+		case "Oslo":
+			w.Write([]byte(`{"temperature_c": -3}`))
+		case "Atlantis":
+			http.NotFound(w, r)
+		case "Broken":
+			w.Write([]byte(`{"temperature_c": "cold"`))
+		default:
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	// A trailing slash on the base URL is tolerated
+	provider := NewHTTPProvider(srv.URL + "/")
+
+	for _, tc := range []struct {
+		name     string
+		location string
+		want     Conditions
+		wantErr  string
+	}{
+		{"escaped location", "São Paulo", Conditions{Location: "Sao Paulo, BR", TemperatureC: 28.5, PrecipitationChance: 0.4}, ""},
+		{"location filled in", "Oslo", Conditions{Location: "Oslo", TemperatureC: -3}, ""},
+		{"unknown", "Atlantis", Conditions{}, ErrUnknownLocation.Error()},
+		{"invalid JSON", "Broken", Conditions{}, "failed to parse weather response"},
+		{"server error", "Anywhere", Conditions{}, "service unavailable"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := provider.Current(context.Background(), tc.location)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("conditions = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := provider.Current(context.Background(), "Atlantis"); !errors.Is(err, ErrUnknownLocation) {
+		t.Errorf("error = %v, want ErrUnknownLocation", err)
+	}
+
+	srv.Close()
+	if _, err := provider.Current(context.Background(), "Oslo"); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("error = %v, want ErrServiceUnavailable", err)
+	}
+}
+
+func TestRainy(t *testing.T) {
+	for _, tc := range []struct {
+		chance float64
+		want   bool
+	}{
+		{0, false},
+		{0.49, false},
+		{0.5, true},
+		{1, true},
+	} {
+		if got := (Conditions{PrecipitationChance: tc.chance}).Rainy(); got != tc.want {
+			t.Errorf("Rainy at %g = %v, want %v", tc.chance, got, tc.want)
+		}
+	}
+}